@@ -1,7 +1,20 @@
 package main
 
-import "github.com/VadimBorzenkov/TradeSimulatorBot/internal/app"
+import (
+	"log"
+	"os"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/app"
+)
 
 func main() {
+	// Подкоманда backtest прогоняет стратегию на истории без запуска бота
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		if err := app.RunBacktest(os.Args[2:]); err != nil {
+			log.Fatalf("Ошибка бэктеста: %v", err)
+		}
+		return
+	}
+
 	app.RunApp()
 }
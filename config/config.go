@@ -5,14 +5,32 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
 // Config содержит все настройки
 type Config struct {
-	BotToken string
-	AdminID  int64
+	BotToken        string
+	AdminID         int64
+	StartingCapital decimal.Decimal
+	CostBasis       string
+	MakerFeePercent decimal.Decimal
+	TakerFeePercent decimal.Decimal
+	Slippage        string
+	StorageDriver   string
+	StoragePath     string
+	OKXBaseURL      string
+	OKXWSURL        string
+
+	OrderCheckInterval       time.Duration
+	InstrumentsRefreshPeriod time.Duration
+
+	AlertCheckInterval time.Duration
+	AlertCooldown      time.Duration
+	AlertLimit         int
 }
 
 // LoadConfig загружает конфигурацию из .env файла
@@ -36,8 +54,111 @@ func LoadConfig() Config {
 		log.Fatalf("Невозможно преобразовать ADMIN_ID в int64: %v", err)
 	}
 
+	// Читаем начальный капитал портфеля, по умолчанию 100 долларов
+	startingCapital := decimal.NewFromInt(100)
+	if capitalStr := os.Getenv("STARTING_CAPITAL"); capitalStr != "" {
+		startingCapital, err = decimal.NewFromString(capitalStr)
+		if err != nil || !startingCapital.IsPositive() {
+			log.Fatalf("Некорректное значение STARTING_CAPITAL: %s", capitalStr)
+		}
+	}
+
+	// Читаем метод учета себестоимости: average, fifo или lifo
+	costBasis := os.Getenv("COST_BASIS")
+	if costBasis == "" {
+		costBasis = "average"
+	}
+
+	// Читаем ставки комиссий в процентах, по умолчанию ставки OKX spot
+	makerFee := readPercent("FEE_MAKER", decimal.RequireFromString("0.08"))
+	takerFee := readPercent("FEE_TAKER", decimal.RequireFromString("0.1"))
+
+	// Проскальзывание: none, depth, процент ("0.05%") или сумма в USDT ("1.5")
+	slippage := os.Getenv("SLIPPAGE")
+
+	// Читаем настройки хранилища портфелей
+	storageDriver := os.Getenv("STORAGE_DRIVER")
+	if storageDriver == "" {
+		storageDriver = "file"
+	}
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "data/portfolios.json"
+	}
+
+	// Читаем адреса API OKX; их можно направить на локальный тестовый сервер
+	okxBaseURL := os.Getenv("OKX_BASE_URL")
+	if okxBaseURL == "" {
+		okxBaseURL = "https://www.okx.com"
+	}
+	okxWSURL := os.Getenv("OKX_WS_URL")
+	if okxWSURL == "" {
+		okxWSURL = "wss://ws.okx.com:8443/ws/v5/public"
+	}
+
+	// Читаем интервал проверки отложенных заявок
+	orderCheckInterval := readDuration("ORDER_CHECK_INTERVAL", 10*time.Second)
+
+	// Читаем период обновления справочника инструментов OKX
+	instrumentsRefresh := readDuration("INSTRUMENTS_REFRESH_INTERVAL", time.Hour)
+
+	// Читаем настройки алертов: период проверки, паузу между повторными уведомлениями и лимит на пользователя
+	alertCheckInterval := readDuration("ALERT_CHECK_INTERVAL", 10*time.Second)
+	alertCooldown := readDuration("ALERT_COOLDOWN", 15*time.Minute)
+	alertLimit := 20
+	if limitStr := os.Getenv("ALERT_LIMIT"); limitStr != "" {
+		alertLimit, err = strconv.Atoi(limitStr)
+		if err != nil || alertLimit <= 0 {
+			log.Fatalf("Некорректное значение ALERT_LIMIT: %s", limitStr)
+		}
+	}
+
 	return Config{
-		BotToken: botToken,
-		AdminID:  adminID,
+		BotToken:        botToken,
+		AdminID:         adminID,
+		StartingCapital: startingCapital,
+		CostBasis:       costBasis,
+		MakerFeePercent: makerFee,
+		TakerFeePercent: takerFee,
+		Slippage:        slippage,
+		StorageDriver:   storageDriver,
+		StoragePath:     storagePath,
+		OKXBaseURL:      okxBaseURL,
+		OKXWSURL:        okxWSURL,
+
+		OrderCheckInterval:       orderCheckInterval,
+		InstrumentsRefreshPeriod: instrumentsRefresh,
+
+		AlertCheckInterval: alertCheckInterval,
+		AlertCooldown:      alertCooldown,
+		AlertLimit:         alertLimit,
+	}
+}
+
+// readPercent читает неотрицательный процент из переменной окружения
+func readPercent(name string, fallback decimal.Decimal) decimal.Decimal {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := decimal.NewFromString(raw)
+	if err != nil || value.IsNegative() {
+		log.Fatalf("Некорректное значение %s: %s", name, raw)
+	}
+	return value
+}
+
+// readDuration читает положительный интервал вида "10s" или "1m" из переменной окружения
+func readDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		log.Fatalf("Некорректное значение %s: %s", name, raw)
 	}
+	return value
 }
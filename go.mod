@@ -8,3 +8,7 @@ require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/joho/godotenv v1.5.1
 )
+
+require github.com/gorilla/websocket v1.5.3
+
+require github.com/shopspring/decimal v1.4.0
@@ -0,0 +1,392 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/shopspring/decimal"
+)
+
+// alertStateKey задает ключ состояния алертов в хранилище
+const alertStateKey = "alerts"
+
+// Виды алертов
+const (
+	KindAbove = "above" // Цена поднялась до уровня или выше
+	KindBelow = "below" // Цена опустилась до уровня или ниже
+	KindMove  = "move"  // Цена изменилась на заданный процент за окно
+)
+
+// Направления движения цены для алертов KindMove
+const (
+	DirectionBoth = "both"
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// maxWindow ограничивает окно алерта на движение цены: история цен хранится в памяти
+const maxWindow = 7 * 24 * time.Hour
+
+// StateStore сохраняет алерты между перезапусками
+type StateStore interface {
+	// LoadState загружает состояние по ключу и сообщает, было ли оно найдено
+	LoadState(key string, v any) (bool, error)
+	// SaveState сохраняет состояние по ключу
+	SaveState(key string, v any) error
+}
+
+// Config описывает условие алерта
+type Config struct {
+	Token     string
+	Kind      string
+	Level     decimal.Decimal // Уровень цены для KindAbove и KindBelow
+	Percent   decimal.Decimal // Процент изменения для KindMove
+	Direction string          // Направление изменения для KindMove
+	Window    time.Duration   // Окно, за которое считается изменение для KindMove
+}
+
+// Validate проверяет параметры алерта
+func (c Config) Validate() error {
+	switch c.Kind {
+	case KindAbove, KindBelow:
+		if !c.Level.IsPositive() {
+			return fmt.Errorf("уровень цены должен быть больше нуля")
+		}
+	case KindMove:
+		if !c.Percent.IsPositive() || c.Percent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			return fmt.Errorf("процент изменения должен быть от 0 до 100%%")
+		}
+		switch c.Direction {
+		case DirectionBoth, DirectionUp, DirectionDown:
+		default:
+			return fmt.Errorf("неизвестное направление %s", c.Direction)
+		}
+		if c.Window < time.Minute || c.Window > maxWindow {
+			return fmt.Errorf("окно должно быть от 1 минуты до %d дней", int(maxWindow.Hours()/24))
+		}
+	default:
+		return fmt.Errorf("неизвестный вид алерта %s", c.Kind)
+	}
+	return nil
+}
+
+// Alert хранит алерт пользователя и историю его срабатываний
+type Alert struct {
+	ID        int64
+	UserID    int64
+	Config    Config
+	Armed     bool      // Уровневый алерт взведен: цена еще не пересекла уровень после прошлого срабатывания
+	Fired     int       // Количество срабатываний
+	LastFired time.Time // Время последнего срабатывания
+	CreatedAt time.Time
+}
+
+// Event описывает срабатывание алерта
+type Event struct {
+	Alert     Alert
+	Price     decimal.Decimal // Цена в момент срабатывания
+	Reference decimal.Decimal // Цена в начале окна для KindMove
+	Change    decimal.Decimal // Изменение цены в процентах для KindMove
+	Time      time.Time
+}
+
+// sample хранит цену токена в момент проверки
+type sample struct {
+	Time  time.Time
+	Price decimal.Decimal
+}
+
+// Manager проверяет алерты пользователей по ценам источника и сообщает о срабатываниях.
+// Количество алертов у пользователя ограничено, а повторное срабатывание алерта возможно не раньше, чем через Cooldown
+type Manager struct {
+	Feed       market.PriceFeed
+	OnAlert    func(event Event)
+	MaxPerUser int
+	Cooldown   time.Duration
+	state      StateStore
+	mu         sync.Mutex // Защищает алерты: команды бота и проверки приходят из разных горутин
+	alerts     map[int64]*Alert
+	history    map[string][]sample // Цены токенов за окно самого длинного алерта на движение
+	nextID     int64
+}
+
+// alertState описывает сохраняемое состояние менеджера
+type alertState struct {
+	NextID int64
+	Alerts map[int64]*Alert
+}
+
+// NewManager создает менеджер алертов и восстанавливает сохраненные алерты
+func NewManager(state StateStore, feed market.PriceFeed, maxPerUser int, cooldown time.Duration) (*Manager, error) {
+	saved := alertState{Alerts: make(map[int64]*Alert)}
+	if _, err := state.LoadState(alertStateKey, &saved); err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		Feed:       feed,
+		MaxPerUser: maxPerUser,
+		Cooldown:   cooldown,
+		state:      state,
+		alerts:     saved.Alerts,
+		history:    make(map[string][]sample),
+		nextID:     saved.NextID,
+	}, nil
+}
+
+// Create добавляет алерт пользователя
+func (m *Manager) Create(userID int64, cfg Config, now time.Time) (Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := cfg.Validate(); err != nil {
+		return Alert{}, err
+	}
+	if m.MaxPerUser > 0 && len(m.userAlerts(userID)) >= m.MaxPerUser {
+		return Alert{}, fmt.Errorf("достигнут лимит алертов: %d. Удалите ненужные", m.MaxPerUser)
+	}
+
+	m.nextID++
+	alert := &Alert{
+		ID:        m.nextID,
+		UserID:    userID,
+		Config:    cfg,
+		Armed:     true,
+		CreatedAt: now,
+	}
+	m.alerts[alert.ID] = alert
+	return *alert, m.save()
+}
+
+// Delete удаляет алерт пользователя
+func (m *Manager) Delete(userID, id int64) (Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alert, ok := m.alerts[id]
+	if !ok || alert.UserID != userID {
+		return Alert{}, fmt.Errorf("алерт #%d не найден", id)
+	}
+
+	delete(m.alerts, id)
+	return *alert, m.save()
+}
+
+// Alerts возвращает алерты пользователя в порядке создания
+func (m *Manager) Alerts(userID int64) []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.userAlerts(userID)
+}
+
+// userAlerts возвращает копии алертов пользователя, отсортированные по номеру
+func (m *Manager) userAlerts(userID int64) []Alert {
+	var alerts []Alert
+	for _, alert := range m.alerts {
+		if alert.UserID == userID {
+			alerts = append(alerts, *alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+	return alerts
+}
+
+// Run проверяет алерты с заданным интервалом, пока не будет отменен контекст
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Step(now)
+		}
+	}
+}
+
+// Step запоминает текущие цены токенов с алертами и проверяет условия алертов.
+// Цены запрашиваются и уведомления отправляются без блокировки, чтобы медленная сеть
+// не задерживала команды управления алертами
+func (m *Manager) Step(now time.Time) {
+	// Цена каждого токена запрашивается не более одного раза за проход
+	prices := make(map[string]decimal.Decimal)
+	for _, token := range m.tokens() {
+		price, err := m.Feed.Last(token)
+		if err != nil {
+			log.Printf("Ошибка получения цены %s для алертов: %v", token, err)
+			continue
+		}
+		prices[token] = price
+	}
+
+	for _, event := range m.evaluate(prices, now) {
+		if m.OnAlert != nil {
+			m.OnAlert(event)
+		}
+	}
+}
+
+// tokens возвращает токены, по которым есть алерты
+func (m *Manager) tokens() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, alert := range m.alerts {
+		if token := alert.Config.Token; !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// evaluate записывает цены в историю, проверяет алерты и возвращает сработавшие.
+// Алерты, удаленные пока запрашивались цены, уже не проверяются
+func (m *Manager) evaluate(prices map[string]decimal.Decimal, now time.Time) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	windows := make(map[string]time.Duration)
+	ids := make([]int64, 0, len(m.alerts))
+	for id, alert := range m.alerts {
+		ids = append(ids, id)
+		if alert.Config.Kind == KindMove {
+			windows[alert.Config.Token] = max(windows[alert.Config.Token], alert.Config.Window)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	m.record(prices, windows, now)
+
+	var events []Event
+	changed := false
+	for _, id := range ids {
+		alert := m.alerts[id]
+		price, ok := prices[alert.Config.Token]
+		if !ok {
+			continue
+		}
+
+		event, fired, updated := m.check(alert, price, now)
+		changed = changed || updated
+		if !fired {
+			continue
+		}
+
+		alert.Fired++
+		alert.LastFired = now
+		changed = true
+		event.Alert = *alert
+		events = append(events, event)
+	}
+
+	if changed {
+		if err := m.save(); err != nil {
+			log.Printf("Ошибка сохранения алертов: %v", err)
+		}
+	}
+	return events
+}
+
+// record добавляет цены в историю и отбрасывает цены старше самого длинного окна по токену
+func (m *Manager) record(prices map[string]decimal.Decimal, windows map[string]time.Duration, now time.Time) {
+	for token := range m.history {
+		if _, ok := windows[token]; !ok {
+			delete(m.history, token)
+		}
+	}
+
+	for token, window := range windows {
+		price, ok := prices[token]
+		if !ok {
+			continue
+		}
+
+		samples := append(m.history[token], sample{Time: now, Price: price})
+		start := 0
+		for start < len(samples)-1 && samples[start].Time.Before(now.Add(-window)) {
+			start++
+		}
+		m.history[token] = samples[start:]
+	}
+}
+
+// check проверяет условие алерта при цене price. updated сообщает об изменении состояния алерта без срабатывания
+func (m *Manager) check(alert *Alert, price decimal.Decimal, now time.Time) (event Event, fired, updated bool) {
+	event = Event{Price: price, Time: now}
+	cooling := !alert.LastFired.IsZero() && now.Sub(alert.LastFired) < m.Cooldown
+
+	switch alert.Config.Kind {
+	case KindAbove, KindBelow:
+		reached := price.GreaterThanOrEqual(alert.Config.Level)
+		if alert.Config.Kind == KindBelow {
+			reached = price.LessThanOrEqual(alert.Config.Level)
+		}
+
+		// Уровневый алерт срабатывает при пересечении уровня и взводится снова, когда цена вернется обратно
+		if !reached {
+			updated = !alert.Armed
+			alert.Armed = true
+			return event, false, updated
+		}
+		if !alert.Armed || cooling {
+			return event, false, false
+		}
+		alert.Armed = false
+		return event, true, true
+
+	case KindMove:
+		if cooling {
+			return event, false, false
+		}
+
+		// Изменение считается от самой ранней цены в окне, но не раньше прошлого срабатывания,
+		// чтобы одно и то же движение не вызывало повторных уведомлений
+		since := now.Add(-alert.Config.Window)
+		if alert.LastFired.After(since) {
+			since = alert.LastFired
+		}
+		reference, ok := m.reference(alert.Config.Token, since)
+		if !ok {
+			return event, false, false
+		}
+
+		change := price.Sub(reference).Div(reference).Mul(decimal.NewFromInt(100))
+		event.Reference, event.Change = reference, change
+		switch alert.Config.Direction {
+		case DirectionUp:
+			fired = change.GreaterThanOrEqual(alert.Config.Percent)
+		case DirectionDown:
+			fired = change.Neg().GreaterThanOrEqual(alert.Config.Percent)
+		default:
+			fired = change.Abs().GreaterThanOrEqual(alert.Config.Percent)
+		}
+		return event, fired, fired
+	}
+	return event, false, false
+}
+
+// reference возвращает самую раннюю цену токена, записанную не раньше since
+func (m *Manager) reference(token string, since time.Time) (decimal.Decimal, bool) {
+	for _, s := range m.history[token] {
+		if !s.Time.Before(since) {
+			return s.Price, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// save сохраняет все алерты
+func (m *Manager) save() error {
+	return m.state.SaveState(alertStateKey, alertState{NextID: m.nextID, Alerts: m.alerts})
+}
@@ -0,0 +1,155 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/shopspring/decimal"
+)
+
+const token = "BTC-USDT"
+
+// fakeFeed отдает заданную цену токена; остальные методы источника цен в тестах не вызываются
+type fakeFeed struct {
+	market.PriceFeed
+	price decimal.Decimal
+}
+
+func (f *fakeFeed) Last(string) (decimal.Decimal, error) {
+	return f.price, nil
+}
+
+// tick задает цену на минуте проверки
+type tick struct {
+	minute int
+	price  int64
+}
+
+func TestManagerCheck(t *testing.T) {
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		cooldown  time.Duration
+		ticks     []tick
+		wantFired []int // Минуты срабатываний
+	}{
+		{
+			name:      "выше уровня взводится после возврата",
+			cfg:       Config{Kind: KindAbove, Level: decimal.NewFromInt(110)},
+			ticks:     []tick{{0, 100}, {1, 111}, {2, 112}, {3, 105}, {4, 115}},
+			wantFired: []int{1, 4},
+		},
+		{
+			name:      "ниже уровня взводится после возврата",
+			cfg:       Config{Kind: KindBelow, Level: decimal.NewFromInt(90)},
+			ticks:     []tick{{0, 100}, {1, 89}, {2, 85}, {3, 95}, {4, 80}},
+			wantFired: []int{1, 4},
+		},
+		{
+			name:     "повторное пересечение ждет окончания паузы",
+			cfg:      Config{Kind: KindAbove, Level: decimal.NewFromInt(110)},
+			cooldown: 10 * time.Minute,
+			// На 4-й минуте алерт взведен, но пауза еще идет; срабатывание переносится на 12-ю
+			ticks:     []tick{{0, 100}, {1, 111}, {3, 105}, {4, 115}, {12, 115}},
+			wantFired: []int{1, 12},
+		},
+		{
+			name:  "рост считается от прошлого срабатывания",
+			cfg:   Config{Kind: KindMove, Percent: decimal.NewFromInt(5), Direction: DirectionUp, Window: 10 * time.Minute},
+			ticks: []tick{{0, 100}, {1, 103}, {2, 106}, {3, 107}, {4, 112}},
+			// 106 — рост на 6% от 100; 107 — меньше 1% от 106; 112 — рост на 5.7% от 106
+			wantFired: []int{2, 4},
+		},
+		{
+			name:      "рост не срабатывает при падении",
+			cfg:       Config{Kind: KindMove, Percent: decimal.NewFromInt(5), Direction: DirectionUp, Window: 10 * time.Minute},
+			ticks:     []tick{{0, 100}, {1, 90}, {2, 80}},
+			wantFired: nil,
+		},
+		{
+			name:     "движение в любую сторону с паузой",
+			cfg:      Config{Kind: KindMove, Percent: decimal.NewFromInt(5), Direction: DirectionBoth, Window: 10 * time.Minute},
+			cooldown: 5 * time.Minute,
+			// На 2-й минуте идет пауза; на 6-й изменение считается от 94, цены прошлого срабатывания
+			ticks:     []tick{{0, 100}, {1, 94}, {2, 88}, {6, 80}},
+			wantFired: []int{1, 6},
+		},
+		{
+			name:  "цены старше окна не учитываются",
+			cfg:   Config{Kind: KindMove, Percent: decimal.NewFromInt(5), Direction: DirectionUp, Window: 2 * time.Minute},
+			ticks: []tick{{0, 100}, {1, 102}, {2, 103}, {3, 104}, {4, 108}, {5, 110}},
+			// 104 и 108 меньше 5% от цены двумя минутами раньше, 110 — рост на 5.8% от 104
+			wantFired: []int{5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			feed := &fakeFeed{}
+			manager, err := NewManager(store, feed, 0, test.cooldown)
+			if err != nil {
+				t.Fatalf("NewManager: %v", err)
+			}
+			var fired []int
+			manager.OnAlert = func(event Event) {
+				fired = append(fired, int(event.Time.Sub(start)/time.Minute))
+			}
+
+			test.cfg.Token = token
+			if _, err := manager.Create(1, test.cfg, start); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			for _, tick := range test.ticks {
+				feed.price = decimal.NewFromInt(tick.price)
+				manager.Step(start.Add(time.Duration(tick.minute) * time.Minute))
+			}
+
+			if len(fired) != len(test.wantFired) {
+				t.Fatalf("срабатывания на минутах %v, ожидалось %v", fired, test.wantFired)
+			}
+			for i := range fired {
+				if fired[i] != test.wantFired[i] {
+					t.Fatalf("срабатывания на минутах %v, ожидалось %v", fired, test.wantFired)
+				}
+			}
+
+			// Счетчик срабатываний и состояние взвода переживают перезапуск
+			restored, err := NewManager(store, feed, 0, test.cooldown)
+			if err != nil {
+				t.Fatalf("NewManager: %v", err)
+			}
+			if alerts := restored.Alerts(1); len(alerts) != 1 || alerts[0].Fired != len(test.wantFired) {
+				t.Errorf("после перезапуска %+v, ожидалось срабатываний %d", alerts, len(test.wantFired))
+			}
+		})
+	}
+}
+
+func TestManagerLimit(t *testing.T) {
+	manager, err := NewManager(storage.NewMemoryStore(), &fakeFeed{}, 2, 0)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	cfg := Config{Token: token, Kind: KindAbove, Level: decimal.NewFromInt(110)}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.Create(1, cfg, now); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if _, err := manager.Create(1, cfg, now); err == nil {
+		t.Error("ожидалась ошибка лимита алертов")
+	}
+	if _, err := manager.Create(2, cfg, now); err != nil {
+		t.Errorf("лимит другого пользователя: %v", err)
+	}
+	if _, err := manager.Delete(2, 1); err == nil {
+		t.Error("удален чужой алерт")
+	}
+}
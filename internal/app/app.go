@@ -1,26 +1,111 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/VadimBorzenkov/TradeSimulatorBot/config"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/alerts"
 	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/bot"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/engine"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
 	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
 )
 
+// dcaCheckInterval задает период проверки расписаний DCA
+const dcaCheckInterval = time.Minute
+
 // RunApp запускает все компоненты приложения
 func RunApp() {
 	cfg := config.LoadConfig()
 
-	// Создаем экземпляр trader.Trader с начальным капиталом 100 долларов
-	traderInstance := &trader.Trader{
-		Capital:     100.0,
-		Investments: []trader.Investment{}, // Начинаем с пустым списком инвестиций
+	store, err := newStore(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации хранилища: %v", err)
+	}
+
+	costBasis, err := trader.ParseCostBasis(cfg.CostBasis)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации: %v", err)
+	}
+
+	client := okx.NewClient(cfg.OKXBaseURL)
+
+	// Справочник инструментов загружается до запуска бота и затем обновляется в фоне
+	instruments := market.NewInstruments(client.GetInstruments)
+	if err := instruments.Refresh(); err != nil {
+		log.Printf("Справочник инструментов не загружен, повторим в фоне: %v", err)
+	}
+	go instruments.Run(context.Background(), cfg.InstrumentsRefreshPeriod)
+
+	// Все компоненты получают рыночные данные через общий кэш цен, который наполняет поток WebSocket OKX.
+	// На поток подписываются только инструменты из справочника, неиспользуемые отписываются
+	stream := okx.NewStream(cfg.OKXWSURL)
+	go stream.Run(context.Background())
+	feed := market.NewStreamFeed(stream, market.NewOKXFeed(client), instruments.Valid)
+	go feed.Run(context.Background())
+
+	// Проскальзывание по стакану берет стаканы из того же источника рыночных данных
+	slippage, err := trader.ParseSlippage(cfg.Slippage, feed.OrderBook)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации: %v", err)
+	}
+
+	costs := trader.CostModel{
+		MakerFee: percentRate(cfg.MakerFeePercent),
+		TakerFee: percentRate(cfg.TakerFeePercent),
+		Slippage: slippage,
+	}
+
+	// Каждый пользователь получает собственный портфель при первом /start.
+	// Заявки проверяются по шагам и минимумам инструментов, как на бирже
+	portfolios := trader.NewPortfolios(store, cfg.StartingCapital, costBasis, costs)
+	portfolios.Instruments = instruments.Lookup
+
+	// Загружаем сохраненные портфели, чтобы их заявки исполнялись сразу после запуска
+	if err := portfolios.LoadAll(); err != nil {
+		log.Fatalf("Ошибка загрузки портфелей: %v", err)
 	}
 
-	// Передаем traderInstance в NewTelegramBot
-	tgBot := bot.NewTelegramBot(cfg.BotToken, cfg.AdminID, traderInstance)
+	grids, err := strategy.NewGridManager(portfolios, store)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки сеточных стратегий: %v", err)
+	}
+
+	dca, err := strategy.NewDCAManager(portfolios, store, feed)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки планов DCA: %v", err)
+	}
+
+	alertManager, err := alerts.NewManager(store, feed, cfg.AlertLimit, cfg.AlertCooldown)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки алертов: %v", err)
+	}
+
+	tgBot := bot.NewTelegramBot(cfg.BotToken, cfg.AdminID, portfolios, feed, instruments, grids, dca, alertManager)
+
+	// Запускаем фоновое исполнение заявок: сетки переставляют заявки, бот уведомляет владельцев
+	matcher := engine.NewMatcher(portfolios, feed, cfg.OrderCheckInterval)
+	matcher.OnFill = func(userID int64, fill trader.Fill) {
+		grids.OnFill(userID, fill)
+		tgBot.NotifyFill(userID, fill)
+	}
+	go matcher.Run(context.Background())
+
+	// Запускаем регулярные покупки по планам DCA
+	dca.OnBuy = tgBot.NotifyDCA
+	go dca.Run(context.Background(), dcaCheckInterval)
+
+	// Запускаем проверку алертов по живым ценам
+	alertManager.OnAlert = tgBot.NotifyAlert
+	go alertManager.Run(context.Background(), cfg.AlertCheckInterval)
 
 	// Используем wait group, чтобы программа не завершалась
 	var wg sync.WaitGroup
@@ -36,3 +121,27 @@ func RunApp() {
 
 	wg.Wait()
 }
+
+// storageBackend объединяет хранилище портфелей, состояния стратегий и алертов
+type storageBackend interface {
+	trader.Store
+	strategy.StateStore
+	alerts.StateStore
+}
+
+// newStore создает хранилище согласно конфигурации
+func newStore(cfg config.Config) (storageBackend, error) {
+	switch cfg.StorageDriver {
+	case "file":
+		return storage.NewFileStore(cfg.StoragePath)
+	case "memory":
+		return storage.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("неизвестный драйвер хранилища: %s", cfg.StorageDriver)
+	}
+}
+
+// percentRate переводит процент комиссии из настроек в долю от суммы сделки
+func percentRate(percent decimal.Decimal) decimal.Decimal {
+	return percent.Div(decimal.NewFromInt(100))
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/backtest"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// RunBacktest прогоняет стратегию на исторических свечах из OKX или CSV и печатает отчет.
+// Вызывается подкомандой "backtest" и не требует настроек бота
+func RunBacktest(args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	var (
+		token      = flags.String("token", "BTC-USDT", "торговая пара")
+		bar        = flags.String("bar", "1H", "размер свечи: 1m, 5m, 1H, 4H, 1D, ...")
+		days       = flags.Int("days", 30, "длина периода в днях, если не задан -from")
+		from       = flags.String("from", "", "начало периода (2006-01-02)")
+		to         = flags.String("to", "", "конец периода (2006-01-02), по умолчанию сейчас")
+		csvPath    = flags.String("csv", "", "файл свечей time,open,high,low,close[,volume] вместо загрузки из OKX")
+		okxURL     = flags.String("okx-url", okx.DefaultBaseURL, "адрес REST API OKX")
+		equityPath = flags.String("equity", "", "файл для записи кривой стоимости портфеля")
+		capital    = decimalFlag(flags, "capital", "100", "начальный капитал в USDT")
+		costBasis  = flags.String("cost-basis", "average", "учет себестоимости: average, fifo, lifo")
+		makerFee   = decimalFlag(flags, "maker", "0.08", "комиссия мейкера в процентах")
+		takerFee   = decimalFlag(flags, "taker", "0.1", "комиссия тейкера в процентах")
+		slippage   = flags.String("slippage", "", "проскальзывание: процент (0.05%) или сумма в USDT")
+		kind       = flags.String("strategy", "grid", "стратегия: grid или dca")
+		lower      = decimalFlag(flags, "lower", "0", "grid: нижняя граница")
+		upper      = decimalFlag(flags, "upper", "0", "grid: верхняя граница")
+		levels     = flags.Int("levels", 10, "grid: количество уровней")
+		geometric  = flags.Bool("geom", false, "grid: геометрический шаг")
+		quantity   = decimalFlag(flags, "qty", "0", "grid: количество токена на уровень")
+		amount     = decimalFlag(flags, "amount", "10", "dca: сумма покупки в USDT")
+		schedule   = flags.String("schedule", "daily", "dca: hourly, daily, weekly или cron-выражение")
+		budget     = decimalFlag(flags, "budget", "0", "dca: лимит трат в USDT")
+		boost      = decimalFlag(flags, "boost", "0", "dca: процент снижения цены для усиленной покупки")
+		boostMult  = decimalFlag(flags, "boost-mult", "2", "dca: множитель усиленной покупки")
+	)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	cfg := backtest.Config{Token: *token, StartingCapital: *capital}
+	var err error
+	if cfg.Bar, err = okx.ParseBar(*bar); err != nil {
+		return err
+	}
+	if cfg.CostBasis, err = trader.ParseCostBasis(*costBasis); err != nil {
+		return err
+	}
+	cfg.Costs = trader.CostModel{MakerFee: percentRate(*makerFee), TakerFee: percentRate(*takerFee)}
+	if cfg.Costs.Slippage, err = trader.ParseSlippage(*slippage, nil); err != nil {
+		return err
+	}
+
+	switch *kind {
+	case "grid":
+		cfg.Grid = &strategy.GridConfig{
+			Token:     *token,
+			Lower:     *lower,
+			Upper:     *upper,
+			Levels:    *levels,
+			Geometric: *geometric,
+			Quantity:  *quantity,
+		}
+		if err := cfg.Grid.Validate(); err != nil {
+			return err
+		}
+	case "dca":
+		cfg.DCA = &strategy.DCAConfig{Token: *token, Amount: *amount, Schedule: *schedule, Budget: *budget}
+		if boost.IsPositive() {
+			cfg.DCA.BoostPercent, cfg.DCA.BoostMultiplier = *boost, *boostMult
+		}
+		if err := cfg.DCA.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("неизвестная стратегия %s", *kind)
+	}
+
+	// Торговые правила берутся из справочника OKX; без него, например при офлайн-прогоне по CSV, они не проверяются
+	client := okx.NewClient(*okxURL)
+	instruments := market.NewInstruments(client.GetInstruments)
+	if err := instruments.Refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "Торговые правила %s не проверяются: %v\n", *token, err)
+	} else {
+		cfg.Instruments = instruments.Lookup
+	}
+
+	var candles []okx.Candle
+	if *csvPath != "" {
+		candles, err = backtest.LoadCSV(*csvPath)
+	} else {
+		var start, end time.Time
+		if start, end, err = backtestPeriod(*from, *to, *days); err != nil {
+			return err
+		}
+		candles, err = market.NewOKXFeed(client).Candles(context.Background(), *token, cfg.Bar, start, end)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := backtest.Run(context.Background(), cfg, candles)
+	if err != nil {
+		return err
+	}
+	fmt.Println(report.Summary())
+
+	if *equityPath != "" {
+		file, err := os.Create(*equityPath)
+		if err != nil {
+			return fmt.Errorf("не удалось создать файл кривой стоимости: %w", err)
+		}
+		defer file.Close()
+		return backtest.WriteEquityCSV(file, report.Equity)
+	}
+	return nil
+}
+
+// decimalValue разбирает флаг командной строки как точное десятичное число
+type decimalValue struct{ value *decimal.Decimal }
+
+func (v decimalValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return v.value.String()
+}
+
+func (v decimalValue) Set(raw string) error {
+	value, err := decimal.NewFromString(raw)
+	if err != nil {
+		return fmt.Errorf("некорректное число %s", raw)
+	}
+	*v.value = value
+	return nil
+}
+
+// decimalFlag объявляет десятичный флаг со значением по умолчанию
+func decimalFlag(flags *flag.FlagSet, name, fallback, usage string) *decimal.Decimal {
+	value := decimal.RequireFromString(fallback)
+	flags.Var(decimalValue{&value}, name, usage)
+	return &value
+}
+
+// backtestPeriod определяет интервал загрузки свечей по датам или количеству дней
+func backtestPeriod(from, to string, days int) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != "" {
+		var err error
+		if end, err = time.Parse(time.DateOnly, to); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("некорректная дата %s", to)
+		}
+	}
+
+	if from == "" {
+		if days <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("количество дней должно быть больше нуля")
+		}
+		return end.AddDate(0, 0, -days), end, nil
+	}
+
+	start, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("некорректная дата %s", from)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,257 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/engine"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// userID задает владельца модельного портфеля
+const userID = 1
+
+// Config описывает параметры прогона стратегии на исторических данных
+type Config struct {
+	Token           string
+	Bar             okx.Bar
+	StartingCapital decimal.Decimal
+	CostBasis       trader.CostBasis
+	Costs           trader.CostModel
+	Instruments     func(token string) (okx.Instrument, bool) // Торговые правила инструментов, как в боте; без справочника не проверяются
+	Grid            *strategy.GridConfig                      // Задается ровно одна стратегия
+	DCA             *strategy.DCAConfig
+}
+
+// EquityPoint описывает стоимость портфеля на закрытии свечи
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Report содержит результаты прогона. Портфель ведется в точной десятичной арифметике,
+// а статистика отчета рассчитывается в float64
+type Report struct {
+	Strategy        string
+	Token           string
+	Bar             okx.Bar
+	From            time.Time
+	To              time.Time
+	Bars            int
+	StartingCapital float64
+	FinalEquity     float64
+	TotalReturn     float64 // В процентах
+	MaxDrawdown     float64 // В процентах от пика
+	Sharpe          float64 // Годовой коэффициент Шарпа без учета безрисковой ставки
+	Sortino         float64 // Годовой коэффициент Сортино
+	Trades          int
+	Wins            int // Продажи с положительным реализованным PnL
+	Losses          int
+	WinRate         float64 // Доля прибыльных продаж в процентах
+	Fees            float64
+	DepthIgnored    bool // Проскальзывание по стакану заменено нулевым
+	Equity          []EquityPoint
+}
+
+// Run прогоняет стратегию по свечам: каждая свеча раскладывается на путь цены open → экстремумы → close,
+// на каждом шаге модельные часы и цена сдвигаются, а заявки сверяются тем же Matcher, что и в боте.
+// Прогон прерывается при отмене ctx
+func Run(ctx context.Context, cfg Config, candles []okx.Candle) (Report, error) {
+	if len(candles) == 0 {
+		return Report{}, fmt.Errorf("нет свечей для бэктеста")
+	}
+	if (cfg.Grid == nil) == (cfg.DCA == nil) {
+		return Report{}, fmt.Errorf("нужно указать ровно одну стратегию: grid или dca")
+	}
+
+	// Истории стаканов нет, поэтому проскальзывание по стакану не учитывается, а отчет сообщает об этом
+	depthIgnored := false
+	if _, ok := cfg.Costs.Slippage.(trader.DepthSlippage); ok {
+		cfg.Costs.Slippage = trader.NoSlippage{}
+		depthIgnored = true
+	}
+
+	// Модельные цена и время задаются источником Replay, который проигрывает путь цены каждой свечи
+	feed := market.NewReplay()
+	feed.Load(cfg.Token, cfg.Bar, candles)
+	feed.Set(cfg.Token, candles[0].Open, candles[0].Time)
+
+	store := storage.NewMemoryStore()
+	portfolios := trader.NewPortfolios(store, cfg.StartingCapital, cfg.CostBasis, cfg.Costs)
+	portfolios.Clock = feed.Now
+	// Заявки сетки и покупки DCA проходят те же проверки шага лота, шага цены и минимального размера, что и в боте
+	portfolios.Instruments = cfg.Instruments
+	t, err := portfolios.Open(userID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	matcher := &engine.Matcher{Portfolios: portfolios, Feed: feed, Quiet: true}
+	report := Report{
+		Token:           cfg.Token,
+		Bar:             cfg.Bar,
+		From:            candles[0].Time,
+		To:              candles[len(candles)-1].Time.Add(cfg.Bar.Duration()),
+		Bars:            len(candles),
+		StartingCapital: cfg.StartingCapital.InexactFloat64(),
+		DepthIgnored:    depthIgnored,
+	}
+
+	// step выполняет действия стратегии в текущий момент модельного времени
+	var step func()
+	switch {
+	case cfg.Grid != nil:
+		report.Strategy = "grid"
+		grids, err := strategy.NewGridManager(portfolios, store)
+		if err != nil {
+			return Report{}, err
+		}
+		if _, err := grids.Start(userID, *cfg.Grid, candles[0].Open); err != nil {
+			return Report{}, err
+		}
+		matcher.OnFill = grids.OnFill
+		step = matcher.Step
+
+	case cfg.DCA != nil:
+		report.Strategy = "dca"
+		dca, err := strategy.NewDCAManager(portfolios, store, feed)
+		if err != nil {
+			return Report{}, err
+		}
+		if _, err := dca.Create(userID, *cfg.DCA, feed.Now()); err != nil {
+			return Report{}, err
+		}
+		step = func() {
+			dca.Step(feed.Now())
+			matcher.Step()
+		}
+	}
+
+	for _, candle := range candles {
+		if err := ctx.Err(); err != nil {
+			return Report{}, err
+		}
+		for _, point := range pricePath(candle, cfg.Bar.Duration()) {
+			feed.Set(cfg.Token, point.Price, point.Time)
+			step()
+		}
+
+		equity := t.Report(map[string]decimal.Decimal{cfg.Token: candle.Close}).Equity.InexactFloat64()
+		report.Equity = append(report.Equity, EquityPoint{Time: candle.Time, Equity: equity})
+	}
+
+	trades, err := store.ListTrades(userID, 0, int(t.TradeCount))
+	if err != nil {
+		return Report{}, err
+	}
+	for _, trade := range trades {
+		report.Fees += trade.Fee.InexactFloat64()
+		if trade.Side != trader.SideSell {
+			continue
+		}
+		if trade.RealizedPnL.IsPositive() {
+			report.Wins++
+		} else {
+			report.Losses++
+		}
+	}
+	report.Trades = len(trades)
+	if sells := report.Wins + report.Losses; sells > 0 {
+		report.WinRate = float64(report.Wins) / float64(sells) * 100
+	}
+
+	report.FinalEquity = report.Equity[len(report.Equity)-1].Equity
+	report.TotalReturn = (report.FinalEquity/report.StartingCapital - 1) * 100
+	report.MaxDrawdown = maxDrawdown(report.StartingCapital, report.Equity)
+	report.Sharpe, report.Sortino = ratios(report.StartingCapital, report.Equity, cfg.Bar.Duration())
+	return report, nil
+}
+
+// pricePoint описывает модельную цену в момент времени внутри свечи
+type pricePoint struct {
+	Time  time.Time
+	Price decimal.Decimal
+}
+
+// pricePath раскладывает свечу на четыре точки: open, ближний к open экстремум, дальний экстремум, close
+func pricePath(candle okx.Candle, duration time.Duration) []pricePoint {
+	first, second := candle.High, candle.Low
+	if candle.Close.GreaterThanOrEqual(candle.Open) {
+		first, second = candle.Low, candle.High
+	}
+
+	return []pricePoint{
+		{Time: candle.Time, Price: candle.Open},
+		{Time: candle.Time.Add(duration / 3), Price: first},
+		{Time: candle.Time.Add(duration * 2 / 3), Price: second},
+		{Time: candle.Time.Add(duration - time.Second), Price: candle.Close},
+	}
+}
+
+// maxDrawdown возвращает наибольшее падение стоимости портфеля от пика в процентах
+func maxDrawdown(start float64, equity []EquityPoint) float64 {
+	peak, drawdown := start, 0.0
+	for _, point := range equity {
+		peak = max(peak, point.Equity)
+		drawdown = max(drawdown, (peak-point.Equity)/peak*100)
+	}
+	return drawdown
+}
+
+// ratios рассчитывает годовые коэффициенты Шарпа и Сортино по доходностям за свечу
+func ratios(start float64, equity []EquityPoint, bar time.Duration) (sharpe, sortino float64) {
+	if len(equity) < 2 || bar <= 0 {
+		return 0, 0
+	}
+
+	returns := make([]float64, 0, len(equity))
+	previous := start
+	for _, point := range equity {
+		returns = append(returns, point.Equity/previous-1)
+		previous = point.Equity
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance, downside := 0.0, 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	variance /= float64(len(returns) - 1)
+	downside /= float64(len(returns))
+
+	annualization := math.Sqrt(float64(365*24*time.Hour) / float64(bar))
+	if variance > 0 {
+		sharpe = mean / math.Sqrt(variance) * annualization
+	}
+	if downside > 0 {
+		sortino = mean / math.Sqrt(downside) * annualization
+	}
+	return sharpe, sortino
+}
+
+// Summary описывает результаты прогона
+func (r Report) Summary() string {
+	summary := fmt.Sprintf("Бэктест %s %s, свечи %s: %s – %s (%d)\nКапитал: $%.2f → $%.2f (%+.2f%%)\nМакс. просадка: %.2f%%\nШарп: %.2f, Сортино: %.2f\nСделок: %d, продаж в плюс: %d из %d (%.1f%%)\nКомиссии: $%.4f",
+		r.Strategy, r.Token, r.Bar, r.From.Format("02.01.2006 15:04"), r.To.Format("02.01.2006 15:04"), r.Bars,
+		r.StartingCapital, r.FinalEquity, r.TotalReturn, r.MaxDrawdown, r.Sharpe, r.Sortino,
+		r.Trades, r.Wins, r.Wins+r.Losses, r.WinRate, r.Fees)
+	if r.DepthIgnored {
+		summary += "\nПроскальзывание по стакану не учитывалось: истории стаканов нет."
+	}
+	return summary
+}
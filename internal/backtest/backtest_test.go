@@ -0,0 +1,210 @@
+package backtest
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+const token = "BTC-USDT"
+
+var start = time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+// candle создает часовую свечу с номером i от начала теста
+func candle(i int, open, high, low, close int64) okx.Candle {
+	return okx.Candle{
+		Time:  start.Add(time.Duration(i) * time.Hour),
+		Open:  decimal.NewFromInt(open),
+		High:  decimal.NewFromInt(high),
+		Low:   decimal.NewFromInt(low),
+		Close: decimal.NewFromInt(close),
+	}
+}
+
+// equity строит кривую стоимости по значениям на закрытии свечей
+func equity(values ...float64) []EquityPoint {
+	points := make([]EquityPoint, len(values))
+	for i, value := range values {
+		points[i] = EquityPoint{Time: start.Add(time.Duration(i) * time.Hour), Equity: value}
+	}
+	return points
+}
+
+func TestPricePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		candle okx.Candle
+		want   []int64
+	}{
+		{name: "растущая свеча сначала идет к минимуму", candle: candle(0, 100, 110, 90, 105), want: []int64{100, 90, 110, 105}},
+		{name: "падающая свеча сначала идет к максимуму", candle: candle(0, 100, 110, 90, 95), want: []int64{100, 110, 90, 95}},
+	}
+
+	for _, test := range tests {
+		path := pricePath(test.candle, time.Hour)
+		if len(path) != len(test.want) {
+			t.Fatalf("%s: точек %d, ожидалось %d", test.name, len(path), len(test.want))
+		}
+		for i, want := range test.want {
+			if !path[i].Price.Equal(decimal.NewFromInt(want)) {
+				t.Errorf("%s: точка %d по цене %s, ожидалась %d", test.name, i, path[i].Price, want)
+			}
+			if i > 0 && !path[i].Time.After(path[i-1].Time) {
+				t.Errorf("%s: точка %d не позже предыдущей", test.name, i)
+			}
+		}
+		if end := test.candle.Time.Add(time.Hour); !path[len(path)-1].Time.Before(end) {
+			t.Errorf("%s: закрытие %s не внутри свечи", test.name, path[len(path)-1].Time)
+		}
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		equity []EquityPoint
+		want   float64
+	}{
+		{name: "только рост", equity: equity(110, 120), want: 0},
+		{name: "просадка от начального капитала", equity: equity(90, 95), want: 10},
+		{name: "наибольшая из просадок", equity: equity(120, 108, 130, 104, 140), want: 20},
+	}
+
+	for _, test := range tests {
+		if got := maxDrawdown(100, test.equity); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: просадка %v, ожидалась %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRatios(t *testing.T) {
+	tests := []struct {
+		name        string
+		equity      []EquityPoint
+		wantSharpe  float64
+		wantSortino float64
+	}{
+		{name: "одна свеча", equity: equity(110)},
+		{name: "без изменений", equity: equity(100, 100, 100)},
+		// Доходности +10% и -5%: среднее 2.5%, отклонение 10.6%, нижнее отклонение 3.5%, год — 8760 часовых свечей
+		{name: "рост и падение", equity: equity(110, 104.5), wantSharpe: 22.0605228, wantSortino: 66.1815684},
+	}
+
+	for _, test := range tests {
+		sharpe, sortino := ratios(100, test.equity, time.Hour)
+		if math.Abs(sharpe-test.wantSharpe) > 1e-6 || math.Abs(sortino-test.wantSortino) > 1e-6 {
+			t.Errorf("%s: Шарп %v, Сортино %v, ожидалось %v и %v", test.name, sharpe, sortino, test.wantSharpe, test.wantSortino)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	instruments := market.NewStaticInstruments([]okx.Instrument{{
+		InstID:   token,
+		BaseCcy:  "BTC",
+		QuoteCcy: "USDT",
+		LotSz:    decimal.RequireFromString("0.01"),
+		TickSz:   decimal.RequireFromString("0.5"),
+		MinSz:    decimal.RequireFromString("0.01"),
+		State:    okx.InstrumentLive,
+	}}).Lookup
+
+	// Цена опускается к 95, возвращается к 100 и стоит на месте
+	candles := []okx.Candle{
+		candle(0, 100, 100, 95, 95),
+		candle(1, 95, 100, 95, 100),
+		candle(2, 100, 100, 100, 100),
+	}
+	grid := func(quantity string, lower, upper int64, levels int) *strategy.GridConfig {
+		return &strategy.GridConfig{
+			Token: token, Lower: decimal.NewFromInt(lower), Upper: decimal.NewFromInt(upper), Levels: levels, Quantity: decimal.RequireFromString(quantity),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		grid        *strategy.GridConfig
+		dca         *strategy.DCAConfig
+		instruments func(string) (okx.Instrument, bool)
+		wantErr     string
+		wantTrades  int
+		wantWins    int
+		wantEquity  float64
+	}{
+		{
+			name: "сетка покупает на 95 и продает на 100",
+			grid: grid("1", 90, 110, 5), instruments: instruments,
+			wantTrades: 2, wantWins: 1, wantEquity: 1005,
+		},
+		{
+			name: "размер уровня не кратен шагу лота",
+			grid: grid("1.005", 90, 110, 5), instruments: instruments,
+			wantErr: "лот",
+		},
+		{
+			name: "без справочника размер уровня не проверяется",
+			grid: grid("1.005", 90, 110, 5),
+			// Покупка на 95 и продажа на 100 по 1.005 токена
+			wantTrades: 2, wantWins: 1, wantEquity: 1005.025,
+		},
+		{
+			name: "уровни сливаются после округления до шага цены",
+			grid: grid("1", 100, 101, 4), instruments: instruments,
+			wantErr: "сливаются",
+		},
+		{
+			name: "покупки DCA меньше минимального размера пропускаются",
+			// 0.5 USDT при цене около 100 — это 0.005 токена, меньше минимального размера 0.01
+			dca:         &strategy.DCAConfig{Token: token, Amount: decimal.RequireFromString("0.5"), Schedule: "hourly"},
+			instruments: instruments,
+			wantEquity:  1000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Config{
+				Token:           token,
+				Bar:             okx.Bar1H,
+				StartingCapital: decimal.NewFromInt(1000),
+				CostBasis:       trader.AverageCost,
+				Costs:           trader.CostModel{Slippage: trader.NoSlippage{}},
+				Instruments:     test.instruments,
+				Grid:            test.grid,
+				DCA:             test.dca,
+			}
+
+			report, err := Run(context.Background(), cfg, candles)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			if report.Trades != test.wantTrades || report.Wins != test.wantWins {
+				t.Errorf("сделок %d, в плюс %d, ожидалось %d и %d", report.Trades, report.Wins, test.wantTrades, test.wantWins)
+			}
+			if len(report.Equity) != len(candles) {
+				t.Fatalf("точек кривой %d, ожидалось %d", len(report.Equity), len(candles))
+			}
+			if math.Abs(report.FinalEquity-test.wantEquity) > 1e-9 {
+				t.Errorf("итоговая стоимость %v, ожидалась %v", report.FinalEquity, test.wantEquity)
+			}
+			if want := (test.wantEquity/1000 - 1) * 100; math.Abs(report.TotalReturn-want) > 1e-9 {
+				t.Errorf("доходность %v%%, ожидалась %v%%", report.TotalReturn, want)
+			}
+		})
+	}
+}
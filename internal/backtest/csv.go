@@ -0,0 +1,130 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// LoadCSV читает свечи из CSV-файла со столбцами time,open,high,low,close[,volume].
+// Время задается в формате RFC 3339, датой 2006-01-02 или unix-временем в секундах или миллисекундах.
+// Строка заголовка необязательна
+func LoadCSV(path string) ([]okx.Candle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл свечей: %w", err)
+	}
+	defer file.Close()
+
+	return ReadCSV(file)
+}
+
+// ReadCSV читает свечи в формате LoadCSV и возвращает их в порядке возрастания времени
+func ReadCSV(r io.Reader) ([]okx.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var candles []okx.Candle
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CSV: %w", err)
+		}
+
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "time") {
+			continue
+		}
+		if len(record) < 5 {
+			return nil, fmt.Errorf("строка %d: ожидается не менее 5 столбцов", line)
+		}
+
+		candle, err := parseCSVCandle(record)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", line, err)
+		}
+		if n := len(candles); n > 0 && !candles[n-1].Time.Before(candle.Time) {
+			return nil, fmt.Errorf("строка %d: свечи должны идти по возрастанию времени", line)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// parseCSVCandle разбирает одну строку CSV
+func parseCSVCandle(record []string) (okx.Candle, error) {
+	openTime, err := parseTime(record[0])
+	if err != nil {
+		return okx.Candle{}, err
+	}
+
+	values := make([]decimal.Decimal, 5)
+	for i := range values {
+		if i+1 >= len(record) {
+			break
+		}
+		if values[i], err = decimal.NewFromString(strings.TrimSpace(record[i+1])); err != nil {
+			return okx.Candle{}, fmt.Errorf("некорректное значение %q", record[i+1])
+		}
+	}
+
+	candle := okx.Candle{
+		Time:      openTime,
+		Open:      values[0],
+		High:      values[1],
+		Low:       values[2],
+		Close:     values[3],
+		Volume:    values[4],
+		Confirmed: true,
+	}
+	if !candle.Low.IsPositive() || candle.Low.GreaterThan(decimal.Min(candle.Open, candle.Close)) ||
+		candle.High.LessThan(decimal.Max(candle.Open, candle.Close)) {
+		return okx.Candle{}, fmt.Errorf("некорректные цены свечи")
+	}
+	return candle, nil
+}
+
+// parseTime разбирает время свечи
+func parseTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if unix, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		// Значения больше 10^11 считаются миллисекундами
+		if unix > 1e11 {
+			return time.UnixMilli(unix), nil
+		}
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("некорректное время %q", raw)
+}
+
+// WriteEquityCSV записывает кривую стоимости портфеля в формате time,equity
+func WriteEquityCSV(w io.Writer, equity []EquityPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "equity"}); err != nil {
+		return err
+	}
+	for _, point := range equity {
+		record := []string{point.Time.UTC().Format(time.RFC3339), strconv.FormatFloat(point.Equity, 'f', 4, 64)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
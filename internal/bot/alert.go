@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/alerts"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// alertUsage описывает команды управления алертами
+const alertUsage = `Алерты присылают уведомление, когда цена достигает уровня или резко меняется.
+
+/alert BTC > 70000 — цена поднялась до уровня
+/alert BTC < 60000 — цена опустилась до уровня
+/alert ETH ±5% 1h — цена изменилась на 5% за час в любую сторону (+5% — только рост, -5% — только падение)
+/alert list — список алертов
+/alert del <id> — удалить алерт
+
+Окно изменения: от 1m до 7d, например 15m, 4h, 1d.`
+
+// handleAlert обрабатывает команду /alert и ее подкоманды
+func (tb *TelegramBot) handleAlert(chatID, userID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, alertUsage+"\n\n"+formatAlerts(tb.Alerts.Alerts(userID))))
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "list":
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, formatAlerts(tb.Alerts.Alerts(userID))))
+
+	case "del", "delete", "rm":
+		if len(fields) != 2 {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Укажите номер алерта: /alert del <id>"))
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Неверный номер алерта "+fields[1]))
+			return
+		}
+
+		alert, err := tb.Alerts.Delete(userID, id)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()))
+			return
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Алерт удален: "+formatAlert(alert)))
+
+	default:
+		cfg, err := tb.parseAlertConfig(fields)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n\n"+alertUsage))
+			return
+		}
+
+		alert, err := tb.Alerts.Create(userID, cfg, time.Now())
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()))
+			return
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Алерт создан: "+formatAlert(alert)))
+	}
+}
+
+// parseAlertConfig разбирает "ТОКЕН > УРОВЕНЬ", "ТОКЕН < УРОВЕНЬ" или "ТОКЕН ±ПРОЦЕНТ% ОКНО"
+func (tb *TelegramBot) parseAlertConfig(fields []string) (alerts.Config, error) {
+	cfg := alerts.Config{Token: normalizeSymbol(fields[0])}
+	if !tb.isValidAsset(cfg.Token) {
+		return cfg, fmt.Errorf("недействительный актив %s", cfg.Token)
+	}
+
+	// Уровень можно писать слитно со знаком: "> 70000" и ">70000" равнозначны
+	condition := strings.Join(fields[1:], " ")
+	if operator, level, ok := cutOperator(condition); ok {
+		cfg.Kind = alerts.KindAbove
+		if operator == "<" {
+			cfg.Kind = alerts.KindBelow
+		}
+
+		var err error
+		if cfg.Level, err = parsePrice(strings.TrimSpace(level)); err != nil {
+			return cfg, err
+		}
+		if err = tb.checkPrice(cfg.Token, cfg.Level); err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.Validate()
+	}
+
+	if len(fields) != 3 {
+		return cfg, fmt.Errorf("неверное количество аргументов")
+	}
+
+	cfg.Kind = alerts.KindMove
+	percent := fields[1]
+	switch {
+	case strings.HasPrefix(percent, "±"), strings.HasPrefix(percent, "+-"), strings.HasPrefix(percent, "-+"):
+		cfg.Direction = alerts.DirectionBoth
+		percent = strings.TrimLeft(percent, "±+-")
+	case strings.HasPrefix(percent, "+"):
+		cfg.Direction = alerts.DirectionUp
+		percent = percent[1:]
+	case strings.HasPrefix(percent, "-"):
+		cfg.Direction = alerts.DirectionDown
+		percent = percent[1:]
+	default:
+		cfg.Direction = alerts.DirectionBoth
+	}
+
+	var ok bool
+	if cfg.Percent, ok = parsePositive(strings.TrimSuffix(percent, "%")); !ok {
+		return cfg, fmt.Errorf("неверный процент %s", fields[1])
+	}
+
+	var err error
+	if cfg.Window, err = parseWindow(fields[2]); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// cutOperator отделяет знак сравнения от уровня цены
+func cutOperator(condition string) (operator, level string, ok bool) {
+	for _, operator := range []string{">", "<"} {
+		if level, ok := strings.CutPrefix(condition, operator); ok {
+			return operator, level, true
+		}
+	}
+	return "", "", false
+}
+
+// parseWindow разбирает окно изменения цены: "15m", "4h" или "1d"
+func parseWindow(raw string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(strings.ToLower(raw), "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("неверное окно %s", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	window, err := time.ParseDuration(strings.ToLower(raw))
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("неверное окно %s", raw)
+	}
+	return window, nil
+}
+
+// NotifyAlert сообщает владельцу о срабатывании алерта
+func (tb *TelegramBot) NotifyAlert(event alerts.Event) {
+	cfg := event.Alert.Config
+
+	var text string
+	switch cfg.Kind {
+	case alerts.KindAbove:
+		text = fmt.Sprintf("Алерт #%d: %s поднялся до $%s (уровень $%s)", event.Alert.ID, cfg.Token, formatPrice(event.Price), formatPrice(cfg.Level))
+	case alerts.KindBelow:
+		text = fmt.Sprintf("Алерт #%d: %s опустился до $%s (уровень $%s)", event.Alert.ID, cfg.Token, formatPrice(event.Price), formatPrice(cfg.Level))
+	default:
+		text = fmt.Sprintf("Алерт #%d: %s изменился на %s%% за %s: $%s → $%s", event.Alert.ID, cfg.Token,
+			formatChange(event.Change), formatWindow(cfg.Window), formatPrice(event.Reference), formatPrice(event.Price))
+	}
+	text += "\nУдалить алерт: /alert del " + strconv.FormatInt(event.Alert.ID, 10)
+	tb.Bot.Send(tgbotapi.NewMessage(tb.notify.chat(event.Alert.UserID), text))
+}
+
+// formatAlerts описывает список алертов пользователя
+func formatAlerts(list []alerts.Alert) string {
+	if len(list) == 0 {
+		return "Алертов нет."
+	}
+
+	lines := make([]string, 0, len(list)+1)
+	lines = append(lines, "Алерты:")
+	for _, alert := range list {
+		lines = append(lines, formatAlert(alert))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAlert описывает алерт одной строкой
+func formatAlert(alert alerts.Alert) string {
+	cfg := alert.Config
+
+	var condition string
+	switch cfg.Kind {
+	case alerts.KindAbove:
+		condition = "≥ $" + formatPrice(cfg.Level)
+	case alerts.KindBelow:
+		condition = "≤ $" + formatPrice(cfg.Level)
+	default:
+		sign := map[string]string{alerts.DirectionUp: "+", alerts.DirectionDown: "-"}[cfg.Direction]
+		if sign == "" {
+			sign = "±"
+		}
+		condition = fmt.Sprintf("%s%s%% за %s", sign, cfg.Percent, formatWindow(cfg.Window))
+	}
+
+	line := fmt.Sprintf("#%d %s %s", alert.ID, cfg.Token, condition)
+	if alert.Fired > 0 {
+		line += fmt.Sprintf(" (срабатываний: %d, последнее %s)", alert.Fired, alert.LastFired.Format("02.01 15:04"))
+	}
+	return line
+}
+
+// formatChange показывает изменение цены в процентах со знаком
+func formatChange(change decimal.Decimal) string {
+	if change.IsPositive() {
+		return "+" + change.StringFixed(2)
+	}
+	return change.StringFixed(2)
+}
+
+// formatWindow показывает окно в днях, часах или минутах
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", int(window/(24*time.Hour)))
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", int(window/time.Hour))
+	}
+	return fmt.Sprintf("%dm", int(window/time.Minute))
+}
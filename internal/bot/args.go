@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+// Подсказки по однострочному синтаксису команд
+const (
+	buyUsage   = "Формат: /buy BTC <количество|сумма usdt|процент%|all>\nПримеры: /buy BTC 25usdt, /buy ETH 0.01, /buy TON 10%"
+	sellUsage  = "Формат: /sell BTC <количество|сумма usdt|процент%|all>\nПримеры: /sell SOL all, /sell ETH 0.01, /sell BTC 50%"
+	limitUsage = "Формат: /limit buy|sell BTC <количество> @ <цена>\nПример: /limit buy BTC 0.001 @ 60000"
+)
+
+// normalizeSymbol приводит символ к виду OKX без учета регистра: "btc", "btc/usdt" и "BTC-USDT" дают "BTC-USDT".
+// Если котируемая валюта не указана, используется USDT
+func normalizeSymbol(raw string) string {
+	symbol := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(raw), "/", "-"))
+	if symbol != "" && !strings.Contains(symbol, "-") {
+		symbol += "-" + market.QuoteCurrency
+	}
+	return symbol
+}
+
+// parseOrderArgs разбирает "ТОКЕН РАЗМЕР" команд /buy и /sell. Размер задается количеством токенов ("0.01"),
+// суммой в USDT ("25usdt", "25 usdt", "$25"), долей свободных USDT или позиции ("25%") или all
+func (tb *TelegramBot) parseOrderArgs(portfolio *trader.Trader, side, args string) (trader.OrderIntent, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 3 && isQuoteCurrency(fields[2]) {
+		fields = []string{fields[0], fields[1] + fields[2]}
+	}
+	if len(fields) != 2 {
+		return trader.OrderIntent{}, fmt.Errorf("неверное количество аргументов")
+	}
+
+	intent := trader.OrderIntent{Side: side, Token: normalizeSymbol(fields[0]), Mode: trader.ByQuantity}
+	if !tb.isValidAsset(intent.Token) {
+		return intent, fmt.Errorf("недействительный актив %s", intent.Token)
+	}
+
+	amount := strings.ToLower(fields[1])
+	if amount == "all" {
+		return tb.presetIntent(portfolio, intent, decimal.NewFromInt(100))
+	}
+	if raw, ok := strings.CutSuffix(amount, "%"); ok {
+		percent, ok := parsePositive(raw)
+		if !ok || percent.GreaterThan(decimal.NewFromInt(100)) {
+			return intent, fmt.Errorf("неверная доля %s", fields[1])
+		}
+		return tb.presetIntent(portfolio, intent, percent)
+	}
+	if raw, ok := cutQuoteCurrency(amount); ok {
+		intent.Mode = trader.ByNotional
+		amount = raw
+	}
+
+	value, ok := parsePositive(amount)
+	if !ok {
+		return intent, fmt.Errorf("неверный размер заявки %s", fields[1])
+	}
+	intent.Value = value
+	return intent, nil
+}
+
+// parseLimitArgs разбирает "buy|sell ТОКЕН КОЛИЧЕСТВО @ ЦЕНА" команды /limit; знак @ можно опустить
+func (tb *TelegramBot) parseLimitArgs(args string) (limitDraft, error) {
+	var fields []string
+	for _, field := range strings.Fields(args) {
+		if field = strings.TrimPrefix(field, "@"); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) != 4 {
+		return limitDraft{}, fmt.Errorf("неверное количество аргументов")
+	}
+
+	var draft limitDraft
+	switch side := strings.ToLower(fields[0]); side {
+	case trader.SideBuy, trader.SideSell:
+		draft.Side = side
+	default:
+		return draft, fmt.Errorf("неизвестная сторона %s", fields[0])
+	}
+
+	draft.Token = normalizeSymbol(fields[1])
+	if !tb.isValidAsset(draft.Token) {
+		return draft, fmt.Errorf("недействительный актив %s", draft.Token)
+	}
+
+	var ok bool
+	if draft.Quantity, ok = parsePositive(fields[2]); !ok {
+		return draft, fmt.Errorf("неверное количество %s", fields[2])
+	}
+	if err := tb.checkQuantity(draft.Token, draft.Quantity); err != nil {
+		return draft, err
+	}
+
+	if draft.Price, ok = parsePositive(fields[3]); !ok {
+		return draft, fmt.Errorf("неверная цена %s", fields[3])
+	}
+	return draft, tb.checkPrice(draft.Token, draft.Price)
+}
+
+// isQuoteCurrency сообщает, обозначает ли слово сумму в USDT
+func isQuoteCurrency(word string) bool {
+	_, ok := cutQuoteCurrency(strings.ToLower(word))
+	return ok
+}
+
+// cutQuoteCurrency отделяет обозначение USDT от суммы: "25usdt", "25$" и "$25" дают "25"
+func cutQuoteCurrency(amount string) (string, bool) {
+	if raw, ok := strings.CutSuffix(amount, strings.ToLower(market.QuoteCurrency)); ok {
+		return raw, true
+	}
+	if raw, ok := strings.CutSuffix(amount, "$"); ok {
+		return raw, true
+	}
+	return strings.CutPrefix(amount, "$")
+}
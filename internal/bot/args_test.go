@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// newArgsBot создает бота со справочником из BTC-USDT (лот 0.001, шаг цены 0.1) и ETH-USDT
+// и портфель с $1000 и 0.5 BTC
+func newArgsBot(t *testing.T) (*TelegramBot, *trader.Trader) {
+	t.Helper()
+	instruments := market.NewStaticInstruments([]okx.Instrument{
+		{InstID: "BTC-USDT", BaseCcy: "BTC", QuoteCcy: "USDT", LotSz: decimal.RequireFromString("0.001"), MinSz: decimal.RequireFromString("0.001"), TickSz: decimal.RequireFromString("0.1"), State: okx.InstrumentLive},
+		{InstID: "ETH-USDT", BaseCcy: "ETH", QuoteCcy: "USDT", State: okx.InstrumentLive},
+	})
+
+	portfolios := trader.NewPortfolios(storage.NewMemoryStore(), decimal.NewFromInt(1100), trader.AverageCost, trader.CostModel{Slippage: trader.NoSlippage{}})
+	portfolio, err := portfolios.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	quote, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: "BTC-USDT", Mode: trader.ByQuantity, Value: decimal.RequireFromString("0.5")}, decimal.NewFromInt(200))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if _, err := portfolio.Execute(quote); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	return &TelegramBot{Instruments: instruments}, portfolio
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := map[string]string{
+		"btc":       "BTC-USDT",
+		" Eth ":     "ETH-USDT",
+		"btc/usdt":  "BTC-USDT",
+		"ETH-BTC":   "ETH-BTC",
+		"":          "",
+		"sol-usdt ": "SOL-USDT",
+	}
+
+	for raw, want := range tests {
+		if got := normalizeSymbol(raw); got != want {
+			t.Errorf("%q: получено %q, ожидалось %q", raw, got, want)
+		}
+	}
+}
+
+func TestParseOrderArgs(t *testing.T) {
+	tb, portfolio := newArgsBot(t)
+
+	tests := []struct {
+		side      string
+		args      string
+		wantToken string
+		wantMode  trader.OrderMode
+		wantValue string
+		wantErr   bool
+	}{
+		{side: trader.SideBuy, args: "btc 0.01", wantToken: "BTC-USDT", wantMode: trader.ByQuantity, wantValue: "0.01"},
+		{side: trader.SideBuy, args: "BTC 25usdt", wantToken: "BTC-USDT", wantMode: trader.ByNotional, wantValue: "25"},
+		{side: trader.SideBuy, args: "BTC 25 USDT", wantToken: "BTC-USDT", wantMode: trader.ByNotional, wantValue: "25"},
+		{side: trader.SideBuy, args: "eth $25", wantToken: "ETH-USDT", wantMode: trader.ByNotional, wantValue: "25"},
+		{side: trader.SideBuy, args: "eth 25$", wantToken: "ETH-USDT", wantMode: trader.ByNotional, wantValue: "25"},
+		// Доля покупки считается от свободных USDT, доля продажи — от позиции с округлением до лота
+		{side: trader.SideBuy, args: "eth 10%", wantToken: "ETH-USDT", wantMode: trader.ByNotional, wantValue: "100"},
+		{side: trader.SideSell, args: "btc all", wantToken: "BTC-USDT", wantMode: trader.ByQuantity, wantValue: "0.5"},
+		{side: trader.SideSell, args: "btc 33%", wantToken: "BTC-USDT", wantMode: trader.ByQuantity, wantValue: "0.165"},
+		{side: trader.SideSell, args: "eth all", wantErr: true},
+		{side: trader.SideBuy, args: "btc 101%", wantErr: true},
+		{side: trader.SideBuy, args: "btc -1", wantErr: true},
+		{side: trader.SideBuy, args: "btc много", wantErr: true},
+		{side: trader.SideBuy, args: "doge 1", wantErr: true},
+		{side: trader.SideBuy, args: "btc", wantErr: true},
+		{side: trader.SideBuy, args: "btc 1 2 3", wantErr: true},
+	}
+
+	for _, test := range tests {
+		intent, err := tb.parseOrderArgs(portfolio, test.side, test.args)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s %q: ожидалась ошибка, получено %+v", test.side, test.args, intent)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", test.side, test.args, err)
+			continue
+		}
+		if intent.Side != test.side || intent.Token != test.wantToken || intent.Mode != test.wantMode || !intent.Value.Equal(decimal.RequireFromString(test.wantValue)) {
+			t.Errorf("%s %q: получено %s %s %v %s, ожидалось %s %v %s", test.side, test.args,
+				intent.Side, intent.Token, intent.Mode, intent.Value, test.wantToken, test.wantMode, test.wantValue)
+		}
+	}
+}
+
+func TestParseLimitArgs(t *testing.T) {
+	tb, _ := newArgsBot(t)
+
+	tests := []struct {
+		args    string
+		want    limitDraft
+		wantErr bool
+	}{
+		{args: "buy BTC 0.001 @ 60000", want: limitDraft{Side: trader.SideBuy, Token: "BTC-USDT", Quantity: decimal.RequireFromString("0.001"), Price: decimal.NewFromInt(60000)}},
+		{args: "SELL eth 2 @3000.55", want: limitDraft{Side: trader.SideSell, Token: "ETH-USDT", Quantity: decimal.NewFromInt(2), Price: decimal.RequireFromString("3000.55")}},
+		{args: "buy btc/usdt 0.5 59000.1", want: limitDraft{Side: trader.SideBuy, Token: "BTC-USDT", Quantity: decimal.RequireFromString("0.5"), Price: decimal.RequireFromString("59000.1")}},
+		{args: "hold BTC 1 @ 60000", wantErr: true},
+		{args: "buy DOGE 1 @ 1", wantErr: true},
+		{args: "buy BTC 0.0015 @ 60000", wantErr: true},
+		{args: "buy BTC 0.001 @ 60000.05", wantErr: true},
+		{args: "buy BTC 0 @ 60000", wantErr: true},
+		{args: "buy BTC 0.001 @", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := tb.parseLimitArgs(test.args)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: ожидалась ошибка, получено %+v", test.args, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+		if got.Side != test.want.Side || got.Token != test.want.Token || !got.Quantity.Equal(test.want.Quantity) || !got.Price.Equal(test.want.Price) {
+			t.Errorf("%q: получено %+v, ожидалось %+v", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	assetsSearchLimit = 20   // Количество результатов поиска в одном сообщении
+	messageLimit      = 4000 // Длина сообщения с запасом до ограничения Telegram в 4096 символов
+)
+
+// sendAssets отправляет список доступных пар к USDT или результаты поиска по запросу
+func (tb *TelegramBot) sendAssets(chatID int64, query string) {
+	if tb.Instruments.Len() == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Справочник инструментов еще не загружен, попробуйте позже."))
+		return
+	}
+
+	if query = strings.TrimSpace(query); query != "" {
+		found := tb.Instruments.Search(query, assetsSearchLimit)
+		if len(found) == 0 {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "По запросу «"+query+"» ничего не найдено."))
+			return
+		}
+
+		lines := make([]string, 0, len(found))
+		for _, instrument := range found {
+			lines = append(lines, formatInstrument(instrument))
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Найдено:\n"+strings.Join(lines, "\n")))
+		return
+	}
+
+	// Полный список делится на сообщения, чтобы не превысить ограничение Telegram
+	instruments := tb.Instruments.List()
+	message := fmt.Sprintf("Доступно пар к USDT: %d. Поиск с шагами цены и количества: /assets <запрос>\n\n", len(instruments))
+	for i, instrument := range instruments {
+		symbol := instrument.BaseCcy
+		if i < len(instruments)-1 {
+			symbol += ", "
+		}
+		if len(message)+len(symbol) > messageLimit {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, message))
+			message = ""
+		}
+		message += symbol
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, message))
+}
+
+// roundQuantity округляет вниз до шага лота количество, рассчитанное ботом (доля позиции, продажа всего);
+// введенное пользователем количество проверяется checkQuantity
+func (tb *TelegramBot) roundQuantity(token string, quantity decimal.Decimal) (decimal.Decimal, error) {
+	instrument, ok := tb.Instruments.Lookup(token)
+	if !ok {
+		return quantity, nil
+	}
+
+	rounded := instrument.RoundQuantity(quantity)
+	if !rounded.IsPositive() {
+		return decimal.Zero, fmt.Errorf("количество меньше шага лота %s %s", instrument.LotSz, instrument.BaseCcy)
+	}
+	return rounded, nil
+}
+
+// checkQuantity проверяет введенное количество по шагу лота и минимуму инструмента.
+// Количество не округляется молча: при нарушении возвращается trader.RuleError с ближайшим допустимым значением
+func (tb *TelegramBot) checkQuantity(token string, quantity decimal.Decimal) error {
+	instrument, ok := tb.Instruments.Lookup(token)
+	if !ok {
+		return nil
+	}
+	return trader.CheckQuantity(instrument, quantity)
+}
+
+// checkPrice проверяет введенную цену по шагу цены инструмента и при нарушении возвращает trader.RuleError
+func (tb *TelegramBot) checkPrice(token string, price decimal.Decimal) error {
+	instrument, ok := tb.Instruments.Lookup(token)
+	if !ok {
+		return nil
+	}
+	return trader.CheckPrice(instrument, price)
+}
+
+// formatInstrument описывает инструмент и его торговые шаги
+func formatInstrument(instrument okx.Instrument) string {
+	return fmt.Sprintf("%s — шаг цены %s, шаг количества %s, минимум %s %s",
+		instrument.InstID, instrument.TickSz, instrument.LotSz, instrument.MinSz, instrument.BaseCcy)
+}
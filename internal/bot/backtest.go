@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/backtest"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// backtestMaxBars ограничивает объем истории, загружаемой по команде из чата
+const backtestMaxBars = 5000
+
+// backtestUsage описывает команду /backtest
+const backtestUsage = `Бэктест прогоняет стратегию на исторических свечах OKX с начальным капиталом и комиссиями симулятора.
+
+/backtest <свеча> <дней> grid <параметры как у /grid start>
+/backtest <свеча> <дней> dca <параметры как у /dca start>
+
+Свечи: 1m, 5m, 15m, 1H, 4H, 1D, 1W.
+
+Примеры:
+/backtest 1H 30 grid BTC-USDT 60000 70000 10 arith 0.0005
+/backtest 1D 365 dca ETH-USDT 10 weekly boost 10 2`
+
+// handleBacktest разбирает команду /backtest и запускает прогон в фоне, чтобы не задерживать другие сообщения.
+// Одновременно у пользователя выполняется не больше jobsPerUser фоновых задач
+func (tb *TelegramBot) handleBacktest(chatID, userID int64, args string) {
+	cfg, days, err := tb.parseBacktest(strings.Fields(args))
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n\n"+backtestUsage))
+		return
+	}
+
+	bars := time.Duration(days) * 24 * time.Hour / cfg.Bar.Duration()
+	if bars > backtestMaxBars {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Слишком длинный период: %d свечей, допустимо не более %d. Увеличьте размер свечи или сократите период.", bars, backtestMaxBars)))
+		return
+	}
+
+	started := tb.startJob(chatID, userID, func(ctx context.Context) {
+		tb.runBacktest(ctx, chatID, cfg, days)
+	})
+	if started {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Загружаю историю и запускаю бэктест..."))
+	}
+}
+
+// parseBacktest разбирает аргументы "СВЕЧА ДНЕЙ grid|dca ПАРАМЕТРЫ"
+func (tb *TelegramBot) parseBacktest(fields []string) (backtest.Config, int, error) {
+	if len(fields) < 4 {
+		return backtest.Config{}, 0, fmt.Errorf("неверное количество аргументов")
+	}
+
+	cfg := backtest.Config{
+		StartingCapital: tb.Portfolios.StartingCapital,
+		CostBasis:       tb.Portfolios.CostBasis,
+		Costs:           tb.Portfolios.Costs,
+		Instruments:     tb.Portfolios.Instruments,
+	}
+
+	var err error
+	if cfg.Bar, err = okx.ParseBar(fields[0]); err != nil {
+		return cfg, 0, err
+	}
+	days, err := strconv.Atoi(fields[1])
+	if err != nil || days <= 0 {
+		return cfg, 0, fmt.Errorf("неверное количество дней %s", fields[1])
+	}
+
+	switch fields[2] {
+	case "grid":
+		grid, err := tb.parseGridConfig(fields[3:])
+		if err != nil {
+			return cfg, 0, err
+		}
+		cfg.Token, cfg.Grid = grid.Token, &grid
+	case "dca":
+		dca, err := tb.parseDCAConfig(fields[3:])
+		if err != nil {
+			return cfg, 0, err
+		}
+		cfg.Token, cfg.DCA = dca.Token, &dca
+	default:
+		return cfg, 0, fmt.Errorf("неизвестная стратегия %s", fields[2])
+	}
+	return cfg, days, nil
+}
+
+// runBacktest загружает свечи, прогоняет стратегию и отправляет отчет с кривой стоимости портфеля.
+// Загрузка и прогон прерываются при отмене ctx
+func (tb *TelegramBot) runBacktest(ctx context.Context, chatID int64, cfg backtest.Config, days int) {
+	end := time.Now()
+	candles, err := tb.Feed.Candles(ctx, cfg.Token, cfg.Bar, end.AddDate(0, 0, -days), end)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка загрузки свечей: "+jobError(err)))
+		return
+	}
+
+	report, err := backtest.Run(ctx, cfg, candles)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка бэктеста: "+jobError(err)))
+		return
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, report.Summary()))
+
+	var equity bytes.Buffer
+	if err := backtest.WriteEquityCSV(&equity, report.Equity); err != nil {
+		log.Printf("Ошибка формирования кривой стоимости: %v", err)
+		return
+	}
+	document := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "equity.csv", Bytes: equity.Bytes()})
+	document.Caption = "Кривая стоимости портфеля"
+	tb.Bot.Send(document)
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// sendBalance отправляет таблицу позиций с PnL по текущим рыночным ценам
+func (tb *TelegramBot) sendBalance(chatID int64, portfolio *trader.Trader) {
+	balance, err := portfolio.GetBalance()
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения баланса: "+err.Error()))
+		return
+	}
+
+	// Запрашиваем цену каждого токена один раз
+	marks := make(map[string]decimal.Decimal)
+	for _, position := range balance.Positions {
+		price, err := tb.Feed.Last(position.Token)
+		if err != nil {
+			log.Printf("Ошибка получения цены для %s: %v", position.Token, err)
+			continue
+		}
+		marks[position.Token] = price
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatReport(portfolio.Report(marks)))
+	msg.ParseMode = tgbotapi.ModeHTML
+	tb.Bot.Send(msg)
+}
+
+// formatReport выводит отчет по портфелю компактной моноширинной таблицей
+func formatReport(report trader.Report) string {
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-10s %12s %10s %10s %16s\n", "Токен", "Кол-во", "Ср.цена", "Цена", "PnL"))
+	for _, row := range report.Positions {
+		token := strings.TrimSuffix(row.Token, "-USDT")
+		if !row.Priced {
+			table.WriteString(fmt.Sprintf("%-10s %12s %10s %10s %16s\n", token, formatQuantity(row.Quantity), formatPrice(row.AveragePrice), "н/д", "н/д"))
+			continue
+		}
+
+		pnl := fmt.Sprintf("%s (%s)", signed(row.UnrealizedPnL, ""), signed(row.UnrealizedPnLPercent, "%"))
+		table.WriteString(fmt.Sprintf("%-10s %12s %10s %10s %16s\n", token, formatQuantity(row.Quantity), formatPrice(row.AveragePrice), formatPrice(row.MarkPrice), pnl))
+	}
+	table.WriteString(fmt.Sprintf("%-10s %12s\n", "USDT", formatUSD(report.Cash)))
+	if report.ReservedCash.IsPositive() {
+		table.WriteString(fmt.Sprintf("%-10s %12s\n", "В заявках", formatUSD(report.ReservedCash)))
+	}
+
+	var summary strings.Builder
+	summary.WriteString(fmt.Sprintf("Нереализованный PnL: %s$\n", signed(report.UnrealizedPnL, "")))
+	summary.WriteString(fmt.Sprintf("Реализованный PnL: %s$\n", signed(report.RealizedPnL, "")))
+	summary.WriteString(fmt.Sprintf("Капитал: $%s (старт $%s, %s)", formatUSD(report.Equity), formatUSD(report.StartingCapital), signed(report.ReturnPercent, "%")))
+
+	return "<pre>" + html.EscapeString(table.String()) + "</pre>\n" + html.EscapeString(summary.String())
+}
+
+// signed форматирует число со знаком и двумя знаками после запятой
+func signed(value decimal.Decimal, suffix string) string {
+	if value.IsNegative() {
+		return value.StringFixed(2) + suffix
+	}
+	return "+" + value.StringFixed(2) + suffix
+}
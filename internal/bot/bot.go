@@ -2,42 +2,61 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"strings"
 	"time"
 
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/alerts"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
 	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
 	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
 )
 
 // TelegramBot содержит структуру для работы с ботом
 type TelegramBot struct {
-	Bot                 *tgbotapi.BotAPI
-	AdminID             int64
-	Trader              *trader.Trader
-	AwaitingAssetInput  map[int64]bool   // Ожидание ввода актива
-	AwaitingBuyInput    map[int64]bool   // Ожидание ввода для покупки
-	AwaitingSellInput   map[int64]bool   // Ожидание ввода для продажи
-	AwaitingAmountInput map[int64]string // Хранение актива для ввода суммы (buy/sell)
+	Bot         *tgbotapi.BotAPI
+	AdminID     int64
+	Portfolios  *trader.Portfolios
+	Feed        market.PriceFeed
+	Instruments *market.Instruments
+	Grids       *strategy.GridManager
+	DCA         *strategy.DCAManager
+	Alerts      *alerts.Manager
+	chats       *chatStates  // Состояния диалогов по чатам и пользователям
+	notify      *notifyChats // Чаты для уведомлений по пользователям
+	jobs        *userJobs    // Фоновые задачи по пользователям
+	commands    map[string]commandHandler
+	states      map[dialogState]stateHandler
 }
 
-func NewTelegramBot(token string, adminID int64, t *trader.Trader) *TelegramBot {
+func NewTelegramBot(token string, adminID int64, portfolios *trader.Portfolios, feed market.PriceFeed, instruments *market.Instruments, grids *strategy.GridManager, dca *strategy.DCAManager, alertManager *alerts.Manager) *TelegramBot {
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	return &TelegramBot{
-		Bot:                 bot,
-		AdminID:             adminID,
-		Trader:              t, // Сохраняем Trader
-		AwaitingAssetInput:  make(map[int64]bool),
-		AwaitingBuyInput:    make(map[int64]bool),
-		AwaitingSellInput:   make(map[int64]bool),
-		AwaitingAmountInput: make(map[int64]string),
+	tb := &TelegramBot{
+		Bot:         bot,
+		AdminID:     adminID,
+		Portfolios:  portfolios,
+		Feed:        feed,
+		Instruments: instruments,
+		Grids:       grids,
+		DCA:         dca,
+		Alerts:      alertManager,
+		chats:       newChatStates(),
+		notify:      newNotifyChats(),
+		jobs:        newUserJobs(),
+		commands:    make(map[string]commandHandler),
+		states:      make(map[dialogState]stateHandler),
 	}
+	tb.registerHandlers()
+	return tb
 }
 
 // Создаем клавиатуру с кнопками
@@ -49,6 +68,8 @@ func createReplyKeyboard() tgbotapi.ReplyKeyboardMarkup {
 		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("/price"),
+			tgbotapi.NewKeyboardButton("/chart"),
+			tgbotapi.NewKeyboardButton("/alert"),
 		),
 	)
 }
@@ -63,6 +84,17 @@ func createTradeKeyboard() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("/sell"),
 			tgbotapi.NewKeyboardButton("/grid_strategy"),
 		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("/history"),
+			tgbotapi.NewKeyboardButton("/lots"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("/limit"),
+			tgbotapi.NewKeyboardButton("/orders"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("/dca"),
+		),
 	)
 }
 
@@ -72,391 +104,245 @@ func (tb *TelegramBot) Start() {
 
 	updates := tb.Bot.GetUpdatesChan(u)
 
+	dispatcher := newDispatcher(tb.handleUpdate)
 	for update := range updates {
-		if update.Message != nil {
-			log.Printf("[%s] %s", update.Message.From.UserName, update.Message.Text)
-
-			// Обработка команды /start
-			if update.Message.Text == "/start" {
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Бот запущен! Выберите команду.")
-				msg.ReplyMarkup = createReplyKeyboard() // Добавляем клавиатуру
-				tb.Bot.Send(msg)
-				continue
-			}
-
-			// Обработка команды /assets
-			if update.Message.Text == "/assets" {
-				assets, err := okx.GetAssets()
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения активов"))
-					continue
-				}
-
-				assetList := ""
-				for _, asset := range assets {
-					assetList += asset + "\n"
-				}
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Список активов:\n"+assetList))
-				continue
-			}
+		chatID, ok := updateChatID(update)
+		if !ok {
+			continue
+		}
+		dispatcher.dispatch(chatID, update)
+	}
+	dispatcher.wait()
+}
 
-			// Обработка команды /trade
-			if update.Message.Text == "/trade" {
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Выберите действие:")
-				msg.ReplyMarkup = createTradeKeyboard() // Добавляем клавиатуру с кнопками торговли
-				tb.Bot.Send(msg)
-				continue
-			}
+// handleUpdate обрабатывает одно обновление: нажатие inline-кнопки или сообщение
+func (tb *TelegramBot) handleUpdate(update tgbotapi.Update) {
+	// Обработка нажатий на inline-кнопки
+	if update.CallbackQuery != nil {
+		if update.CallbackQuery.Message != nil {
+			tb.rememberChat(update.CallbackQuery.From.ID, update.CallbackQuery.Message.Chat, "")
+		}
+		switch data := update.CallbackQuery.Data; {
+		case strings.HasPrefix(data, historyCallbackPrefix):
+			tb.handleHistoryCallback(update.CallbackQuery)
+		case strings.HasPrefix(data, dialogCallbackPrefix):
+			tb.handleDialogCallback(update.CallbackQuery)
+		default:
+			tb.Bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+		}
+		return
+	}
 
-			// Обработка команды /price
-			if update.Message.Text == "/price" {
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Введите символ актива (например, BTC-USDT):")
-				tb.Bot.Send(msg)
-				tb.AwaitingAssetInput[update.Message.Chat.ID] = true // Устанавливаем состояние ожидания ввода актива
-				continue
-			}
+	if update.Message != nil {
+		log.Printf("[%s] %s", update.Message.From.UserName, update.Message.Text)
+		tb.rememberChat(update.Message.From.ID, update.Message.Chat, update.Message.Text)
+		tb.handleMessage(update.Message)
+	}
+}
 
-			// Проверяем, является ли введенный текст символом актива
-			if tb.AwaitingAssetInput[update.Message.Chat.ID] {
-				asset := update.Message.Text
-				if !isValidAsset(asset) {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недействительный актив. Попробуйте снова."))
-					continue
-				}
-
-				price, err := tb.getPriceWithRetries(asset)
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-					delete(tb.AwaitingAssetInput, update.Message.Chat.ID)
-					continue
-				}
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Текущая цена для "+asset+": "+price+"$"))
-
-				// Сбрасываем состояние ожидания актива
-				delete(tb.AwaitingAssetInput, update.Message.Chat.ID)
-				continue
-			}
+// rememberChat направляет уведомления пользователя в чат, если это личный чат или группа, где пользователь
+// вызвал команду этого бота. Обычные сообщения и нажатия кнопок в группе уведомления не перенаправляют
+func (tb *TelegramBot) rememberChat(userID int64, chat *tgbotapi.Chat, text string) {
+	if chat.IsPrivate() || tb.commandToMe(text) {
+		tb.notify.remember(userID, chat.ID)
+	}
+}
 
-			// Обработка команды /balance
-			if update.Message.Text == "/balance" {
-				balance, err := tb.Trader.GetBalance()
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения баланса: "+err.Error()))
-					continue
-				}
-
-				// Начинаем формировать сообщение о балансе
-				balanceMessage := "Текущие активы:\n"
-
-				// Добавляем токен USDT с текущим балансом
-				usdtValue := tb.Trader.Capital
-				balanceMessage += fmt.Sprintf("Токен: USDT, Количество: %.2f, Общая стоимость: $%.2f\n", usdtValue, usdtValue)
-
-				// Перебираем все инвестиции и добавляем их в сообщение
-				for _, investment := range balance.Investments {
-					currentPrice, err := okx.GetCurrentPrice(investment.Token)
-					if err != nil {
-						balanceMessage += fmt.Sprintf("Ошибка получения цены для %s\n", investment.Token)
-						continue
-					}
-
-					price, _ := strconv.ParseFloat(currentPrice, 64)
-					totalValue := investment.Amount * price
-					balanceMessage += fmt.Sprintf("Токен: %s, Количество: %.2f, Общая стоимость: $%.2f\n",
-						investment.Token, investment.Amount, totalValue)
-				}
-
-				// Суммируем общую стоимость всех активов
-				totalAssetsValue := usdtValue
-				for _, investment := range balance.Investments {
-					currentPrice, err := okx.GetCurrentPrice(investment.Token)
-					if err == nil {
-						price, _ := strconv.ParseFloat(currentPrice, 64)
-						totalAssetsValue += investment.Amount * price
-					}
-				}
-
-				balanceMessage += fmt.Sprintf("Общая стоимость: $%.2f", totalAssetsValue)
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, balanceMessage))
-				continue
-			}
+// registerHandlers регистрирует команды бота и шаги диалогов
+func (tb *TelegramBot) registerHandlers() {
+	tb.handleCommand("/start", commandHandler{Handle: tb.start})
+	tb.handleCommand("/trade", commandHandler{Handle: func(req request) dialogState {
+		msg := tgbotapi.NewMessage(req.ChatID, "Выберите действие:")
+		msg.ReplyMarkup = createTradeKeyboard() // Добавляем клавиатуру с кнопками торговли
+		tb.Bot.Send(msg)
+		return stateIdle
+	}})
+	tb.handleCommand("/assets", commandHandler{Handle: func(req request) dialogState {
+		tb.sendAssets(req.ChatID, req.Args)
+		return stateIdle
+	}})
+	tb.handleCommand("/price", commandHandler{Handle: func(req request) dialogState {
+		if req.Args != "" {
+			tb.sendPrices(req.ChatID, strings.Fields(req.Args))
+			return stateIdle
+		}
 
-			// Обработка команды /buy
-			if update.Message.Text == "/buy" {
-				usdtValue := tb.Trader.GetCapital()
-				tokenList := "Доступные токены для покупки:\n"
+		tb.askInstrument(req, statePriceAsset, "Выберите актив или введите символ (например, BTC-USDT):", tb.instrumentIDs())
+		return statePriceAsset
+	}})
+	tb.handleState(statePriceAsset, stateHandler{Handle: tb.handlePriceAsset})
+
+	tb.handleCommand("/balance", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.sendBalance(req.ChatID, req.Portfolio)
+		return stateIdle
+	}})
+	tb.handleCommand("/lots", commandHandler{Portfolio: true, Handle: tb.sendLots})
+	tb.handleCommand("/history", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.sendHistory(req.Message)
+		return stateIdle
+	}})
+
+	tb.registerOrderHandlers()
+	tb.registerLimitHandlers()
+
+	// Команды условных заявок /stop, /tp, /trail и /oco
+	for command, orderType := range triggerCommands {
+		tb.handleCommand(command, commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+			tb.placeTrigger(req.ChatID, req.Portfolio, orderType, req.Args)
+			return stateIdle
+		}})
+	}
+	tb.handleCommand("/oco", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.placeOCO(req.ChatID, req.Portfolio, req.Args)
+		return stateIdle
+	}})
+
+	grid := commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.handleGrid(req.ChatID, req.UserID, req.Args)
+		return stateIdle
+	}}
+	tb.handleCommand("/grid_strategy", grid)
+	tb.handleCommand("/grid", grid)
+	tb.handleCommand("/dca", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.handleDCA(req.ChatID, req.UserID, req.Args)
+		return stateIdle
+	}})
+	tb.handleCommand("/alert", commandHandler{Handle: func(req request) dialogState {
+		tb.handleAlert(req.ChatID, req.UserID, req.Args)
+		return stateIdle
+	}})
+	tb.handleCommand("/alerts", commandHandler{Handle: func(req request) dialogState {
+		tb.handleAlert(req.ChatID, req.UserID, "list")
+		return stateIdle
+	}})
+	tb.handleCommand("/chart", commandHandler{Handle: func(req request) dialogState {
+		tb.handleChart(req.ChatID, req.UserID, req.Args)
+		return stateIdle
+	}})
+	tb.handleCommand("/backtest", commandHandler{Handle: func(req request) dialogState {
+		tb.handleBacktest(req.ChatID, req.UserID, req.Args)
+		return stateIdle
+	}})
+}
 
-				// Получаем список доступных токенов
-				availableTokens := []string{"BTC-USDT", "ETH-USDT", "LTC-USDT", "XRP-USDT", "ADA-USDT", "SOL-USDT", "DOT-USDT", "BNB-USDT", "DOGE-USDT", "LINK-USDT"}
-				for _, token := range availableTokens {
-					tokenList += token + "\n"
-				}
+// start открывает портфель пользователя и показывает основную клавиатуру
+func (tb *TelegramBot) start(req request) dialogState {
+	if _, err := tb.Portfolios.Open(req.UserID); err != nil {
+		log.Printf("Ошибка открытия портфеля: %v", err)
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Не удалось открыть портфель. Попробуйте позже."))
+		return stateIdle
+	}
 
-				msg := fmt.Sprintf("Ваш баланс USDT: %.2f\n%s", usdtValue, tokenList)
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, msg))
+	msg := tgbotapi.NewMessage(req.ChatID, "Бот запущен! Выберите команду.")
+	msg.ReplyMarkup = createReplyKeyboard() // Добавляем клавиатуру
+	tb.Bot.Send(msg)
+	return stateIdle
+}
 
-				tb.AwaitingAssetInput[update.Message.Chat.ID] = true
-				tb.AwaitingBuyInput[update.Message.Chat.ID] = true
-				continue
-			}
+// handlePriceAsset отправляет текущую цену введенного актива
+func (tb *TelegramBot) handlePriceAsset(req request) dialogState {
+	asset, ok := tb.selectInstrument(req, statePriceAsset, tb.instrumentIDs())
+	if !ok {
+		return statePriceAsset
+	}
 
-			// Проверка, ожидается ли ввод токена для покупки
-			if tb.AwaitingAssetInput[update.Message.Chat.ID] && tb.AwaitingBuyInput[update.Message.Chat.ID] {
-				asset := update.Message.Text
-				if !isValidAsset(asset) {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недействительный актив. Попробуйте снова."))
-					continue
-				}
-
-				// Сохраняем актив и просим ввести сумму
-				tb.AwaitingAmountInput[update.Message.Chat.ID] = asset
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Введите сумму для покупки:"))
-				delete(tb.AwaitingAssetInput, update.Message.Chat.ID)
-				continue
-			}
+	price, err := tb.getPriceWithRetries(asset)
+	if err != nil {
+		tb.reply(req, "Ошибка получения цены: "+err.Error(), nil)
+		return stateIdle
+	}
 
-			// Проверка, ожидается ли ввод суммы для покупки
-			if asset, awaiting := tb.AwaitingAmountInput[update.Message.Chat.ID]; awaiting {
-				amount, err := strconv.ParseFloat(update.Message.Text, 64)
-				if err != nil || amount <= 0 {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недопустимая сумма. Попробуйте снова."))
-					continue
-				}
-
-				currentPrice, err := okx.GetCurrentPrice(asset) // Получаем текущую цену токена
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-					continue
-				}
-
-				price, _ := strconv.ParseFloat(currentPrice, 64)
-				totalCost := price * amount
-
-				if totalCost > tb.Trader.GetCapital() {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недостаточно средств для покупки."))
-					continue
-				}
-
-				// Выполняем покупку
-				err = tb.Trader.BuyToken(asset, amount, price)
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка при покупке: "+err.Error()))
-					continue
-				}
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Вы успешно купили %.2f токена(ов) %s по цене %.2f$", amount, asset, totalCost)))
-				delete(tb.AwaitingAmountInput, update.Message.Chat.ID) // Сбрасываем состояние ожидания суммы
-			}
+	tb.reply(req, "Текущая цена для "+asset+": "+price.String()+"$", nil)
+	return stateIdle
+}
 
-			// Обработка команды /sell
-			if update.Message.Text == "/sell" {
-				balance, err := tb.Trader.GetBalance()
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения баланса: "+err.Error()))
-					continue
-				}
-
-				// Формируем сообщение со списком текущих активов
-				sellMessage := "Текущие токены для продажи:\n"
-				for _, investment := range balance.Investments {
-					currentPrice, err := okx.GetCurrentPrice(investment.Token)
-					if err != nil {
-						sellMessage += fmt.Sprintf("Ошибка получения цены для %s\n", investment.Token)
-						continue
-					}
-
-					price, _ := strconv.ParseFloat(currentPrice, 64)
-					sellMessage += fmt.Sprintf("Токен: %s, Количество: %.2f, Текущая цена: $%.2f\n",
-						investment.Token, investment.Amount, price)
-				}
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, sellMessage))
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Введите символ токена для продажи (например, BTC-USDT):"))
-				tb.AwaitingAssetInput[update.Message.Chat.ID] = true
-				tb.AwaitingSellInput[update.Message.Chat.ID] = true
-				continue
-			}
+// sendPrices отправляет текущие цены нескольких активов одним сообщением: "/price btc eth ton"
+func (tb *TelegramBot) sendPrices(chatID int64, symbols []string) {
+	lines := make([]string, 0, len(symbols))
+	for _, raw := range symbols {
+		symbol := normalizeSymbol(raw)
+		if !tb.isValidAsset(symbol) {
+			lines = append(lines, fmt.Sprintf("%s: недействительный актив", symbol))
+			continue
+		}
 
-			// Проверка, ожидается ли ввод токена для продажи
-			if tb.AwaitingAssetInput[update.Message.Chat.ID] && tb.AwaitingSellInput[update.Message.Chat.ID] {
-				asset := update.Message.Text
-				if !isValidAsset(asset) {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недействительный актив. Попробуйте снова."))
-					continue
-				}
-
-				// Сохраняем актив и просим ввести сумму
-				tb.AwaitingAmountInput[update.Message.Chat.ID] = asset
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Введите количество для продажи (в USDT):"))
-				delete(tb.AwaitingAssetInput, update.Message.Chat.ID) // Сбрасываем состояние ожидания актива
-				continue
-			}
+		price, err := tb.getPriceWithRetries(symbol)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: ошибка получения цены", symbol))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: $%s", symbol, formatPrice(price)))
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, strings.Join(lines, "\n")))
+}
 
-			// Проверка, ожидается ли ввод суммы для продажи
-			if asset, awaiting := tb.AwaitingAmountInput[update.Message.Chat.ID]; awaiting {
-				amount, err := strconv.ParseFloat(update.Message.Text, 64)
-				if err != nil || amount <= 0 {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверная сумма. Попробуйте снова."))
-					continue
-				}
-
-				currentBalance := 0.0
-				for _, investment := range tb.Trader.Investments {
-					if investment.Token == asset {
-						currentBalance = investment.Amount
-						break
-					}
-				}
-
-				if currentBalance == 0 {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "У вас нет токенов для продажи."))
-					delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-					delete(tb.AwaitingSellInput, update.Message.Chat.ID)
-					continue
-				}
-
-				// Выполняем продажу токена
-				currentPriceStr, err := tb.getPriceWithRetries(asset)
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-					delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-					continue
-				}
-
-				currentPrice, _ := strconv.ParseFloat(currentPriceStr, 64)
-				_, err = tb.Trader.SellToken(asset, amount/currentPrice, currentPrice)
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка продажи токена: "+err.Error()))
-					continue
-				}
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Токен "+asset+" успешно продан."))
-				delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-				delete(tb.AwaitingSellInput, update.Message.Chat.ID)
-			}
+// sendLots отправляет разбивку позиций на партии
+func (tb *TelegramBot) sendLots(req request) dialogState {
+	balance, err := req.Portfolio.GetBalance()
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Ошибка получения баланса: "+err.Error()))
+		return stateIdle
+	}
 
-			// Обработка команды /grid_strategy
-			if update.Message.Text == "/grid_strategy" {
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Введите символ актива для сеточной стратегии (например, BTC-USDT):")
-				tb.Bot.Send(msg)
-				tb.AwaitingAssetInput[update.Message.Chat.ID] = true
-				// Логика для сеточной стратегии будет добавлена здесь позже
-				continue
-			}
+	tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, formatLots(balance.Positions, req.Portfolio.CostBasis)))
+	return stateIdle
+}
 
-			// Проверяем, является ли введенный текст символом актива
-			if tb.AwaitingAssetInput[update.Message.Chat.ID] {
-				asset := update.Message.Text
-				if !isValidAsset(asset) {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Недействительный актив. Попробуйте снова."))
-					continue
-				}
-
-				price, err := tb.getPriceWithRetries(asset)
-				if err != nil {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-					delete(tb.AwaitingAssetInput, update.Message.Chat.ID)
-					continue
-				}
-
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Текущая цена для "+asset+": "+price))
-
-				// Сохраняем актив и просим ввести сумму
-				tb.AwaitingAmountInput[update.Message.Chat.ID] = asset
-				tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Введите сумму для покупки/продажи:"))
-				delete(tb.AwaitingAssetInput, update.Message.Chat.ID)
-				continue
-			}
+// formatLots описывает разбивку позиций на партии
+func formatLots(positions []trader.Position, basis trader.CostBasis) string {
+	if len(positions) == 0 {
+		return "Открытых позиций нет."
+	}
 
-			// Проверка, ожидается ли ввод суммы для покупки или продажи
-			if asset, awaiting := tb.AwaitingAmountInput[update.Message.Chat.ID]; awaiting {
-				amount, err := strconv.ParseFloat(update.Message.Text, 64)
-				if err != nil || amount <= 0 {
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверная сумма. Попробуйте снова."))
-					continue
-				}
-
-				// Проверяем, было ли это покупкой
-				if tb.AwaitingBuyInput[update.Message.Chat.ID] {
-					priceStr, err := tb.getPriceWithRetries(asset)
-					if err != nil {
-						tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-						delete(tb.AwaitingBuyInput, update.Message.Chat.ID)
-						delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-						continue
-					}
-
-					price, _ := strconv.ParseFloat(priceStr, 64)
-					if err := tb.Trader.BuyToken(asset, amount, price); err != nil {
-						tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка покупки: "+err.Error()))
-						delete(tb.AwaitingBuyInput, update.Message.Chat.ID)
-						delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-						continue
-					}
-
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Успешно куплено %.2f %s по цене $%.2f.", amount, asset, price)))
-					delete(tb.AwaitingBuyInput, update.Message.Chat.ID)
-					delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-					continue
-				}
-
-				// Проверяем, было ли это продажей
-				if tb.AwaitingSellInput[update.Message.Chat.ID] {
-					priceStr, err := tb.getPriceWithRetries(asset)
-					if err != nil {
-						tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка получения цены: "+err.Error()))
-						delete(tb.AwaitingSellInput, update.Message.Chat.ID)
-						delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-						continue
-					}
-
-					price, _ := strconv.ParseFloat(priceStr, 64)
-					if _, err := tb.Trader.SellToken(asset, amount, price); err != nil {
-						tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка продажи: "+err.Error()))
-						delete(tb.AwaitingSellInput, update.Message.Chat.ID)
-						delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-						continue
-					}
-
-					tb.Bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Успешно продано %.2f %s по цене $%.2f.", amount, asset, price)))
-					delete(tb.AwaitingSellInput, update.Message.Chat.ID)
-					delete(tb.AwaitingAmountInput, update.Message.Chat.ID)
-					continue
-				}
+	message := fmt.Sprintf("Партии по позициям (метод учета: %s):\n", basis)
+	for _, position := range positions {
+		message += fmt.Sprintf("\n%s: %s, средняя цена $%s\n", position.Token, formatQuantity(position.Quantity()), formatPrice(position.AveragePrice()))
+		for i, lot := range position.Lots {
+			bought := "—"
+			if !lot.BoughtAt.IsZero() {
+				bought = lot.BoughtAt.Format("02.01.2006 15:04")
 			}
+			message += fmt.Sprintf("  %d. %s по $%s (%s)\n", i+1, formatQuantity(lot.Quantity), formatPrice(lot.Price), bought)
 		}
 	}
+	return message
+}
+
+// portfolioFor возвращает портфель отправителя или просит сначала выполнить /start
+func (tb *TelegramBot) portfolioFor(chatID, userID int64) (*trader.Trader, bool) {
+	portfolio, err := tb.Portfolios.Get(userID)
+	if errors.Is(err, trader.ErrNotFound) {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Сначала отправьте /start, чтобы открыть портфель."))
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Ошибка получения портфеля: %v", err)
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка загрузки портфеля. Попробуйте позже."))
+		return nil, false
+	}
+	return portfolio, true
 }
 
 // Функция для получения цены с повторными попытками
-func (tb *TelegramBot) getPriceWithRetries(symbol string) (string, error) {
+func (tb *TelegramBot) getPriceWithRetries(symbol string) (decimal.Decimal, error) {
 	const maxRetries = 3
 	const retryDelay = 5 * time.Second
 
 	for i := 0; i < maxRetries; i++ {
-		price, err := okx.GetCurrentPrice(symbol)
+		price, err := tb.Feed.Last(symbol)
 		if err != nil {
-			if err.Error() == "429 Too Many Requests" {
+			if errors.Is(err, okx.ErrTooManyRequests) {
 				time.Sleep(retryDelay)
 				continue
 			}
-			return "", err
+			return decimal.Zero, err
 		}
 		return price, nil
 	}
-	return "", fmt.Errorf("не удалось получить цену после нескольких попыток")
+	return decimal.Zero, fmt.Errorf("не удалось получить цену после нескольких попыток")
 }
 
-// Функция для проверки, является ли актив допустимым
-func isValidAsset(symbol string) bool {
-	validAssets := []string{"BTC-USDT", "ETH-USDT", "XRP-USDT", "TON-USDT", "LTC-USDT",
-		"BCH-USDT", "ADA-USDT", "DOT-USDT", "SOL-USDT", "DOGE-USDT"}
-	for _, asset := range validAssets {
-		if asset == symbol {
-			return true
-		}
-	}
-	return false
+// isValidAsset проверяет, что актив есть в справочнике инструментов и доступен для торговли
+func (tb *TelegramBot) isValidAsset(symbol string) bool {
+	return tb.Instruments.Valid(symbol)
 }
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/chart"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения графика: слишком мало свечей не дают картины, слишком много не помещаются по ширине
+const (
+	chartMinBars = 10
+	chartMaxBars = 300
+	chartTrades  = 500 // Количество последних сделок, просматриваемых для отметок на графике
+)
+
+// chartUsage описывает команду /chart
+const chartUsage = `График цены со свечами и объемом. На график наносятся ваши сделки и открытые заявки.
+
+/chart BTC [свеча] [период] [plain]
+
+Свечи: 1m, 5m, 15m, 1H, 4H, 1D, 1W; по умолчанию 1H. Период: 12h, 7d и т. п.; по умолчанию 100 свечей.
+plain — без сделок и заявок.
+
+Примеры:
+/chart BTC 4h 7d
+/chart ETH 15m 1d plain`
+
+// chartRequest содержит параметры графика из команды /chart
+type chartRequest struct {
+	Token  string
+	Bar    okx.Bar
+	Period time.Duration
+	Plain  bool
+}
+
+// handleChart разбирает команду /chart и строит график в фоне, чтобы не задерживать другие сообщения.
+// Как и бэктест, график занимает одно из jobsPerUser мест фоновых задач пользователя
+func (tb *TelegramBot) handleChart(chatID, userID int64, args string) {
+	req, err := tb.parseChart(strings.Fields(args))
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()+"\n\n"+chartUsage))
+		return
+	}
+
+	bars := int(req.Period / req.Bar.Duration())
+	if bars < chartMinBars || bars > chartMaxBars {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("На графике должно быть от %d до %d свечей, указано %d. Измените размер свечи или период.", chartMinBars, chartMaxBars, bars)))
+		return
+	}
+
+	tb.startJob(chatID, userID, func(ctx context.Context) {
+		tb.sendChart(ctx, chatID, userID, req)
+	})
+}
+
+// parseChart разбирает аргументы "ТОКЕН [СВЕЧА] [ПЕРИОД] [plain]"
+func (tb *TelegramBot) parseChart(fields []string) (chartRequest, error) {
+	if len(fields) == 0 {
+		return chartRequest{}, fmt.Errorf("укажите токен")
+	}
+
+	req := chartRequest{Token: normalizeSymbol(fields[0]), Bar: okx.Bar1H}
+	if !tb.isValidAsset(req.Token) {
+		return req, fmt.Errorf("недействительный актив %s", req.Token)
+	}
+
+	rest := fields[1:]
+	if len(rest) > 0 && strings.EqualFold(rest[len(rest)-1], "plain") {
+		req.Plain = true
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) > 2 {
+		return req, fmt.Errorf("неверное количество аргументов")
+	}
+
+	var err error
+	if len(rest) > 0 {
+		if req.Bar, err = okx.ParseBar(rest[0]); err != nil {
+			return req, err
+		}
+	}
+	req.Period = 100 * req.Bar.Duration()
+	if len(rest) > 1 {
+		if req.Period, err = parseWindow(rest[1]); err != nil {
+			return req, fmt.Errorf("неверный период %s", rest[1])
+		}
+	}
+	return req, nil
+}
+
+// sendChart загружает свечи, рисует график с отметками пользователя и отправляет его изображением.
+// Загрузка свечей прерывается при отмене ctx
+func (tb *TelegramBot) sendChart(ctx context.Context, chatID, userID int64, req chartRequest) {
+	end := time.Now()
+	candles, err := tb.Feed.Candles(ctx, req.Token, req.Bar, end.Add(-req.Period), end)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка загрузки свечей: "+jobError(err)))
+		return
+	}
+
+	var opts chart.Options
+	if !req.Plain {
+		opts = tb.chartMarks(userID, req.Token)
+	}
+
+	var image bytes.Buffer
+	if err := chart.Render(&image, candles, opts); err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка построения графика: "+err.Error()))
+		return
+	}
+
+	last := candles[len(candles)-1]
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "chart.png", Bytes: image.Bytes()})
+	photo.Caption = fmt.Sprintf("%s, свечи %s, %s — %s\nПоследняя цена: $%s",
+		req.Token, req.Bar, candles[0].Time.Format("02.01.2006 15:04"), last.Time.Format("02.01.2006 15:04"), formatPrice(last.Close))
+	if len(opts.Markers) > 0 || len(opts.Levels) > 0 {
+		photo.Caption += fmt.Sprintf("\nСделок на графике: %d, открытых заявок: %d", len(opts.Markers), len(opts.Levels))
+	}
+	tb.Bot.Send(photo)
+}
+
+// chartMarks собирает сделки и открытые заявки пользователя по токену; без портфеля график строится без отметок
+func (tb *TelegramBot) chartMarks(userID int64, token string) chart.Options {
+	var opts chart.Options
+
+	portfolio, err := tb.Portfolios.Get(userID)
+	if err != nil {
+		return opts
+	}
+
+	trades, err := tb.Portfolios.History(userID, 0, chartTrades)
+	if err != nil {
+		log.Printf("Ошибка получения сделок для графика: %v", err)
+	}
+	for _, trade := range trades {
+		if trade.Token == token {
+			opts.Markers = append(opts.Markers, chart.Marker{
+				Time:  trade.Time,
+				Price: trade.Price.InexactFloat64(),
+				Buy:   trade.Side == trader.SideBuy,
+			})
+		}
+	}
+
+	for _, order := range portfolio.OpenOrders() {
+		if order.Token != token {
+			continue
+		}
+		price := order.Price
+		if order.Type != trader.OrderLimit {
+			price = order.TriggerPrice
+		}
+		opts.Levels = append(opts.Levels, chart.Level{Price: price.InexactFloat64(), Buy: order.Side == trader.SideBuy})
+	}
+	return opts
+}
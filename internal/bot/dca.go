@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// dcaShownBuys задает количество последних покупок в описании плана
+const dcaShownBuys = 5
+
+// dcaUsage описывает команды управления планами DCA
+const dcaUsage = `DCA — регулярная покупка токена на фиксированную сумму в USDT по расписанию.
+
+/dca start BTC-USDT <сумма> <hourly|daily|weekly|cron м ч д мес дн> [budget <лимит>] [boost <процент> <множитель>]
+/dca status — планы, выполненные покупки и средняя цена
+/dca stop <id> — удалить план
+
+boost увеличивает покупку в заданное число раз, если цена ниже средней цены позиции на указанный процент.
+
+Примеры:
+/dca start BTC-USDT 10 daily budget 300
+/dca start ETH-USDT 5 cron 0 9 * * 1 boost 10 2`
+
+// handleDCA обрабатывает команду /dca и ее подкоманды
+func (tb *TelegramBot) handleDCA(chatID, userID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, dcaUsage))
+		return
+	}
+
+	switch fields[0] {
+	case "start":
+		cfg, err := tb.parseDCAConfig(fields[1:])
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()+"\n\n"+dcaUsage))
+			return
+		}
+
+		plan, err := tb.DCA.Create(userID, cfg, time.Now())
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()))
+			return
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "План создан.\n"+formatDCAPlan(plan)))
+
+	case "status":
+		plans := tb.DCA.Plans(userID)
+		if len(plans) == 0 {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Планов DCA нет.\n\n"+dcaUsage))
+			return
+		}
+
+		descriptions := make([]string, 0, len(plans))
+		for _, plan := range plans {
+			descriptions = append(descriptions, formatDCAPlan(plan))
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, strings.Join(descriptions, "\n\n")))
+
+	case "stop":
+		if len(fields) != 2 {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Укажите номер плана: /dca stop <id>"))
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Неверный номер плана "+fields[1]))
+			return
+		}
+
+		plan, err := tb.DCA.Cancel(userID, id)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()))
+			return
+		}
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "План удален.\n"+formatDCAPlan(plan)))
+
+	default:
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, dcaUsage))
+	}
+}
+
+// parseDCAConfig разбирает аргументы "ТОКЕН СУММА РАСПИСАНИЕ [budget X] [boost P M]"
+func (tb *TelegramBot) parseDCAConfig(fields []string) (strategy.DCAConfig, error) {
+	if len(fields) < 3 {
+		return strategy.DCAConfig{}, fmt.Errorf("неверное количество аргументов")
+	}
+
+	cfg := strategy.DCAConfig{Token: normalizeSymbol(fields[0])}
+	if !tb.isValidAsset(cfg.Token) {
+		return cfg, fmt.Errorf("недействительный актив %s", cfg.Token)
+	}
+
+	var err error
+	if cfg.Amount, err = parseUSDT(fields[1]); err != nil {
+		return cfg, err
+	}
+
+	rest := fields[2:]
+	if rest[0] == "cron" {
+		if len(rest) < 6 {
+			return cfg, fmt.Errorf("cron-выражение должно состоять из 5 полей")
+		}
+		cfg.Schedule = strings.Join(rest[1:6], " ")
+		rest = rest[6:]
+	} else {
+		cfg.Schedule = rest[0]
+		rest = rest[1:]
+	}
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case "budget":
+			if len(rest) < 2 {
+				return cfg, fmt.Errorf("укажите лимит бюджета")
+			}
+			if cfg.Budget, err = parseUSDT(rest[1]); err != nil {
+				return cfg, err
+			}
+			rest = rest[2:]
+		case "boost":
+			if len(rest) < 3 {
+				return cfg, fmt.Errorf("укажите процент снижения и множитель покупки")
+			}
+			var ok bool
+			if cfg.BoostPercent, ok = parsePositive(strings.TrimSuffix(rest[1], "%")); !ok {
+				return cfg, fmt.Errorf("неверный процент %s", rest[1])
+			}
+			if cfg.BoostMultiplier, ok = parsePositive(strings.TrimPrefix(rest[2], "x")); !ok {
+				return cfg, fmt.Errorf("неверный множитель %s", rest[2])
+			}
+			rest = rest[3:]
+		default:
+			return cfg, fmt.Errorf("неизвестный параметр %s", rest[0])
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// parseUSDT разбирает положительную сумму в USDT
+func parseUSDT(raw string) (decimal.Decimal, error) {
+	amount, ok := parsePositive(raw)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("неверная сумма %s", raw)
+	}
+	return amount, nil
+}
+
+// NotifyDCA сообщает владельцу плана о выполненной или пропущенной покупке
+func (tb *TelegramBot) NotifyDCA(plan strategy.DCAPlan, buy strategy.DCABuy, err error) {
+	text := fmt.Sprintf("DCA #%d: покупка пропущена: %v", plan.ID, err)
+	if err == nil {
+		text = fmt.Sprintf("DCA #%d: %s", plan.ID, formatDCABuy(plan.Config.Token, buy))
+	}
+	if !plan.Active {
+		text += "\nПлан завершен: " + plan.Stopped + "."
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(tb.notify.chat(plan.UserID), text))
+}
+
+// formatDCAPlan описывает параметры плана, его последние покупки и среднюю цену
+func formatDCAPlan(plan strategy.DCAPlan) string {
+	status := "активен"
+	if !plan.Active {
+		status = "завершен (" + plan.Stopped + ")"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "План #%d %s: %s\n$%s по расписанию %s\n", plan.ID, plan.Config.Token, status, formatUSD(plan.Config.Amount), plan.Config.Schedule)
+	if plan.Config.Budget.IsPositive() {
+		fmt.Fprintf(&b, "Потрачено: $%s из $%s\n", formatUSD(plan.Spent), formatUSD(plan.Config.Budget))
+	} else {
+		fmt.Fprintf(&b, "Потрачено: $%s\n", formatUSD(plan.Spent))
+	}
+	if plan.Config.BoostPercent.IsPositive() {
+		fmt.Fprintf(&b, "Усиление: x%s при цене ниже средней на %s%%\n", plan.Config.BoostMultiplier, plan.Config.BoostPercent)
+	}
+	if plan.Active {
+		fmt.Fprintf(&b, "Следующая покупка: %s\n", plan.NextRun.Format("02.01.2006 15:04"))
+	}
+
+	if plan.Skipped > 0 {
+		fmt.Fprintf(&b, "Пропущено покупок: %d, последняя причина: %s\n", plan.Skipped, plan.LastError)
+	}
+
+	if len(plan.Buys) == 0 {
+		b.WriteString("Покупок пока не было.")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Покупок: %d, средняя цена: $%s\n", len(plan.Buys), formatPrice(plan.AveragePrice()))
+	buys := plan.Buys[max(0, len(plan.Buys)-dcaShownBuys):]
+	for i := len(buys) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%s — %s\n", buys[i].Time.Format("02.01 15:04"), formatDCABuy(plan.Config.Token, buys[i]))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatDCABuy описывает одну покупку по плану
+func formatDCABuy(token string, buy strategy.DCABuy) string {
+	text := fmt.Sprintf("куплено %s %s по $%s за $%s", formatQuantity(buy.Quantity), token, formatPrice(buy.Price), formatUSD(buy.Cash))
+	if buy.Boosted {
+		text += " (усиленная покупка)"
+	}
+	return text
+}
@@ -0,0 +1,262 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dialogState обозначает шаг многошагового диалога с пользователем
+type dialogState string
+
+// Состояния диалогов. В stateIdle бот не ждет ввода и реагирует только на команды
+const (
+	stateIdle          dialogState = ""
+	statePriceAsset    dialogState = "price.asset"
+	stateOrderAsset    dialogState = "order.asset"
+	stateOrderMode     dialogState = "order.mode"
+	stateOrderAmount   dialogState = "order.amount"
+	stateOrderConfirm  dialogState = "order.confirm"
+	stateLimitSide     dialogState = "limit.side"
+	stateLimitToken    dialogState = "limit.token"
+	stateLimitQuantity dialogState = "limit.quantity"
+	stateLimitPrice    dialogState = "limit.price"
+	stateLimitConfirm  dialogState = "limit.confirm"
+)
+
+// inputTimeout задает время ожидания ввода на шаге диалога, если у шага нет своего
+const inputTimeout = 5 * time.Minute
+
+// transitions перечисляет допустимые переходы между состояниями.
+// Возврат в stateIdle и повтор текущего шага разрешены всегда
+var transitions = map[dialogState][]dialogState{
+	stateIdle:          {statePriceAsset, stateOrderAsset, stateOrderConfirm, stateLimitSide, stateLimitConfirm},
+	stateOrderAsset:    {stateOrderMode},
+	stateOrderMode:     {stateOrderAmount},
+	stateOrderAmount:   {stateOrderConfirm},
+	stateLimitSide:     {stateLimitToken},
+	stateLimitToken:    {stateLimitQuantity},
+	stateLimitQuantity: {stateLimitPrice},
+	stateLimitPrice:    {stateLimitConfirm},
+}
+
+// request описывает входящее сообщение вместе с состоянием диалога чата
+type request struct {
+	Message   *tgbotapi.Message
+	ChatID    int64
+	UserID    int64
+	Text      string                  // Текст сообщения без пробелов по краям или значение нажатой кнопки
+	Args      string                  // Аргументы команды: "/cancel 3" дает "3"
+	Callback  *tgbotapi.CallbackQuery // Нажатие inline-кнопки; nil для текстового ввода
+	Chat      *chatState
+	Portfolio *trader.Trader // Портфель отправителя; заполняется, если обработчик его требует
+}
+
+// commandHandler обрабатывает команду и возвращает состояние, в которое переходит диалог
+type commandHandler struct {
+	Portfolio bool // Команде нужен портфель: без /start она не выполняется
+	Handle    func(req request) dialogState
+}
+
+// stateHandler обрабатывает ввод на шаге диалога и возвращает следующее состояние
+type stateHandler struct {
+	Portfolio bool          // Шагу нужен портфель отправителя
+	Timeout   time.Duration // Время ожидания ввода; по умолчанию inputTimeout
+	Expired   string        // Сообщение об истечении времени; по умолчанию общее
+	Handle    func(req request) dialogState
+}
+
+// handleCommand регистрирует обработчик команды
+func (tb *TelegramBot) handleCommand(name string, handler commandHandler) {
+	tb.commands[name] = handler
+}
+
+// handleState регистрирует обработчик шага диалога
+func (tb *TelegramBot) handleState(state dialogState, handler stateHandler) {
+	if handler.Timeout == 0 {
+		handler.Timeout = inputTimeout
+	}
+	tb.states[state] = handler
+}
+
+// handleMessage передает сообщение обработчику команды или текущего шага диалога отправителя.
+// Любая известная команда прерывает незавершенный диалог, поэтому новые сценарии не перехватывают чужой ввод.
+// В группе диалоги участников независимы: ввод одного не попадает в диалог другого
+func (tb *TelegramBot) handleMessage(message *tgbotapi.Message) {
+	chat := tb.chats.get(message.Chat.ID, message.From.ID)
+	req := request{
+		Message: message,
+		ChatID:  message.Chat.ID,
+		UserID:  message.From.ID,
+		Text:    strings.TrimSpace(message.Text),
+		Chat:    chat,
+	}
+	isCommand := strings.HasPrefix(req.Text, "/")
+
+	// В группах команды других ботов приходят и этому боту: на них не отвечаем
+	if !tb.addressedToMe(req.Text) {
+		return
+	}
+
+	// Просроченный шаг сбрасывается до обработки сообщения
+	if text, ok := tb.expire(chat); ok && !isCommand {
+		msg := tgbotapi.NewMessage(req.ChatID, text)
+		msg.ReplyMarkup = createTradeKeyboard()
+		tb.Bot.Send(msg)
+		return
+	}
+
+	if isCommand {
+		name, mention, args := splitCommand(req.Text)
+		req.Args = args
+
+		// /cancel без номера заявки прерывает диалог в любом состоянии
+		if name == "/cancel" && req.Args == "" {
+			tb.cancelDialog(req)
+			return
+		}
+
+		handler, ok := tb.commands[name]
+		if !ok {
+			// В группе команда без упоминания могла предназначаться другому боту, поэтому отвечаем только в личном чате
+			if message.Chat.IsPrivate() || mention != "" {
+				tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Неизвестная команда."))
+			}
+			return
+		}
+
+		chat.reset()
+		tb.run(req, handler.Portfolio, handler.Handle)
+		return
+	}
+
+	handler, ok := tb.states[chat.State]
+	if !ok {
+		return
+	}
+	tb.run(req, handler.Portfolio, handler.Handle)
+}
+
+// splitCommand разбирает команду "/buy@имя_бота аргументы" на имя команды, упомянутого бота и аргументы
+func splitCommand(text string) (name, mention, args string) {
+	name, args, _ = strings.Cut(text, " ")
+	name, mention, _ = strings.Cut(name, "@") // В группах команда приходит как /buy@имя_бота
+	return name, mention, strings.TrimSpace(args)
+}
+
+// addressedToMe сообщает, относится ли сообщение к этому боту: команды с упоминанием другого бота пропускаются
+func (tb *TelegramBot) addressedToMe(text string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(text), "/") || tb.commandToMe(text)
+}
+
+// commandToMe сообщает, является ли сообщение командой без упоминания или с упоминанием этого бота
+func (tb *TelegramBot) commandToMe(text string) bool {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return false
+	}
+	_, mention, _ := splitCommand(text)
+	return mention == "" || strings.EqualFold(mention, tb.Bot.Self.UserName)
+}
+
+// handleDialogCallback передает нажатие inline-кнопки обработчику шага, к которому относится кнопка.
+// Кнопки прошлых шагов и завершенных диалогов не срабатывают, а кнопки чужого диалога только показывают предупреждение
+func (tb *TelegramBot) handleDialogCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		tb.Bot.Request(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
+
+	chatID := query.Message.Chat.ID
+	owner, state, value, parsed := parseDialogCallback(query.Data)
+	if parsed && owner != query.From.ID {
+		tb.Bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, "Эти кнопки относятся к диалогу другого пользователя."))
+		return
+	}
+	chat := tb.chats.get(chatID, query.From.ID)
+
+	if text, ok := tb.expire(chat); ok {
+		tb.Bot.Request(tgbotapi.NewCallback(query.ID, ""))
+		tb.Bot.Send(tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, text))
+		return
+	}
+
+	handler, ok := tb.states[chat.State]
+	if !ok || !parsed || state != chat.State {
+		tb.Bot.Request(tgbotapi.NewCallback(query.ID, "Кнопка устарела"))
+		tb.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, query.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+		}))
+		return
+	}
+
+	tb.Bot.Request(tgbotapi.NewCallback(query.ID, ""))
+	tb.run(request{
+		Message:  query.Message,
+		ChatID:   chatID,
+		UserID:   query.From.ID,
+		Text:     value,
+		Callback: query,
+		Chat:     chat,
+	}, handler.Portfolio, handler.Handle)
+}
+
+// expire сбрасывает шаг диалога, время ожидания которого истекло, и возвращает сообщение для пользователя
+func (tb *TelegramBot) expire(chat *chatState) (string, bool) {
+	handler, ok := tb.states[chat.State]
+	if !ok || time.Since(chat.EnteredAt) <= handler.Timeout {
+		return "", false
+	}
+
+	chat.reset()
+	if handler.Expired != "" {
+		return handler.Expired, true
+	}
+	return "Время ожидания ввода истекло, начните заново.", true
+}
+
+// run загружает портфель, если он нужен обработчику, и переводит диалог в возвращенное состояние
+func (tb *TelegramBot) run(req request, needsPortfolio bool, handle func(req request) dialogState) {
+	if needsPortfolio {
+		portfolio, ok := tb.portfolioFor(req.ChatID, req.UserID)
+		if !ok {
+			return
+		}
+		req.Portfolio = portfolio
+	}
+
+	tb.transition(req.Chat, handle(req))
+}
+
+// transition переводит диалог в состояние next. Переход, не описанный в transitions, сбрасывает диалог
+func (tb *TelegramBot) transition(chat *chatState, next dialogState) {
+	switch {
+	case next == stateIdle:
+		chat.reset()
+		return
+	case next != chat.State && !slices.Contains(transitions[chat.State], next):
+		log.Printf("Недопустимый переход диалога: %q -> %q", chat.State, next)
+		chat.reset()
+		return
+	}
+
+	chat.State = next
+	chat.EnteredAt = time.Now()
+}
+
+// cancelDialog прерывает текущий диалог по команде /cancel
+func (tb *TelegramBot) cancelDialog(req request) {
+	text := "Нечего отменять."
+	if req.Chat.State != stateIdle {
+		text = "Действие отменено."
+	}
+	req.Chat.reset()
+
+	msg := tgbotapi.NewMessage(req.ChatID, text)
+	msg.ReplyMarkup = createTradeKeyboard()
+	tb.Bot.Send(msg)
+}
@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeTelegram отвечает на запросы к Bot API успехом и запоминает отправленные тексты сообщений и ответов на кнопки
+type fakeTelegram struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (f *fakeTelegram) Do(req *http.Request) (*http.Response, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	if text := req.PostForm.Get("text"); text != "" {
+		f.mu.Lock()
+		f.texts = append(f.texts, text)
+		f.mu.Unlock()
+	}
+
+	body := `{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+}
+
+// last возвращает последний отправленный текст
+func (f *fakeTelegram) last() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.texts) == 0 {
+		return ""
+	}
+	return f.texts[len(f.texts)-1]
+}
+
+// sent сообщает, отправлялся ли текст
+func (f *fakeTelegram) sent(text string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, sent := range f.texts {
+		if sent == text {
+			return true
+		}
+	}
+	return false
+}
+
+// newDialogBot создает бота с тестовым диалогом: /order ждет символ, затем режим, затем завершается
+func newDialogBot(t *testing.T) (*TelegramBot, *fakeTelegram, *[]string) {
+	t.Helper()
+	telegram := &fakeTelegram{}
+	api := &tgbotapi.BotAPI{Token: "test", Client: telegram, Self: tgbotapi.User{UserName: "sim_bot"}}
+	api.SetAPIEndpoint(tgbotapi.APIEndpoint)
+
+	tb := &TelegramBot{
+		Bot:      api,
+		chats:    newChatStates(),
+		notify:   newNotifyChats(),
+		jobs:     newUserJobs(),
+		commands: make(map[string]commandHandler),
+		states:   make(map[dialogState]stateHandler),
+	}
+
+	var inputs []string
+	tb.handleCommand("/order", commandHandler{Handle: func(req request) dialogState { return stateOrderAsset }})
+	tb.handleCommand("/balance", commandHandler{Handle: func(req request) dialogState { return stateIdle }})
+	tb.handleCommand("/jump", commandHandler{Handle: func(req request) dialogState { return stateOrderAmount }})
+	tb.handleState(stateOrderAsset, stateHandler{Handle: func(req request) dialogState {
+		inputs = append(inputs, req.Text)
+		if req.Text == "повтор" {
+			return stateOrderAsset
+		}
+		return stateOrderMode
+	}})
+	tb.handleState(stateOrderMode, stateHandler{Expired: "Режим не выбран.", Handle: func(req request) dialogState {
+		inputs = append(inputs, req.Text)
+		return stateIdle
+	}})
+	return tb, telegram, &inputs
+}
+
+// message создает сообщение пользователя userID в чате chatID
+func message(chatID, userID int64, text string) *tgbotapi.Message {
+	chatType := "private"
+	if chatID < 0 {
+		chatType = "group"
+	}
+	return &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: chatID, Type: chatType},
+		From: &tgbotapi.User{ID: userID},
+		Text: text,
+	}
+}
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from dialogState
+		next dialogState
+		want dialogState
+	}{
+		{from: stateIdle, next: stateOrderAsset, want: stateOrderAsset},
+		{from: stateOrderAsset, next: stateOrderMode, want: stateOrderMode},
+		{from: stateOrderMode, next: stateOrderMode, want: stateOrderMode},
+		{from: stateLimitPrice, next: stateLimitConfirm, want: stateLimitConfirm},
+		{from: stateLimitPrice, next: stateIdle, want: stateIdle},
+		// Недопустимые переходы сбрасывают диалог
+		{from: stateOrderAsset, next: stateOrderConfirm, want: stateIdle},
+		{from: stateIdle, next: stateLimitQuantity, want: stateIdle},
+		{from: stateLimitToken, next: stateOrderMode, want: stateIdle},
+	}
+
+	tb := &TelegramBot{}
+	for _, test := range tests {
+		chat := &chatState{State: test.from, Limit: limitDraft{Token: "BTC-USDT"}}
+		tb.transition(chat, test.next)
+
+		if chat.State != test.want {
+			t.Errorf("%q -> %q: состояние %q, ожидалось %q", test.from, test.next, chat.State, test.want)
+		}
+		if test.want == stateIdle && chat.Limit.Token != "" {
+			t.Errorf("%q -> %q: черновик не очищен", test.from, test.next)
+		}
+		if test.want != stateIdle && chat.EnteredAt.IsZero() {
+			t.Errorf("%q -> %q: не отмечено время перехода", test.from, test.next)
+		}
+	}
+}
+
+func TestHandleMessageDialog(t *testing.T) {
+	const groupID, alice, bob = -100, 1, 2
+
+	steps := []struct {
+		name       string
+		chatID     int64
+		userID     int64
+		text       string
+		wantState  dialogState // Состояние диалога alice в группе после шага
+		wantInputs int
+		wantReply  string
+	}{
+		{name: "команда начинает диалог", chatID: groupID, userID: alice, text: "/order", wantState: stateOrderAsset},
+		{name: "ввод другого участника не попадает в диалог", chatID: groupID, userID: bob, text: "BTC", wantState: stateOrderAsset},
+		{name: "ввод в личном чате не попадает в диалог группы", chatID: alice, userID: alice, text: "BTC", wantState: stateOrderAsset},
+		{name: "повтор шага", chatID: groupID, userID: alice, text: "повтор", wantState: stateOrderAsset, wantInputs: 1},
+		{name: "ввод переводит на следующий шаг", chatID: groupID, userID: alice, text: "BTC", wantState: stateOrderMode, wantInputs: 2},
+		{name: "команда другому боту пропускается", chatID: groupID, userID: alice, text: "/balance@other_bot", wantState: stateOrderMode, wantInputs: 2},
+		{name: "команда прерывает диалог", chatID: groupID, userID: alice, text: "/balance@sim_bot", wantState: stateIdle, wantInputs: 2},
+		{name: "без диалога ввод игнорируется", chatID: groupID, userID: alice, text: "BTC", wantState: stateIdle, wantInputs: 2},
+		{name: "диалог начинается снова", chatID: groupID, userID: alice, text: "/order", wantState: stateOrderAsset, wantInputs: 2},
+		{name: "отмена", chatID: groupID, userID: alice, text: "/cancel", wantState: stateIdle, wantInputs: 2, wantReply: "Действие отменено."},
+		{name: "отменять нечего", chatID: groupID, userID: alice, text: "/cancel", wantState: stateIdle, wantInputs: 2, wantReply: "Нечего отменять."},
+		{name: "команда ведет в недопустимое состояние", chatID: groupID, userID: alice, text: "/jump", wantState: stateIdle, wantInputs: 2},
+		{name: "неизвестная команда с упоминанием", chatID: groupID, userID: alice, text: "/nope@sim_bot", wantState: stateIdle, wantInputs: 2, wantReply: "Неизвестная команда."},
+	}
+
+	tb, telegram, inputs := newDialogBot(t)
+	for _, step := range steps {
+		telegram.texts = nil
+		tb.handleMessage(message(step.chatID, step.userID, step.text))
+
+		if got := tb.chats.get(groupID, alice).State; got != step.wantState {
+			t.Errorf("%s: состояние %q, ожидалось %q", step.name, got, step.wantState)
+		}
+		if len(*inputs) != step.wantInputs {
+			t.Errorf("%s: обработано вводов %d, ожидалось %d", step.name, len(*inputs), step.wantInputs)
+		}
+		if step.wantReply != "" && telegram.last() != step.wantReply {
+			t.Errorf("%s: ответ %q, ожидался %q", step.name, telegram.last(), step.wantReply)
+		}
+	}
+}
+
+func TestHandleMessageExpired(t *testing.T) {
+	tb, telegram, inputs := newDialogBot(t)
+	tb.handleMessage(message(1, 1, "/order"))
+	tb.handleMessage(message(1, 1, "BTC"))
+
+	// Шаг выбора режима ждет ввода не дольше inputTimeout
+	chat := tb.chats.get(1, 1)
+	chat.EnteredAt = time.Now().Add(-inputTimeout - time.Second)
+	tb.handleMessage(message(1, 1, "qty"))
+
+	if chat.State != stateIdle || len(*inputs) != 1 {
+		t.Errorf("состояние %q, вводов %d, ожидался сброс без обработки ввода", chat.State, len(*inputs))
+	}
+	if telegram.last() != "Режим не выбран." {
+		t.Errorf("ответ %q, ожидалось сообщение шага об истечении времени", telegram.last())
+	}
+}
+
+func TestHandleDialogCallback(t *testing.T) {
+	const chatID, alice, bob = -100, 1, 2
+
+	tests := []struct {
+		name       string
+		userID     int64
+		data       string
+		wantState  dialogState
+		wantInputs []string
+		wantReply  string
+	}{
+		{name: "кнопка текущего шага", userID: alice, data: "dlg:1:order.asset:BTC-USDT", wantState: stateOrderMode, wantInputs: []string{"BTC-USDT"}},
+		{name: "кнопка прошлого шага", userID: alice, data: "dlg:1:order.mode:qty", wantState: stateOrderAsset, wantReply: "Кнопка устарела"},
+		{name: "кнопка чужого диалога", userID: bob, data: "dlg:1:order.asset:BTC-USDT", wantState: stateOrderAsset, wantReply: "Эти кнопки относятся к диалогу другого пользователя."},
+		{name: "кнопка без владельца", userID: alice, data: "order.asset:BTC-USDT", wantState: stateOrderAsset, wantReply: "Кнопка устарела"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb, telegram, inputs := newDialogBot(t)
+			tb.handleMessage(message(chatID, alice, "/order"))
+
+			tb.handleDialogCallback(&tgbotapi.CallbackQuery{
+				ID:      "1",
+				From:    &tgbotapi.User{ID: test.userID},
+				Message: message(chatID, alice, ""),
+				Data:    test.data,
+			})
+
+			if got := tb.chats.get(chatID, alice).State; got != test.wantState {
+				t.Errorf("состояние %q, ожидалось %q", got, test.wantState)
+			}
+			if strings.Join(*inputs, ",") != strings.Join(test.wantInputs, ",") {
+				t.Errorf("вводы %q, ожидалось %q", *inputs, test.wantInputs)
+			}
+			if test.wantReply != "" && !telegram.sent(test.wantReply) {
+				t.Errorf("ответы %q, ожидалось %q", telegram.texts, test.wantReply)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// gridUsage описывает команды управления сеткой
+const gridUsage = `Сеточная стратегия выставляет лестницу лимитных заявок и после каждого исполнения выставляет встречную заявку на соседнем уровне.
+
+/grid start BTC-USDT <нижняя> <верхняя> <уровней> <arith|geom> <количество на уровень>
+/grid status — состояние сетки
+/grid pause — снять заявки, сохранив параметры
+/grid resume — выставить заявки заново
+/grid stop — остановить сетку
+
+Одновременно у пользователя может работать только одна сетка.
+Отмененная через /cancel заявка сетки освобождает уровень: он будет выставлен снова после исполнения соседнего уровня.
+
+Пример: /grid start BTC-USDT 60000 70000 10 arith 0.0005`
+
+// handleGrid обрабатывает команду /grid и ее подкоманды
+func (tb *TelegramBot) handleGrid(chatID, userID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, gridUsage))
+		return
+	}
+
+	var (
+		grid strategy.Grid
+		err  error
+	)
+
+	switch fields[0] {
+	case "start":
+		var cfg strategy.GridConfig
+		cfg, err = tb.parseGridConfig(fields[1:])
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n\n"+gridUsage))
+			return
+		}
+
+		var price decimal.Decimal
+		price, err = tb.getPriceWithRetries(cfg.Token)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения цены: "+err.Error()))
+			return
+		}
+		grid, err = tb.Grids.Start(userID, cfg, price)
+
+	case "status":
+		var ok bool
+		grid, ok = tb.Grids.Status(userID)
+		if !ok {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Сетка не запущена.\n\n"+gridUsage))
+			return
+		}
+
+	case "pause":
+		grid, err = tb.Grids.Pause(userID)
+
+	case "resume":
+		status, ok := tb.Grids.Status(userID)
+		if !ok {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Сетка не запущена."))
+			return
+		}
+
+		var price decimal.Decimal
+		price, err = tb.getPriceWithRetries(status.Config.Token)
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения цены: "+err.Error()))
+			return
+		}
+		grid, err = tb.Grids.Resume(userID, price)
+
+	case "stop":
+		grid, err = tb.Grids.Stop(userID)
+		if err == nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Сетка остановлена.\n"+formatGrid(grid)))
+			return
+		}
+
+	default:
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, gridUsage))
+		return
+	}
+
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)))
+		return
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, formatGrid(grid)))
+}
+
+// parseGridConfig разбирает аргументы "ТОКЕН НИЖНЯЯ ВЕРХНЯЯ УРОВНЕЙ arith|geom КОЛИЧЕСТВО"
+func (tb *TelegramBot) parseGridConfig(fields []string) (strategy.GridConfig, error) {
+	if len(fields) != 6 {
+		return strategy.GridConfig{}, fmt.Errorf("неверное количество аргументов")
+	}
+
+	cfg := strategy.GridConfig{Token: normalizeSymbol(fields[0])}
+	if !tb.isValidAsset(cfg.Token) {
+		return cfg, fmt.Errorf("недействительный актив %s", cfg.Token)
+	}
+
+	var err error
+	if cfg.Lower, err = parsePrice(fields[1]); err != nil {
+		return cfg, err
+	}
+	if cfg.Upper, err = parsePrice(fields[2]); err != nil {
+		return cfg, err
+	}
+	if cfg.Levels, err = strconv.Atoi(fields[3]); err != nil {
+		return cfg, fmt.Errorf("неверное количество уровней %s", fields[3])
+	}
+
+	switch fields[4] {
+	case "arith":
+	case "geom":
+		cfg.Geometric = true
+	default:
+		return cfg, fmt.Errorf("шаг сетки должен быть arith или geom")
+	}
+
+	var ok bool
+	if cfg.Quantity, ok = parsePositive(fields[5]); !ok {
+		return cfg, fmt.Errorf("неверное количество на уровень %s", fields[5])
+	}
+	if err = tb.checkQuantity(cfg.Token, cfg.Quantity); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// formatGrid описывает параметры и статистику сетки
+func formatGrid(grid strategy.Grid) string {
+	status := "работает"
+	if grid.Status == strategy.GridPaused {
+		status = "на паузе"
+	}
+	spacing := "арифметический"
+	if grid.Config.Geometric {
+		spacing = "геометрический"
+	}
+
+	return fmt.Sprintf("Сетка %s: %s\nДиапазон: $%s – $%s, уровней: %d, шаг: %s\nРазмер уровня: %s\nОткрытых заявок: %d\nИсполнений: %d, прибыль продаж: $%s, комиссии: $%s\nЗапущена: %s",
+		grid.Config.Token, status, grid.Config.Lower, grid.Config.Upper, grid.Config.Levels, spacing,
+		formatQuantity(grid.Config.Quantity), len(grid.Orders), grid.Fills, formatUSD(grid.Profit), formatFee(grid.Fees), grid.StartedAt.Format("02.01.2006 15:04"))
+}
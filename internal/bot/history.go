@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// historyPageSize задает количество сделок на одной странице /history
+const historyPageSize = 5
+
+// historyCallbackPrefix отмечает callback-данные кнопок листания истории: "history:<владелец>:<смещение>"
+const historyCallbackPrefix = "history:"
+
+// sendHistory отправляет первую страницу журнала сделок
+func (tb *TelegramBot) sendHistory(message *tgbotapi.Message) {
+	text, keyboard, err := tb.historyPage(message.From.ID, 0)
+	if err != nil {
+		log.Printf("Ошибка получения истории сделок: %v", err)
+		tb.Bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Ошибка получения истории сделок."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	tb.Bot.Send(msg)
+}
+
+// handleHistoryCallback перелистывает журнал сделок в исходном сообщении.
+// Листать журнал может только его владелец: в группе другие участники получают предупреждение
+func (tb *TelegramBot) handleHistoryCallback(query *tgbotapi.CallbackQuery) {
+	rawOwner, rawOffset, _ := strings.Cut(strings.TrimPrefix(query.Data, historyCallbackPrefix), ":")
+	owner, ownerErr := strconv.ParseInt(rawOwner, 10, 64)
+	offset, err := strconv.Atoi(rawOffset)
+	if ownerErr != nil || err != nil || offset < 0 || query.Message == nil {
+		tb.Bot.Request(tgbotapi.NewCallback(query.ID, "Некорректная страница"))
+		return
+	}
+	if owner != query.From.ID {
+		tb.Bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, "Это история сделок другого пользователя. Отправьте /history, чтобы открыть свою."))
+		return
+	}
+
+	text, keyboard, err := tb.historyPage(query.From.ID, offset)
+	if err != nil {
+		log.Printf("Ошибка получения истории сделок: %v", err)
+		tb.Bot.Request(tgbotapi.NewCallback(query.ID, "Ошибка получения истории"))
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	edit.ReplyMarkup = keyboard
+	tb.Bot.Send(edit)
+	tb.Bot.Request(tgbotapi.NewCallback(query.ID, ""))
+}
+
+// historyPage формирует текст страницы журнала и кнопки листания
+func (tb *TelegramBot) historyPage(userID int64, offset int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	// Запрашиваем на одну сделку больше, чтобы узнать, есть ли следующая страница
+	trades, err := tb.Portfolios.History(userID, offset, historyPageSize+1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(trades) == 0 {
+		if offset == 0 {
+			return "История сделок пуста.", nil, nil
+		}
+		return "На этой странице нет сделок.", nil, nil
+	}
+
+	hasNext := len(trades) > historyPageSize
+	if hasNext {
+		trades = trades[:historyPageSize]
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("История сделок (%d–%d):\n\n", offset+1, offset+len(trades)))
+	for _, trade := range trades {
+		side := "ПОКУПКА"
+		if trade.Side == trader.SideSell {
+			side = "ПРОДАЖА"
+		}
+
+		text.WriteString(fmt.Sprintf("#%d %s %s %s\n", trade.ID, trade.Time.Format("02.01.2006 15:04"), side, trade.Token))
+		text.WriteString(fmt.Sprintf("Количество: %s по $%s, сумма: $%s, комиссия: $%s\n",
+			formatQuantity(trade.Quantity), formatPrice(trade.Price), formatUSD(trade.QuoteAmount), formatFee(trade.Fee)))
+		text.WriteString(fmt.Sprintf("Реализованный PnL: $%s, остаток USDT: $%s\n\n", formatUSD(trade.RealizedPnL), formatUSD(trade.CashAfter)))
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	if offset > 0 {
+		prev := offset - historyPageSize
+		if prev < 0 {
+			prev = 0
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("◀ Новее", historyCallback(userID, prev)))
+	}
+	if hasNext {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("Старее ▶", historyCallback(userID, offset+historyPageSize)))
+	}
+
+	if len(buttons) == 0 {
+		return text.String(), nil, nil
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+	return text.String(), &keyboard, nil
+}
+
+// historyCallback формирует callback-данные кнопки листания журнала пользователя userID
+func historyCallback(userID int64, offset int) string {
+	return fmt.Sprintf("%s%d:%d", historyCallbackPrefix, userID, offset)
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения фоновых задач вроде бэктеста: сколько задач пользователь может запустить одновременно
+// и сколько длится одна задача, прежде чем будет прервана
+const (
+	jobsPerUser = 1
+	jobTimeout  = 2 * time.Minute
+)
+
+// userJobs считает выполняющиеся фоновые задачи каждого пользователя
+type userJobs struct {
+	mu      sync.Mutex
+	running map[int64]int
+}
+
+func newUserJobs() *userJobs {
+	return &userJobs{running: make(map[int64]int)}
+}
+
+// acquire занимает место под задачу пользователя; false означает, что лимит исчерпан
+func (j *userJobs) acquire(userID int64) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running[userID] >= jobsPerUser {
+		return false
+	}
+	j.running[userID]++
+	return true
+}
+
+// release освобождает место, занятое acquire
+func (j *userJobs) release(userID int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running[userID]--; j.running[userID] <= 0 {
+		delete(j.running, userID)
+	}
+}
+
+// startJob запускает задачу пользователя в фоне с ограничением по времени jobTimeout.
+// Если у пользователя уже выполняется jobsPerUser задач, новая не запускается и пользователь получает отказ
+func (tb *TelegramBot) startJob(chatID, userID int64, job func(ctx context.Context)) bool {
+	if !tb.jobs.acquire(userID) {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Предыдущий запрос еще выполняется. Дождитесь результата и повторите."))
+		return false
+	}
+
+	go func() {
+		defer tb.jobs.release(userID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+		defer cancel()
+		job(ctx)
+	}()
+	return true
+}
+
+// jobError описывает ошибку фоновой задачи, заменяя истечение срока понятным сообщением
+func jobError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("запрос не уложился в %s и был остановлен", jobTimeout)
+	}
+	return err.Error()
+}
@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// dialogCallbackPrefix отмечает callback-данные кнопок диалогов: "dlg:<владелец>:<состояние>:<значение>".
+// Клавиатуры строятся без владельца, он дописывается при отправке в reply и replyKeyboard
+const dialogCallbackPrefix = "dlg:"
+
+// Значения кнопок диалогов
+const (
+	callbackYes      = "yes"
+	callbackNo       = "no"
+	callbackQuantity = "qty"      // Размер заявки в количестве токенов
+	callbackNotional = "usd"      // Размер заявки в USDT
+	callbackBuy      = "buy"      // Сторона лимитной заявки
+	callbackSell     = "sell"     // Сторона лимитной заявки
+	callbackPage     = "page:"    // Листание списка инструментов: "page:2"
+	callbackPercent  = "percent:" // Доля средств или позиции: "percent:25"
+	instrumentsPage  = 12         // Количество инструментов на странице клавиатуры
+	instrumentsInRow = 3
+)
+
+// amountPresets задает доли USDT или позиции на кнопках размера заявки
+var amountPresets = []int{10, 25, 50, 100}
+
+// dialogCallback формирует callback-данные кнопки шага state
+func dialogCallback(state dialogState, value string) string {
+	return dialogCallbackPrefix + string(state) + ":" + value
+}
+
+// ownKeyboard возвращает копию клавиатуры диалога, кнопки которой принадлежат пользователю userID
+func ownKeyboard(keyboard tgbotapi.InlineKeyboardMarkup, userID int64) tgbotapi.InlineKeyboardMarkup {
+	owner := dialogCallbackPrefix + strconv.FormatInt(userID, 10) + ":"
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(keyboard.InlineKeyboard))
+	for i, row := range keyboard.InlineKeyboard {
+		rows[i] = make([]tgbotapi.InlineKeyboardButton, len(row))
+		for j, button := range row {
+			if button.CallbackData != nil {
+				if value, ok := strings.CutPrefix(*button.CallbackData, dialogCallbackPrefix); ok {
+					data := owner + value
+					button.CallbackData = &data
+				}
+			}
+			rows[i][j] = button
+		}
+	}
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// parseDialogCallback разбирает callback-данные кнопки диалога на владельца, шаг и значение
+func parseDialogCallback(data string) (owner int64, state dialogState, value string, ok bool) {
+	rawOwner, rest, found := strings.Cut(strings.TrimPrefix(data, dialogCallbackPrefix), ":")
+	if !found {
+		return 0, "", "", false
+	}
+	owner, err := strconv.ParseInt(rawOwner, 10, 64)
+	if err != nil {
+		return 0, "", "", false
+	}
+	rawState, value, _ := strings.Cut(rest, ":")
+	return owner, dialogState(rawState), value, true
+}
+
+// instrumentKeyboard формирует страницу клавиатуры выбора инструмента для шага state
+func instrumentKeyboard(state dialogState, instIDs []string, page int) tgbotapi.InlineKeyboardMarkup {
+	pages := (len(instIDs) + instrumentsPage - 1) / instrumentsPage
+	page = max(0, min(page, pages-1))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, instID := range instIDs[page*instrumentsPage : min(len(instIDs), (page+1)*instrumentsPage)] {
+		base, _, _ := strings.Cut(instID, "-")
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(base, dialogCallback(state, instID)))
+		if len(row) == instrumentsInRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀", dialogCallback(state, callbackPage+strconv.Itoa(page-1))))
+	}
+	if page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d ▶", page+2, pages), dialogCallback(state, callbackPage+strconv.Itoa(page+1))))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// askInstrument предлагает выбрать инструмент из instIDs на шаге state. Если выбирать не из чего,
+// например справочник инструментов еще не загружен, кнопки не показываются и символ вводится текстом
+func (tb *TelegramBot) askInstrument(req request, state dialogState, prompt string, instIDs []string) {
+	if len(instIDs) == 0 {
+		tb.reply(req, prompt+"\nСписок для выбора пуст, введите символ текстом.", nil)
+		return
+	}
+	keyboard := instrumentKeyboard(state, instIDs, 0)
+	tb.reply(req, prompt, &keyboard)
+}
+
+// parsePage разбирает значение кнопки листания списка
+func parsePage(value string) (int, bool) {
+	raw, ok := strings.CutPrefix(value, callbackPage)
+	if !ok {
+		return 0, false
+	}
+	page, err := strconv.Atoi(raw)
+	return page, err == nil && page >= 0
+}
+
+// amountKeyboard формирует кнопки долей USDT (покупка) или позиции (продажа)
+func amountKeyboard(side string) tgbotapi.InlineKeyboardMarkup {
+	of := "USDT"
+	if side == trader.SideSell {
+		of = "позиции"
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, percent := range amountPresets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d%% %s", percent, of), dialogCallback(stateOrderAmount, callbackPercent+strconv.Itoa(percent))))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row[:2], row[2:])
+}
+
+// parsePercent разбирает значение кнопки доли средств
+func parsePercent(value string) (decimal.Decimal, bool) {
+	raw, ok := strings.CutPrefix(value, callbackPercent)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return parsePositive(raw)
+}
+
+// createOrderModeKeyboard формирует кнопки выбора режима заявки
+func createOrderModeKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(orderModeQuantity, dialogCallback(stateOrderMode, callbackQuantity)),
+			tgbotapi.NewInlineKeyboardButtonData(orderModeNotional, dialogCallback(stateOrderMode, callbackNotional)),
+		),
+	)
+}
+
+// createSideKeyboard формирует кнопки выбора стороны лимитной заявки
+func createSideKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(limitSideBuy, dialogCallback(stateLimitSide, callbackBuy)),
+			tgbotapi.NewInlineKeyboardButtonData(limitSideSell, dialogCallback(stateLimitSide, callbackSell)),
+		),
+	)
+}
+
+// createConfirmKeyboard формирует кнопки подтверждения заявки на шаге state
+func createConfirmKeyboard(state dialogState) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(confirmYes, dialogCallback(state, callbackYes)),
+			tgbotapi.NewInlineKeyboardButtonData(confirmNo, dialogCallback(state, callbackNo)),
+		),
+	)
+}
+
+// isConfirmed сообщает, подтвердил ли пользователь заявку кнопкой или текстом
+func isConfirmed(text string) bool {
+	return text == callbackYes || text == confirmYes
+}
+
+// reply отвечает на шаг диалога: нажатие кнопки редактирует исходное сообщение, текстовый ввод получает новое.
+// keyboard равен nil, если кнопки больше не нужны
+func (tb *TelegramBot) reply(req request, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if req.Callback != nil && req.Callback.Message != nil {
+		edit := tgbotapi.NewEditMessageText(req.ChatID, req.Callback.Message.MessageID, text)
+		if keyboard != nil {
+			owned := ownKeyboard(*keyboard, req.UserID)
+			edit.ReplyMarkup = &owned
+		}
+		tb.Bot.Send(edit)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(req.ChatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = ownKeyboard(*keyboard, req.UserID)
+	}
+	tb.Bot.Send(msg)
+}
+
+// replyKeyboard заменяет кнопки исходного сообщения при листании, не меняя его текст
+func (tb *TelegramBot) replyKeyboard(req request, keyboard tgbotapi.InlineKeyboardMarkup) {
+	if req.Callback == nil || req.Callback.Message == nil {
+		return
+	}
+	tb.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(req.ChatID, req.Callback.Message.MessageID, ownKeyboard(keyboard, req.UserID)))
+}
+
+// selectInstrument разбирает выбор инструмента на шаге state: листает клавиатуру со списком instIDs,
+// а на символ с ошибкой предлагает найденные пары. Возвращает выбранный инструмент
+func (tb *TelegramBot) selectInstrument(req request, state dialogState, instIDs []string) (string, bool) {
+	if page, ok := parsePage(req.Text); ok {
+		tb.replyKeyboard(req, instrumentKeyboard(state, instIDs, page))
+		return "", false
+	}
+	if symbol := normalizeSymbol(req.Text); tb.isValidAsset(symbol) {
+		return symbol, true
+	}
+
+	found := tb.Instruments.Search(req.Text, instrumentsPage)
+	if len(found) == 0 {
+		tb.reply(req, "Недействительный актив. Попробуйте снова.", nil)
+		return "", false
+	}
+
+	suggestions := make([]string, 0, len(found))
+	for _, instrument := range found {
+		suggestions = append(suggestions, instrument.InstID)
+	}
+	keyboard := instrumentKeyboard(state, suggestions, 0)
+	tb.reply(req, "Недействительный актив. Возможно, вы имели в виду:", &keyboard)
+	return "", false
+}
+
+// tradableTokens возвращает инструменты для клавиатуры выбора: все пары к USDT для покупки и позиции для продажи
+func (tb *TelegramBot) tradableTokens(side string, portfolio *trader.Trader) []string {
+	if side != trader.SideSell {
+		return tb.instrumentIDs()
+	}
+
+	balance, err := portfolio.GetBalance()
+	if err != nil {
+		return nil
+	}
+	var instIDs []string
+	for _, position := range balance.Positions {
+		instIDs = append(instIDs, position.Token)
+	}
+	return instIDs
+}
+
+// instrumentIDs возвращает все доступные пары к USDT
+func (tb *TelegramBot) instrumentIDs() []string {
+	var instIDs []string
+	for _, instrument := range tb.Instruments.List() {
+		instIDs = append(instIDs, instrument.InstID)
+	}
+	return instIDs
+}
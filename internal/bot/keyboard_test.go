@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram принимает callback-данные длиной не больше 64 байт
+const maxCallbackData = 64
+
+func TestDialogCallbackData(t *testing.T) {
+	instIDs := make([]string, 30)
+	for i := range instIDs {
+		instIDs[i] = fmt.Sprintf("LONGTOKEN%02d-USDT", i)
+	}
+
+	tests := []struct {
+		name     string
+		keyboard tgbotapi.InlineKeyboardMarkup
+	}{
+		{name: "режим заявки", keyboard: createOrderModeKeyboard()},
+		{name: "сторона лимитной заявки", keyboard: createSideKeyboard()},
+		{name: "подтверждение", keyboard: createConfirmKeyboard(stateLimitConfirm)},
+		{name: "доли позиции", keyboard: amountKeyboard(trader.SideSell)},
+		{name: "инструменты", keyboard: instrumentKeyboard(stateLimitQuantity, instIDs, 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			owned := ownKeyboard(test.keyboard, math.MinInt64)
+			for _, row := range owned.InlineKeyboard {
+				for _, button := range row {
+					data := *button.CallbackData
+					if len(data) > maxCallbackData {
+						t.Errorf("кнопка %q: %d байт данных %q", button.Text, len(data), data)
+					}
+					for _, r := range data {
+						if r > 127 {
+							t.Errorf("кнопка %q: данные %q не ASCII", button.Text, data)
+							break
+						}
+					}
+					if owner, _, _, ok := parseDialogCallback(data); !ok || owner != math.MinInt64 {
+						t.Errorf("кнопка %q: данные %q разобраны как %d, %v", button.Text, data, owner, ok)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseDialogCallback(t *testing.T) {
+	tests := []struct {
+		data      string
+		wantOwner int64
+		wantState dialogState
+		wantValue string
+		wantOK    bool
+	}{
+		{data: "dlg:42:order.mode:qty", wantOwner: 42, wantState: stateOrderMode, wantValue: callbackQuantity, wantOK: true},
+		{data: "dlg:42:order.asset:page:2", wantOwner: 42, wantState: stateOrderAsset, wantValue: "page:2", wantOK: true},
+		{data: "dlg:-7:limit.side", wantOwner: -7, wantState: stateLimitSide, wantOK: true},
+		{data: "dlg:order.mode:qty"},
+		{data: "dlg:42"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.data, func(t *testing.T) {
+			owner, state, value, ok := parseDialogCallback(test.data)
+			if owner != test.wantOwner || state != test.wantState || value != test.wantValue || ok != test.wantOK {
+				t.Errorf("%q: получено %d, %q, %q, %v, ожидалось %d, %q, %q, %v",
+					test.data, owner, state, value, ok, test.wantOwner, test.wantState, test.wantValue, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestInstrumentKeyboardPages(t *testing.T) {
+	instIDs := make([]string, 25)
+	for i := range instIDs {
+		instIDs[i] = fmt.Sprintf("T%02d-USDT", i)
+	}
+
+	tests := []struct {
+		name      string
+		page      int
+		wantFirst string
+		wantNav   []string
+	}{
+		{name: "первая", page: 0, wantFirst: "T00", wantNav: []string{"2/3 ▶"}},
+		{name: "средняя", page: 1, wantFirst: "T12", wantNav: []string{"◀", "3/3 ▶"}},
+		{name: "последняя", page: 2, wantFirst: "T24", wantNav: []string{"◀"}},
+		{name: "за пределами списка", page: 9, wantFirst: "T24", wantNav: []string{"◀"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := instrumentKeyboard(stateOrderAsset, instIDs, test.page).InlineKeyboard
+			if got := rows[0][0].Text; got != test.wantFirst {
+				t.Errorf("первая кнопка %q, ожидалась %q", got, test.wantFirst)
+			}
+			nav := rows[len(rows)-1]
+			if len(nav) != len(test.wantNav) {
+				t.Fatalf("кнопок листания %d, ожидалось %d", len(nav), len(test.wantNav))
+			}
+			for i, want := range test.wantNav {
+				if nav[i].Text != want {
+					t.Errorf("кнопка листания %d: %q, ожидалась %q", i, nav[i].Text, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/strategy"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Подписи кнопок выбора стороны лимитной заявки; их можно ввести и текстом
+const (
+	limitSideBuy  = "Купить"
+	limitSideSell = "Продать"
+)
+
+// limitDraft хранит параметры лимитной заявки, введенные пользователем
+type limitDraft struct {
+	Side     string
+	Token    string
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+}
+
+// registerLimitHandlers регистрирует команды лимитных заявок и шаги диалога их создания
+func (tb *TelegramBot) registerLimitHandlers() {
+	tb.handleCommand("/limit", commandHandler{Portfolio: true, Handle: tb.startLimit})
+	tb.handleCommand("/orders", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.sendOrders(req.ChatID, req.Portfolio)
+		return stateIdle
+	}})
+	tb.handleCommand("/cancel", commandHandler{Portfolio: true, Handle: func(req request) dialogState {
+		tb.cancelOrder(req.ChatID, req.Portfolio, req.Args)
+		return stateIdle
+	}})
+
+	tb.handleState(stateLimitSide, stateHandler{Portfolio: true, Handle: tb.handleLimitSide})
+	tb.handleState(stateLimitToken, stateHandler{Portfolio: true, Handle: tb.handleLimitToken})
+	tb.handleState(stateLimitQuantity, stateHandler{Handle: tb.handleLimitQuantity})
+	tb.handleState(stateLimitPrice, stateHandler{Handle: tb.handleLimitPrice})
+	tb.handleState(stateLimitConfirm, stateHandler{Portfolio: true, Handle: tb.handleLimitConfirm})
+}
+
+// startLimit начинает диалог создания лимитной заявки; "/limit buy BTC 0.001 @ 60000" сразу показывает заявку для подтверждения
+func (tb *TelegramBot) startLimit(req request) dialogState {
+	if req.Args != "" {
+		draft, err := tb.parseLimitArgs(req.Args)
+		if err != nil {
+			tb.reply(req, "Ошибка: "+explainOrderError(err)+"\n\n"+limitUsage, nil)
+			return stateIdle
+		}
+
+		req.Chat.Limit = draft
+		return tb.confirmLimit(req)
+	}
+
+	keyboard := createSideKeyboard()
+	tb.reply(req, "Лимитная заявка: выберите сторону. Отмена: /cancel", &keyboard)
+	return stateLimitSide
+}
+
+// handleLimitSide принимает сторону лимитной заявки
+func (tb *TelegramBot) handleLimitSide(req request) dialogState {
+	switch req.Text {
+	case callbackBuy, limitSideBuy:
+		req.Chat.Limit.Side = trader.SideBuy
+	case callbackSell, limitSideSell:
+		req.Chat.Limit.Side = trader.SideSell
+	default:
+		tb.reply(req, "Выберите сторону кнопкой.", nil)
+		return stateLimitSide
+	}
+
+	tb.askInstrument(req, stateLimitToken, "Выберите токен или введите символ (например, BTC-USDT):", tb.tradableTokens(req.Chat.Limit.Side, req.Portfolio))
+	return stateLimitToken
+}
+
+// handleLimitToken принимает токен лимитной заявки
+func (tb *TelegramBot) handleLimitToken(req request) dialogState {
+	token, ok := tb.selectInstrument(req, stateLimitToken, tb.tradableTokens(req.Chat.Limit.Side, req.Portfolio))
+	if !ok {
+		return stateLimitToken
+	}
+
+	req.Chat.Limit.Token = token
+	tb.reply(req, fmt.Sprintf("Токен: %s\nВведите количество токенов:", token), nil)
+	return stateLimitQuantity
+}
+
+// handleLimitQuantity принимает количество, кратное шагу лота
+func (tb *TelegramBot) handleLimitQuantity(req request) dialogState {
+	quantity, ok := parsePositive(req.Text)
+	if !ok {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Неверное количество. Попробуйте снова."))
+		return stateLimitQuantity
+	}
+	if err := tb.checkQuantity(req.Chat.Limit.Token, quantity); err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, explainOrderError(err)+" Попробуйте снова."))
+		return stateLimitQuantity
+	}
+
+	req.Chat.Limit.Quantity = quantity
+	tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Введите лимитную цену в USDT:"))
+	return stateLimitPrice
+}
+
+// handleLimitPrice принимает лимитную цену, кратную шагу цены, и показывает заявку для подтверждения
+func (tb *TelegramBot) handleLimitPrice(req request) dialogState {
+	price, ok := parsePositive(req.Text)
+	if !ok {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Неверная цена. Попробуйте снова."))
+		return stateLimitPrice
+	}
+	if err := tb.checkPrice(req.Chat.Limit.Token, price); err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, explainOrderError(err)+" Попробуйте снова."))
+		return stateLimitPrice
+	}
+
+	req.Chat.Limit.Price = price
+	return tb.confirmLimit(req)
+}
+
+// confirmLimit показывает лимитную заявку и ждет подтверждения
+func (tb *TelegramBot) confirmLimit(req request) dialogState {
+	keyboard := createConfirmKeyboard(stateLimitConfirm)
+	tb.reply(req, formatLimitDraft(&req.Chat.Limit)+"\n\nПодтвердить?", &keyboard)
+	return stateLimitConfirm
+}
+
+// handleLimitConfirm выставляет подтвержденную лимитную заявку
+func (tb *TelegramBot) handleLimitConfirm(req request) dialogState {
+	if !isConfirmed(req.Text) {
+		tb.reply(req, "Заявка отменена.", nil)
+		return stateIdle
+	}
+
+	draft := req.Chat.Limit
+	order, err := req.Portfolio.PlaceLimit(draft.Side, draft.Token, draft.Quantity, draft.Price, "")
+	if err != nil {
+		tb.reply(req, "Заявка невозможна: "+explainOrderError(err), nil)
+		return stateIdle
+	}
+
+	tb.reply(req, "Заявка создана:\n"+formatOrder(order), nil)
+	return stateIdle
+}
+
+// sendOrders отправляет список открытых заявок
+func (tb *TelegramBot) sendOrders(chatID int64, portfolio *trader.Trader) {
+	orders := portfolio.OpenOrders()
+	if len(orders) == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Открытых заявок нет."))
+		return
+	}
+
+	message := "Открытые заявки:\n"
+	for _, order := range orders {
+		message += formatOrder(order) + "\n"
+	}
+	message += "\nДля отмены отправьте /cancel <номер заявки>"
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, message))
+}
+
+// cancelOrder отменяет заявку по номеру из текста команды /cancel
+func (tb *TelegramBot) cancelOrder(chatID int64, portfolio *trader.Trader, args string) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(args), "#"), 10, 64)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Укажите номер заявки: /cancel <номер>. Список заявок: /orders"))
+		return
+	}
+
+	order, err := portfolio.CancelOrder(id)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка отмены: "+err.Error()))
+		return
+	}
+
+	text := "Заявка отменена:\n" + formatOrder(order)
+	if order.Tag == strategy.GridTag {
+		tb.Grids.OnCancel(portfolio.UserID, order)
+		text += "\nУровень сетки освобожден и будет выставлен снова после исполнения соседнего уровня."
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// NotifyFill сообщает владельцу об исполнении отложенной заявки
+func (tb *TelegramBot) NotifyFill(userID int64, fill trader.Fill) {
+	text := fmt.Sprintf("Заявка #%d исполнена.\n%s", fill.Order.ID, formatTrade(fill.Trade))
+	if fill.Err != nil {
+		text = fmt.Sprintf("Заявка #%d сработала, но не исполнена: %v", fill.Order.ID, fill.Err)
+	}
+	for _, canceled := range fill.Canceled {
+		text += fmt.Sprintf("\nСвязанная заявка #%d отменена.", canceled.ID)
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(tb.notify.chat(userID), text))
+}
+
+// formatLimitDraft описывает лимитную заявку перед подтверждением
+func formatLimitDraft(draft *limitDraft) string {
+	side := "Покупка"
+	if draft.Side == trader.SideSell {
+		side = "Продажа"
+	}
+	return fmt.Sprintf("%s %s\nКоличество: %s\nЛимитная цена: $%s\nСумма: $%s",
+		side, draft.Token, formatQuantity(draft.Quantity), draft.Price, formatUSD(draft.Quantity.Mul(draft.Price)))
+}
+
+// formatOrder описывает открытую заявку одной строкой
+func formatOrder(order trader.Order) string {
+	side := "покупка"
+	if order.Side == trader.SideSell {
+		side = "продажа"
+	}
+
+	var condition string
+	switch order.Type {
+	case trader.OrderLimit:
+		condition = fmt.Sprintf("лимит $%s", order.Price)
+	case trader.OrderStop:
+		condition = fmt.Sprintf("стоп $%s", order.TriggerPrice)
+	case trader.OrderTakeProfit:
+		condition = fmt.Sprintf("тейк-профит $%s", order.TriggerPrice)
+	case trader.OrderTrailingStop:
+		distance := fmt.Sprintf("$%s", order.TrailAmount)
+		if order.TrailPercent.IsPositive() {
+			distance = fmt.Sprintf("%s%%", order.TrailPercent)
+		}
+		condition = fmt.Sprintf("трейлинг-стоп %s, уровень $%s", distance, formatPrice(order.TriggerPrice))
+	}
+
+	line := fmt.Sprintf("#%d %s %s %s, %s", order.ID, side, order.Token, formatQuantity(order.Quantity), condition)
+	switch {
+	case order.Side == trader.SideBuy && order.Reserved.IsPositive():
+		line += fmt.Sprintf(" (резерв $%s)", formatUSD(order.Reserved))
+	case order.Side == trader.SideSell && order.Reserved.IsPositive():
+		line += fmt.Sprintf(" (резерв %s токенов)", formatQuantity(order.Reserved))
+	}
+	if order.OCOGroup != 0 {
+		line += fmt.Sprintf(" [OCO %d]", order.OCOGroup)
+	}
+	if order.Tag != "" {
+		line += fmt.Sprintf(" [%s]", order.Tag)
+	}
+	return line
+}
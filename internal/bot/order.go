@@ -0,0 +1,321 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Подписи кнопок выбора режима заявки и подтверждения; их можно ввести и текстом
+const (
+	orderModeQuantity = "Количество токенов"
+	orderModeNotional = "Сумма в USDT"
+	confirmYes        = "Подтвердить"
+	confirmNo         = "Отмена"
+)
+
+// quoteTTL задает время, в течение которого котировку можно подтвердить
+const quoteTTL = time.Minute
+
+// orderDraft хранит параметры рыночной заявки, введенные пользователем
+type orderDraft struct {
+	Side  string
+	Token string
+	Mode  trader.OrderMode
+	Quote trader.Quote // Котировка, ожидающая подтверждения
+}
+
+// registerOrderHandlers регистрирует команды /buy и /sell и шаги диалога рыночной заявки
+func (tb *TelegramBot) registerOrderHandlers() {
+	tb.handleCommand("/buy", commandHandler{Portfolio: true, Handle: tb.startBuy})
+	tb.handleCommand("/sell", commandHandler{Portfolio: true, Handle: tb.startSell})
+
+	tb.handleState(stateOrderAsset, stateHandler{Portfolio: true, Handle: tb.handleOrderAsset})
+	tb.handleState(stateOrderMode, stateHandler{Handle: tb.handleOrderMode})
+	tb.handleState(stateOrderAmount, stateHandler{Portfolio: true, Handle: tb.handleOrderAmount})
+	tb.handleState(stateOrderConfirm, stateHandler{
+		Portfolio: true,
+		Timeout:   quoteTTL,
+		Expired:   "Котировка устарела, создайте заявку заново.",
+		Handle:    tb.handleOrderConfirm,
+	})
+}
+
+// startBuy начинает диалог покупки; "/buy BTC 25usdt" сразу показывает заявку для подтверждения
+func (tb *TelegramBot) startBuy(req request) dialogState {
+	if req.Args != "" {
+		return tb.quickOrder(req, trader.SideBuy, buyUsage)
+	}
+
+	usdtValue := req.Portfolio.GetCapital()
+	msg := fmt.Sprintf("Ваш баланс USDT: %s\nДоступно пар к USDT: %d, поиск: /assets <запрос>\n\nВыберите токен для покупки или введите символ (например, BTC-USDT). Отмена: /cancel", formatUSD(usdtValue), len(tb.Instruments.List()))
+	tb.askInstrument(req, stateOrderAsset, msg, tb.tradableTokens(trader.SideBuy, req.Portfolio))
+
+	req.Chat.Order = orderDraft{Side: trader.SideBuy}
+	return stateOrderAsset
+}
+
+// startSell показывает позиции и начинает диалог продажи; "/sell SOL all" сразу показывает заявку для подтверждения
+func (tb *TelegramBot) startSell(req request) dialogState {
+	if req.Args != "" {
+		return tb.quickOrder(req, trader.SideSell, sellUsage)
+	}
+
+	balance, err := req.Portfolio.GetBalance()
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Ошибка получения баланса: "+err.Error()))
+		return stateIdle
+	}
+	if len(balance.Positions) == 0 {
+		tb.Bot.Send(tgbotapi.NewMessage(req.ChatID, "Токенов для продажи нет."))
+		return stateIdle
+	}
+
+	// Формируем сообщение со списком текущих активов
+	sellMessage := "Текущие токены для продажи:\n"
+	for _, position := range balance.Positions {
+		price, err := tb.Feed.Last(position.Token)
+		if err != nil {
+			sellMessage += fmt.Sprintf("Ошибка получения цены для %s\n", position.Token)
+			continue
+		}
+
+		sellMessage += fmt.Sprintf("Токен: %s, Количество: %s, Текущая цена: $%s\n",
+			position.Token, formatQuantity(position.Quantity()), formatPrice(price))
+	}
+	sellMessage += "\nВыберите токен для продажи или введите символ (например, BTC-USDT). Отмена: /cancel"
+
+	tb.askInstrument(req, stateOrderAsset, sellMessage, tb.tradableTokens(trader.SideSell, req.Portfolio))
+
+	req.Chat.Order = orderDraft{Side: trader.SideSell}
+	return stateOrderAsset
+}
+
+// handleOrderAsset принимает токен и спрашивает, в чем указан размер заявки
+func (tb *TelegramBot) handleOrderAsset(req request) dialogState {
+	token, ok := tb.selectInstrument(req, stateOrderAsset, tb.tradableTokens(req.Chat.Order.Side, req.Portfolio))
+	if !ok {
+		return stateOrderAsset
+	}
+
+	req.Chat.Order.Token = token
+	keyboard := createOrderModeKeyboard()
+	tb.reply(req, fmt.Sprintf("Токен: %s\nКак указать размер заявки?", token), &keyboard)
+	return stateOrderMode
+}
+
+// handleOrderMode принимает режим заявки: количество токенов или сумма в USDT
+func (tb *TelegramBot) handleOrderMode(req request) dialogState {
+	draft := &req.Chat.Order
+	var prompt string
+	switch req.Text {
+	case callbackQuantity, orderModeQuantity:
+		draft.Mode = trader.ByQuantity
+		prompt = "Введите количество токенов %s"
+	case callbackNotional, orderModeNotional:
+		draft.Mode = trader.ByNotional
+		prompt = "Введите сумму в USDT для %s"
+	default:
+		tb.reply(req, "Выберите режим кнопкой.", nil)
+		return stateOrderMode
+	}
+
+	keyboard := amountKeyboard(draft.Side)
+	tb.reply(req, fmt.Sprintf(prompt+" или выберите долю:", draft.Token), &keyboard)
+	return stateOrderAmount
+}
+
+// handleOrderAmount рассчитывает котировку по введенному размеру или доле и просит подтвердить заявку
+func (tb *TelegramBot) handleOrderAmount(req request) dialogState {
+	draft := &req.Chat.Order
+	intent := trader.OrderIntent{Side: draft.Side, Token: draft.Token, Mode: draft.Mode}
+
+	if percent, ok := parsePercent(req.Text); ok {
+		var err error
+		intent, err = tb.presetIntent(req.Portfolio, intent, percent)
+		if err != nil {
+			tb.reply(req, "Заявка невозможна: "+err.Error()+"\nВведите другое значение.", nil)
+			return stateOrderAmount
+		}
+	} else {
+		value, ok := parsePositive(req.Text)
+		if !ok {
+			tb.reply(req, "Неверное значение. Попробуйте снова.", nil)
+			return stateOrderAmount
+		}
+		intent.Value = value
+	}
+
+	price, err := tb.getPriceWithRetries(draft.Token)
+	if err != nil {
+		tb.reply(req, "Ошибка получения цены: "+err.Error(), nil)
+		return stateIdle
+	}
+
+	quote, err := req.Portfolio.Quote(intent, price)
+	if err != nil {
+		keyboard := amountKeyboard(draft.Side)
+		tb.reply(req, "Заявка невозможна: "+explainOrderError(err)+"\nВведите другое значение.", &keyboard)
+		return stateOrderAmount
+	}
+
+	return tb.confirmOrder(req, quote)
+}
+
+// quickOrder рассчитывает заявку, заданную аргументами команды, и просит ее подтвердить
+func (tb *TelegramBot) quickOrder(req request, side, usage string) dialogState {
+	intent, err := tb.parseOrderArgs(req.Portfolio, side, req.Args)
+	if err != nil {
+		tb.reply(req, "Ошибка: "+explainOrderError(err)+"\n\n"+usage, nil)
+		return stateIdle
+	}
+
+	price, err := tb.getPriceWithRetries(intent.Token)
+	if err != nil {
+		tb.reply(req, "Ошибка получения цены: "+err.Error(), nil)
+		return stateIdle
+	}
+
+	quote, err := req.Portfolio.Quote(intent, price)
+	if err != nil {
+		tb.reply(req, "Заявка невозможна: "+explainOrderError(err), nil)
+		return stateIdle
+	}
+	return tb.confirmOrder(req, quote)
+}
+
+// confirmOrder показывает итог заявки и ждет подтверждения
+func (tb *TelegramBot) confirmOrder(req request, quote trader.Quote) dialogState {
+	req.Chat.Order = orderDraft{Side: quote.Intent.Side, Token: quote.Intent.Token, Mode: quote.Intent.Mode, Quote: quote}
+	keyboard := createConfirmKeyboard(stateOrderConfirm)
+	tb.reply(req, formatQuote(quote, req.Portfolio.GetCapital())+"\n\nПодтвердить?", &keyboard)
+	return stateOrderConfirm
+}
+
+// presetIntent переводит долю в размер заявки: покупка тратит долю свободных USDT, продажа продает долю позиции
+func (tb *TelegramBot) presetIntent(portfolio *trader.Trader, intent trader.OrderIntent, percent decimal.Decimal) (trader.OrderIntent, error) {
+	share := percent.Div(decimal.NewFromInt(100))
+
+	if intent.Side == trader.SideBuy {
+		intent.Mode = trader.ByNotional
+		intent.Value = portfolio.GetCapital().Mul(share).RoundDown(2)
+		if !intent.Value.IsPositive() {
+			return intent, fmt.Errorf("недостаточно USDT")
+		}
+		return intent, nil
+	}
+
+	holding := portfolio.Holding(intent.Token)
+	if !holding.IsPositive() {
+		return intent, fmt.Errorf("нет токенов %s для продажи", intent.Token)
+	}
+	quantity := holding
+	if share.LessThan(decimal.NewFromInt(1)) {
+		var err error
+		if quantity, err = tb.roundQuantity(intent.Token, holding.Mul(share)); err != nil {
+			return intent, err
+		}
+	}
+
+	intent.Mode = trader.ByQuantity
+	intent.Value = quantity
+	return intent, nil
+}
+
+// handleOrderConfirm исполняет подтвержденную заявку
+func (tb *TelegramBot) handleOrderConfirm(req request) dialogState {
+	if !isConfirmed(req.Text) {
+		tb.reply(req, "Заявка отменена.", nil)
+		return stateIdle
+	}
+
+	trade, err := req.Portfolio.Execute(req.Chat.Order.Quote)
+	if err != nil {
+		tb.reply(req, "Ошибка исполнения заявки: "+explainOrderError(err), nil)
+		return stateIdle
+	}
+
+	tb.reply(req, formatTrade(trade), nil)
+	return stateIdle
+}
+
+// formatQuote описывает количество токенов и движение денег по заявке
+func formatQuote(quote trader.Quote, capital decimal.Decimal) string {
+	if quote.Intent.Side == trader.SideBuy {
+		return fmt.Sprintf("Покупка %s\nКоличество: %s\nРыночная цена: $%s\nЦена исполнения: $%s\nКомиссия: $%s\nСписание: $%s\nОстаток USDT после сделки: $%s",
+			quote.Intent.Token, formatQuantity(quote.Quantity), formatPrice(quote.MarkPrice), formatPrice(quote.Price),
+			formatFee(quote.Fee), formatUSD(quote.Cash), formatUSD(capital.Sub(quote.Cash)))
+	}
+	return fmt.Sprintf("Продажа %s\nКоличество: %s\nРыночная цена: $%s\nЦена исполнения: $%s\nКомиссия: $%s\nЗачисление: $%s\nОстаток USDT после сделки: $%s",
+		quote.Intent.Token, formatQuantity(quote.Quantity), formatPrice(quote.MarkPrice), formatPrice(quote.Price),
+		formatFee(quote.Fee), formatUSD(quote.Cash), formatUSD(capital.Add(quote.Cash)))
+}
+
+// formatTrade описывает исполненную сделку
+func formatTrade(trade trader.Trade) string {
+	if trade.Side == trader.SideBuy {
+		return fmt.Sprintf("Куплено %s %s по цене $%s на сумму $%s (комиссия $%s). Остаток USDT: $%s",
+			formatQuantity(trade.Quantity), trade.Token, formatPrice(trade.Price), formatUSD(trade.QuoteAmount), formatFee(trade.Fee), formatUSD(trade.CashAfter))
+	}
+	return fmt.Sprintf("Продано %s %s по цене $%s на сумму $%s (комиссия $%s). Реализованный PnL: $%s. Остаток USDT: $%s",
+		formatQuantity(trade.Quantity), trade.Token, formatPrice(trade.Price), formatUSD(trade.QuoteAmount), formatFee(trade.Fee),
+		formatUSD(trade.RealizedPnL), formatUSD(trade.CashAfter))
+}
+
+// explainOrderError объясняет, почему заявка отклонена; нарушения правил инструмента описываются подробно
+func explainOrderError(err error) string {
+	var rule *trader.RuleError
+	if !errors.As(err, &rule) {
+		return err.Error()
+	}
+
+	switch {
+	case errors.Is(rule.Err, trader.ErrLotSize):
+		return fmt.Sprintf("OKX принимает количество %s только кратным шагу лота %s. Ближайшее допустимое количество: %s.",
+			rule.Token, rule.Limit, rule.Suggested)
+	case errors.Is(rule.Err, trader.ErrMinSize):
+		return fmt.Sprintf("Минимальное количество в заявке %s на OKX — %s, указано %s.",
+			rule.Token, rule.Limit, rule.Value)
+	case errors.Is(rule.Err, trader.ErrTickSize):
+		return fmt.Sprintf("Цена %s на OKX должна быть кратна шагу цены %s. Ближайшая допустимая цена: %s.",
+			rule.Token, rule.Limit, rule.Suggested)
+	}
+	return err.Error()
+}
+
+// parsePositive разбирает положительное число из ввода пользователя без потери точности
+func parsePositive(raw string) (decimal.Decimal, bool) {
+	value, err := decimal.NewFromString(strings.TrimSpace(raw))
+	if err != nil || !value.IsPositive() {
+		return decimal.Zero, false
+	}
+	return value, true
+}
+
+// formatUSD показывает сумму в USDT с точностью до цента
+func formatUSD(amount decimal.Decimal) string {
+	return amount.StringFixed(2)
+}
+
+// formatFee показывает комиссию с точностью до сотой доли цента
+func formatFee(fee decimal.Decimal) string {
+	return fee.StringFixed(4)
+}
+
+// formatPrice показывает цены от доллара с двумя знаками, а цены дешевых токенов — до 8 знаков после запятой
+func formatPrice(price decimal.Decimal) string {
+	if price.Abs().GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return price.StringFixed(2)
+	}
+	return price.Round(8).String()
+}
+
+// formatQuantity показывает количество токенов без лишних нулей с точностью учета OKX
+func formatQuantity(quantity decimal.Decimal) string {
+	return quantity.Round(8).String()
+}
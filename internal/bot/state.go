@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatState хранит состояние диалога одного пользователя в одном чате. Обновления чата обрабатываются по очереди,
+// поэтому поля состояния меняет только один обработчик за раз
+type chatState struct {
+	State     dialogState // Текущий шаг диалога
+	EnteredAt time.Time   // Время перехода на шаг; по нему отсчитывается таймаут
+	Order     orderDraft  // Рыночная заявка в процессе ввода
+	Limit     limitDraft  // Лимитная заявка в процессе ввода
+}
+
+// reset завершает диалог и очищает введенные данные
+func (c *chatState) reset() {
+	*c = chatState{}
+}
+
+// dialogKey идентифицирует диалог: в группе у каждого участника свой диалог с ботом
+type dialogKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// chatStates хранит состояния диалогов всех чатов и пользователей и безопасно для параллельного доступа
+type chatStates struct {
+	mu    sync.Mutex
+	chats map[dialogKey]*chatState
+}
+
+func newChatStates() *chatStates {
+	return &chatStates{chats: make(map[dialogKey]*chatState)}
+}
+
+// get возвращает состояние диалога пользователя в чате, создавая его при первом обращении
+func (s *chatStates) get(chatID, userID int64) *chatState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dialogKey{ChatID: chatID, UserID: userID}
+	state, ok := s.chats[key]
+	if !ok {
+		state = &chatState{}
+		s.chats[key] = state
+	}
+	return state
+}
+
+// notifyChats запоминает, в каком чате пользователь писал боту последним: туда приходят уведомления
+// об исполнении заявок, DCA и алертах. Пока пользователь не писал, уведомления идут в личный чат,
+// идентификатор которого совпадает с идентификатором пользователя
+type notifyChats struct {
+	mu    sync.Mutex
+	chats map[int64]int64
+}
+
+func newNotifyChats() *notifyChats {
+	return &notifyChats{chats: make(map[int64]int64)}
+}
+
+// remember отмечает чат, в котором пользователь последним обратился к боту
+func (n *notifyChats) remember(userID, chatID int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.chats[userID] = chatID
+}
+
+// chat возвращает чат для уведомлений пользователя
+func (n *notifyChats) chat(userID int64) int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if chatID, ok := n.chats[userID]; ok {
+		return chatID
+	}
+	return userID
+}
+
+// dispatcher обрабатывает обновления разных чатов параллельно, а обновления одного чата — строго по очереди
+type dispatcher struct {
+	handle func(update tgbotapi.Update)
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update // Очереди чатов, у которых есть запущенный обработчик
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(handle func(update tgbotapi.Update)) *dispatcher {
+	return &dispatcher{
+		handle: handle,
+		queues: make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// dispatch ставит обновление в очередь чата и запускает обработчик очереди, если он еще не работает
+func (d *dispatcher) dispatch(chatID int64, update tgbotapi.Update) {
+	d.mu.Lock()
+	queue, running := d.queues[chatID]
+	d.queues[chatID] = append(queue, update)
+	d.mu.Unlock()
+
+	if !running {
+		d.wg.Add(1)
+		go d.drain(chatID)
+	}
+}
+
+// drain обрабатывает очередь чата, пока она не опустеет
+func (d *dispatcher) drain(chatID int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[chatID]
+		if len(queue) == 0 {
+			delete(d.queues, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[chatID] = queue[1:]
+		d.mu.Unlock()
+
+		d.handle(update)
+	}
+}
+
+// wait дожидается обработки всех поставленных в очередь обновлений
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// updateChatID возвращает чат, к которому относится обновление
+func updateChatID(update tgbotapi.Update) (int64, bool) {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID, true
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID, true
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From.ID, true
+	}
+	return 0, false
+}
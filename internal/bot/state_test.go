@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// update создает обновление чата chatID с порядковым номером id
+func update(chatID int64, id int) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		handled = make(map[int64][]int)
+		active  = make(map[int64]bool)
+	)
+	d := newDispatcher(func(update tgbotapi.Update) {
+		chatID := update.Message.Chat.ID
+
+		mu.Lock()
+		if active[chatID] {
+			t.Errorf("чат %d обрабатывается параллельно", chatID)
+		}
+		active[chatID] = true
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		active[chatID] = false
+		handled[chatID] = append(handled[chatID], update.UpdateID)
+		mu.Unlock()
+	})
+
+	const chats, updates = 5, 20
+	for id := 0; id < updates; id++ {
+		for chatID := int64(1); chatID <= chats; chatID++ {
+			d.dispatch(chatID, update(chatID, id))
+		}
+	}
+	d.wait()
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		ids := handled[chatID]
+		if len(ids) != updates {
+			t.Fatalf("чат %d: обработано %d обновлений, ожидалось %d", chatID, len(ids), updates)
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("чат %d: обновления обработаны не по порядку: %v", chatID, ids)
+			}
+		}
+	}
+}
+
+func TestDispatcherRunsChatsInParallel(t *testing.T) {
+	// Обработчик первого чата ждет, пока начнется обработка второго: при последовательной обработке
+	// чатов он не дождался бы
+	started := make(chan struct{})
+	parallel := make(chan bool, 1)
+	d := newDispatcher(func(update tgbotapi.Update) {
+		if update.Message.Chat.ID == 2 {
+			close(started)
+			return
+		}
+		select {
+		case <-started:
+			parallel <- true
+		case <-time.After(time.Second):
+			parallel <- false
+		}
+	})
+
+	d.dispatch(1, update(1, 0))
+	d.dispatch(2, update(2, 0))
+	d.wait()
+
+	if !<-parallel {
+		t.Fatal("обновление второго чата ждало завершения обработки первого")
+	}
+}
+
+func TestRememberChat(t *testing.T) {
+	const userID, groupID, previousID = 7, -100, 1
+
+	tests := []struct {
+		name string
+		chat tgbotapi.Chat
+		text string
+		want int64
+	}{
+		{name: "личный чат", chat: tgbotapi.Chat{ID: userID, Type: "private"}, text: "100", want: userID},
+		{name: "переписка в группе", chat: tgbotapi.Chat{ID: groupID, Type: "group"}, text: "всем привет", want: previousID},
+		{name: "нажатие кнопки в группе", chat: tgbotapi.Chat{ID: groupID, Type: "supergroup"}, want: previousID},
+		{name: "команда в группе", chat: tgbotapi.Chat{ID: groupID, Type: "group"}, text: "/balance", want: groupID},
+		{name: "команда с упоминанием бота", chat: tgbotapi.Chat{ID: groupID, Type: "group"}, text: "/balance@Sim_Bot", want: groupID},
+		{name: "команда другому боту", chat: tgbotapi.Chat{ID: groupID, Type: "group"}, text: "/balance@other_bot", want: previousID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := &TelegramBot{Bot: &tgbotapi.BotAPI{Self: tgbotapi.User{UserName: "sim_bot"}}, notify: newNotifyChats()}
+			tb.notify.remember(userID, previousID)
+
+			tb.rememberChat(userID, &test.chat, test.text)
+
+			if got := tb.notify.chat(userID); got != test.want {
+				t.Errorf("чат %d, ожидался %d", got, test.want)
+			}
+		})
+	}
+}
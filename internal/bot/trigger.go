@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Подсказки по синтаксису команд условных заявок
+const (
+	stopUsage  = "Формат: /stop [buy|sell] BTC-USDT <количество|all> <цена>"
+	tpUsage    = "Формат: /tp [buy|sell] BTC-USDT <количество|all> <цена>"
+	trailUsage = "Формат: /trail [buy|sell] BTC-USDT <количество|all> <отступ, например 5% или 1500>"
+	ocoUsage   = "Формат: /oco BTC-USDT <количество|all> <стоп-цена> <тейк-профит>"
+)
+
+// triggerCommands сопоставляет команды бота типам условных заявок
+var triggerCommands = map[string]string{
+	"/stop":  trader.OrderStop,
+	"/tp":    trader.OrderTakeProfit,
+	"/trail": trader.OrderTrailingStop,
+}
+
+// triggerArgs содержит общие аргументы команд условных заявок
+type triggerArgs struct {
+	Side     string
+	Token    string
+	Quantity decimal.Decimal
+	Rest     []string
+}
+
+// parseTriggerArgs разбирает "[buy|sell] ТОКЕН КОЛИЧЕСТВО ...". По умолчанию заявка на продажу позиции
+func (tb *TelegramBot) parseTriggerArgs(portfolio *trader.Trader, args string, rest int) (triggerArgs, error) {
+	fields := strings.Fields(args)
+	parsed := triggerArgs{Side: trader.SideSell}
+
+	if len(fields) > 0 {
+		switch strings.ToLower(fields[0]) {
+		case trader.SideBuy, trader.SideSell:
+			parsed.Side = strings.ToLower(fields[0])
+			fields = fields[1:]
+		}
+	}
+	if len(fields) != 2+rest {
+		return parsed, fmt.Errorf("неверное количество аргументов")
+	}
+
+	parsed.Token = normalizeSymbol(fields[0])
+	if !tb.isValidAsset(parsed.Token) {
+		return parsed, fmt.Errorf("недействительный актив %s", parsed.Token)
+	}
+
+	// Позиция для all округляется до шага лота, и итоговое количество видно в созданной заявке;
+	// введенное вручную количество должно быть допустимым для биржи
+	if strings.EqualFold(fields[1], "all") {
+		if parsed.Side != trader.SideSell {
+			return parsed, fmt.Errorf("all доступно только для продажи")
+		}
+		var err error
+		if parsed.Quantity, err = tb.roundQuantity(parsed.Token, portfolio.Holding(parsed.Token)); err != nil {
+			return parsed, err
+		}
+	} else {
+		quantity, ok := parsePositive(fields[1])
+		if !ok {
+			return parsed, fmt.Errorf("неверное количество %s", fields[1])
+		}
+		if err := tb.checkQuantity(parsed.Token, quantity); err != nil {
+			return parsed, err
+		}
+		parsed.Quantity = quantity
+	}
+
+	parsed.Rest = fields[2:]
+	return parsed, nil
+}
+
+// placeTrigger обрабатывает команды /stop, /tp и /trail
+func (tb *TelegramBot) placeTrigger(chatID int64, portfolio *trader.Trader, orderType, args string) {
+	usage := stopUsage
+	switch orderType {
+	case trader.OrderTakeProfit:
+		usage = tpUsage
+	case trader.OrderTrailingStop:
+		usage = trailUsage
+	}
+
+	parsed, err := tb.parseTriggerArgs(portfolio, args, 1)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n"+usage))
+		return
+	}
+
+	req := trader.TriggerRequest{
+		Type:     orderType,
+		Side:     parsed.Side,
+		Token:    parsed.Token,
+		Quantity: parsed.Quantity,
+	}
+
+	if orderType == trader.OrderTrailingStop {
+		req.TrailPercent, req.TrailAmount, err = parseDistance(parsed.Rest[0])
+	} else {
+		if req.TriggerPrice, err = parsePrice(parsed.Rest[0]); err == nil {
+			err = tb.checkPrice(parsed.Token, req.TriggerPrice)
+		}
+	}
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n"+usage))
+		return
+	}
+
+	req.MarkPrice, err = tb.getPriceWithRetries(parsed.Token)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения цены: "+err.Error()))
+		return
+	}
+
+	order, err := portfolio.PlaceTrigger(req)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Заявка невозможна: "+explainOrderError(err)))
+		return
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, "Заявка создана:\n"+formatOrder(order)))
+}
+
+// placeOCO обрабатывает команду /oco
+func (tb *TelegramBot) placeOCO(chatID int64, portfolio *trader.Trader, args string) {
+	parsed, err := tb.parseTriggerArgs(portfolio, args, 2)
+	if err == nil && parsed.Side != trader.SideSell {
+		err = fmt.Errorf("OCO доступна только для продажи позиции")
+	}
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n"+ocoUsage))
+		return
+	}
+
+	var prices [2]decimal.Decimal
+	for i, raw := range parsed.Rest {
+		if prices[i], err = parsePrice(raw); err == nil {
+			err = tb.checkPrice(parsed.Token, prices[i])
+		}
+		if err != nil {
+			tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+explainOrderError(err)+"\n"+ocoUsage))
+			return
+		}
+	}
+	stopPrice, takeProfitPrice := prices[0], prices[1]
+
+	markPrice, err := tb.getPriceWithRetries(parsed.Token)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения цены: "+err.Error()))
+		return
+	}
+
+	orders, err := portfolio.PlaceOCO(parsed.Token, parsed.Quantity, stopPrice, takeProfitPrice, markPrice)
+	if err != nil {
+		tb.Bot.Send(tgbotapi.NewMessage(chatID, "Заявка невозможна: "+explainOrderError(err)))
+		return
+	}
+
+	message := "Связанные заявки созданы:\n"
+	for _, order := range orders {
+		message += formatOrder(order) + "\n"
+	}
+	tb.Bot.Send(tgbotapi.NewMessage(chatID, message))
+}
+
+// parsePrice разбирает положительную цену
+func parsePrice(raw string) (decimal.Decimal, error) {
+	price, ok := parsePositive(raw)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("неверная цена %s", raw)
+	}
+	return price, nil
+}
+
+// parseDistance разбирает отступ трейлинг-стопа в процентах ("5%") или в USDT ("1500")
+func parseDistance(raw string) (percent, amount decimal.Decimal, err error) {
+	if value, ok := strings.CutSuffix(raw, "%"); ok {
+		percent, ok = parsePositive(value)
+		if !ok || percent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("неверный отступ %s", raw)
+		}
+		return percent, decimal.Zero, nil
+	}
+
+	amount, ok := parsePositive(raw)
+	if !ok {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("неверный отступ %s", raw)
+	}
+	return decimal.Zero, amount, nil
+}
@@ -0,0 +1,331 @@
+package chart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+)
+
+// Размеры и отступы графика по умолчанию
+const (
+	defaultWidth  = 1000
+	defaultHeight = 600
+	padding       = 10
+	axisWidth     = 100 // Ширина правой оси с ценами
+	timeAxis      = 24  // Высота нижней оси со временем
+	panelGap      = 8   // Отступ между ценовой панелью и панелью объема
+	volumeShare   = 0.2 // Доля высоты, отведенная под объем
+	markerSize    = 6
+	priceTicks    = 6 // Желаемое количество делений ценовой оси
+	timeTicks     = 5
+)
+
+// Цвета графика
+var (
+	colorBackground = color.RGBA{0x13, 0x17, 0x22, 0xff}
+	colorGrid       = color.RGBA{0x2a, 0x2e, 0x39, 0xff}
+	colorText       = color.RGBA{0xb2, 0xb5, 0xbe, 0xff}
+	colorUp         = color.RGBA{0x26, 0xa6, 0x9a, 0xff}
+	colorDown       = color.RGBA{0xef, 0x53, 0x50, 0xff}
+	colorUpVolume   = color.RGBA{0x1a, 0x52, 0x50, 0xff}
+	colorDownVolume = color.RGBA{0x6b, 0x2c, 0x31, 0xff}
+	colorBuy        = color.RGBA{0x29, 0x62, 0xff, 0xff}
+	colorSell       = color.RGBA{0xff, 0x98, 0x00, 0xff}
+)
+
+// Marker отмечает сделку пользователя на графике
+type Marker struct {
+	Time  time.Time
+	Price float64
+	Buy   bool
+}
+
+// Level отмечает цену открытой заявки горизонтальной линией
+type Level struct {
+	Price float64
+	Buy   bool
+}
+
+// Options задает размер изображения и дополнительные отметки
+type Options struct {
+	Width   int // По умолчанию 1000
+	Height  int // По умолчанию 600
+	Markers []Marker
+	Levels  []Level
+}
+
+// bar хранит свечу в float64: точные значения не нужны для перевода цен в пиксели
+type bar struct {
+	Time                           time.Time
+	Open, High, Low, Close, Volume float64
+}
+
+// toBars переводит свечи в float64 для отрисовки
+func toBars(candles []okx.Candle) []bar {
+	bars := make([]bar, len(candles))
+	for i, candle := range candles {
+		bars[i] = bar{
+			Time:   candle.Time,
+			Open:   candle.Open.InexactFloat64(),
+			High:   candle.High.InexactFloat64(),
+			Low:    candle.Low.InexactFloat64(),
+			Close:  candle.Close.InexactFloat64(),
+			Volume: candle.Volume.InexactFloat64(),
+		}
+	}
+	return bars
+}
+
+// layout описывает области изображения и перевод цен в координаты
+type layout struct {
+	left, right           int
+	priceTop, priceBottom int
+	volumeTop, volume     int // Верх и высота панели объема
+	min, max              float64
+	slot                  float64 // Ширина, отведенная одной свече
+}
+
+// y переводит цену в вертикальную координату ценовой панели
+func (l layout) y(price float64) int {
+	return l.priceTop + int(math.Round((l.max-price)/(l.max-l.min)*float64(l.priceBottom-l.priceTop)))
+}
+
+// x возвращает центр свечи с индексом i
+func (l layout) x(i int) int {
+	return l.left + int(l.slot*(float64(i)+0.5))
+}
+
+// Render рисует свечной график с объемами в формате PNG. Свечи должны идти по возрастанию времени
+func Render(w io.Writer, candles []okx.Candle, opts Options) error {
+	if len(candles) < 2 {
+		return fmt.Errorf("недостаточно свечей для графика: %d", len(candles))
+	}
+	if opts.Width == 0 {
+		opts.Width = defaultWidth
+	}
+	if opts.Height == 0 {
+		opts.Height = defaultHeight
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+
+	bars := toBars(candles)
+	l := newLayout(bars, opts)
+	drawPriceAxis(img, l)
+	drawTimeAxis(img, l, bars)
+	drawVolumes(img, l, bars)
+	drawCandles(img, l, bars)
+	drawLevels(img, l, opts.Levels)
+	drawMarkers(img, l, bars, opts.Markers)
+
+	return png.Encode(w, img)
+}
+
+// newLayout размечает изображение и подбирает диапазон цен по свечам и уровням заявок
+func newLayout(candles []bar, opts Options) layout {
+	low, high := candles[0].Low, candles[0].High
+	for _, candle := range candles {
+		low, high = math.Min(low, candle.Low), math.Max(high, candle.High)
+	}
+
+	// Уровни заявок расширяют диапазон, но не дальше чем на его высоту, чтобы свечи не сжимались в линию
+	span := high - low
+	for _, level := range opts.Levels {
+		if level.Price >= low-span && level.Price <= high+span {
+			low, high = math.Min(low, level.Price), math.Max(high, level.Price)
+		}
+	}
+	margin := (high - low) * 0.05
+	if margin == 0 {
+		margin = high * 0.01
+	}
+
+	plotHeight := opts.Height - 2*padding - timeAxis
+	volumeHeight := int(float64(plotHeight) * volumeShare)
+	l := layout{
+		left:     padding,
+		right:    opts.Width - axisWidth,
+		priceTop: padding,
+		min:      low - margin,
+		max:      high + margin,
+	}
+	l.priceBottom = l.priceTop + plotHeight - volumeHeight - panelGap
+	l.volumeTop = l.priceBottom + panelGap
+	l.volume = volumeHeight
+	l.slot = float64(l.right-l.left) / float64(len(candles))
+	return l
+}
+
+// drawPriceAxis рисует горизонтальную сетку и подписи цен
+func drawPriceAxis(img *image.RGBA, l layout) {
+	step := niceStep((l.max - l.min) / priceTicks)
+	decimals := max(0, int(-math.Floor(math.Log10(step))))
+
+	for price := math.Ceil(l.min/step) * step; price <= l.max; price += step {
+		y := l.y(price)
+		fillRect(img, l.left, y, l.right-l.left, 1, colorGrid)
+		drawText(img, l.right+padding, y-textHeight/2, strconv.FormatFloat(price, 'f', decimals, 64), colorText)
+	}
+}
+
+// drawTimeAxis рисует вертикальную сетку и подписи времени под панелью объема
+func drawTimeAxis(img *image.RGBA, l layout, candles []bar) {
+	layoutTime := "15:04"
+	if candles[len(candles)-1].Time.Sub(candles[0].Time) > 48*time.Hour {
+		layoutTime = "02.01"
+	}
+
+	bottom := l.volumeTop + l.volume
+	for tick := 0; tick < timeTicks; tick++ {
+		i := tick * (len(candles) - 1) / (timeTicks - 1)
+		x := l.x(i)
+		fillRect(img, x, l.priceTop, 1, bottom-l.priceTop, colorGrid)
+
+		label := candles[i].Time.Local().Format(layoutTime)
+		labelX := min(max(x-textWidth(label)/2, l.left), l.right-textWidth(label))
+		drawText(img, labelX, bottom+(timeAxis-textHeight)/2, label, colorText)
+	}
+}
+
+// drawVolumes рисует столбцы объема в нижней панели
+func drawVolumes(img *image.RGBA, l layout, candles []bar) {
+	maxVolume := 0.0
+	for _, candle := range candles {
+		maxVolume = math.Max(maxVolume, candle.Volume)
+	}
+	if maxVolume == 0 {
+		return
+	}
+
+	width := bodyWidth(l)
+	bottom := l.volumeTop + l.volume
+	for i, candle := range candles {
+		height := max(1, int(candle.Volume/maxVolume*float64(l.volume)))
+		c := colorUpVolume
+		if candle.Close < candle.Open {
+			c = colorDownVolume
+		}
+		fillRect(img, l.x(i)-width/2, bottom-height, width, height, c)
+	}
+}
+
+// drawCandles рисует тени и тела свечей
+func drawCandles(img *image.RGBA, l layout, candles []bar) {
+	width := bodyWidth(l)
+	for i, candle := range candles {
+		c := colorUp
+		if candle.Close < candle.Open {
+			c = colorDown
+		}
+
+		x := l.x(i)
+		high, low := l.y(candle.High), l.y(candle.Low)
+		fillRect(img, x, high, 1, max(1, low-high), c)
+
+		top, bottom := l.y(math.Max(candle.Open, candle.Close)), l.y(math.Min(candle.Open, candle.Close))
+		fillRect(img, x-width/2, top, width, max(1, bottom-top), c)
+	}
+}
+
+// drawLevels рисует пунктирные линии открытых заявок с ценой на оси
+func drawLevels(img *image.RGBA, l layout, levels []Level) {
+	for _, level := range levels {
+		if level.Price < l.min || level.Price > l.max {
+			continue
+		}
+
+		c := colorSell
+		if level.Buy {
+			c = colorBuy
+		}
+
+		y := l.y(level.Price)
+		for x := l.left; x < l.right; x += 8 {
+			fillRect(img, x, y, min(4, l.right-x), 1, c)
+		}
+
+		label := strconv.FormatFloat(level.Price, 'f', -1, 64)
+		fillRect(img, l.right+padding/2, y-textHeight/2-2, textWidth(label)+padding, textHeight+4, c)
+		drawText(img, l.right+padding, y-textHeight/2, label, colorBackground)
+	}
+}
+
+// drawMarkers рисует сделки треугольниками: покупки под свечой, продажи над ней
+func drawMarkers(img *image.RGBA, l layout, candles []bar, markers []Marker) {
+	for _, marker := range markers {
+		i := candleAt(candles, marker.Time)
+		if i < 0 {
+			continue
+		}
+
+		x := l.x(i)
+		if marker.Buy {
+			drawTriangle(img, x, l.y(candles[i].Low)+4, true, colorBuy)
+		} else {
+			drawTriangle(img, x, l.y(candles[i].High)-4, false, colorSell)
+		}
+	}
+}
+
+// candleAt возвращает индекс свечи, в которую попадает момент t, или -1, если он вне графика
+func candleAt(candles []bar, t time.Time) int {
+	if t.Before(candles[0].Time) {
+		return -1
+	}
+
+	last := candles[len(candles)-1]
+	if step := last.Time.Sub(candles[len(candles)-2].Time); !t.Before(last.Time.Add(step)) {
+		return -1
+	}
+
+	for i := len(candles) - 1; i >= 0; i-- {
+		if !t.Before(candles[i].Time) {
+			return i
+		}
+	}
+	return -1
+}
+
+// drawTriangle рисует треугольник с вершиной в точке (x, y): вверх для покупок, вниз для продаж
+func drawTriangle(img *image.RGBA, x, y int, up bool, c color.Color) {
+	for row := 0; row < markerSize; row++ {
+		rowY := y + row
+		if !up {
+			rowY = y - row
+		}
+		fillRect(img, x-row, rowY, 2*row+1, 1, c)
+	}
+}
+
+// bodyWidth возвращает ширину тела свечи и столбца объема
+func bodyWidth(l layout) int {
+	return max(1, int(l.slot*0.7))
+}
+
+// niceStep округляет шаг сетки до 1, 2 или 5, умноженных на степень десяти
+func niceStep(raw float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch normalized := raw / magnitude; {
+	case normalized <= 1:
+		return magnitude
+	case normalized <= 2:
+		return 2 * magnitude
+	case normalized <= 5:
+		return 5 * magnitude
+	}
+	return 10 * magnitude
+}
+
+// fillRect закрашивает прямоугольник, обрезая его по границам изображения
+func fillRect(img *image.RGBA, x, y, w, h int, c color.Color) {
+	draw.Draw(img, image.Rect(x, y, x+w, y+h), image.NewUniform(c), image.Point{}, draw.Src)
+}
@@ -0,0 +1,59 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+)
+
+// Растровый шрифт 3x5 для подписей осей: цифры, точка, минус и двоеточие
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+	fontScale   = 2 // Каждая точка глифа рисуется квадратом fontScale x fontScale
+)
+
+// glyphs хранит глифы построчно: '#' — закрашенная точка
+var glyphs = map[rune][glyphHeight]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+}
+
+// textWidth возвращает ширину подписи в пикселях
+func textWidth(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return n*(glyphWidth+1)*fontScale - fontScale
+}
+
+// textHeight задает высоту подписи в пикселях
+const textHeight = glyphHeight * fontScale
+
+// drawText рисует подпись, левый верхний угол которой находится в точке (x, y). Неизвестные символы пропускаются
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if ok {
+			for row, line := range glyph {
+				for col, dot := range line {
+					if dot == '#' {
+						fillRect(img, x+col*fontScale, y+row*fontScale, fontScale, fontScale, c)
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * fontScale
+	}
+}
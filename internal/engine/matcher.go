@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+// Matcher периодически сверяет открытые лимитные и условные заявки пользователей с рыночными ценами и исполняет их
+type Matcher struct {
+	Portfolios *trader.Portfolios
+	Interval   time.Duration
+	Feed       market.PriceFeed
+	OnFill     func(userID int64, fill trader.Fill)
+	Quiet      bool // Не писать исполнения в лог; используется бэктестом
+}
+
+// NewMatcher создает цикл исполнения заявок по ценам из источника feed
+func NewMatcher(portfolios *trader.Portfolios, feed market.PriceFeed, interval time.Duration) *Matcher {
+	return &Matcher{
+		Portfolios: portfolios,
+		Interval:   interval,
+		Feed:       feed,
+	}
+}
+
+// Run проверяет заявки с заданным интервалом, пока не будет отменен контекст
+func (m *Matcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Step()
+		}
+	}
+}
+
+// Step выполняет один проход по открытым заявкам всех портфелей
+func (m *Matcher) Step() {
+	// Цена каждого токена запрашивается не более одного раза за проход
+	prices := make(map[string]decimal.Decimal)
+
+	for _, t := range m.Portfolios.All() {
+		for _, token := range t.OrderTokens() {
+			price, ok := prices[token]
+			if !ok {
+				var err error
+				price, err = m.Feed.Last(token)
+				if err != nil {
+					log.Printf("Ошибка получения цены %s для исполнения заявок: %v", token, err)
+					continue
+				}
+				prices[token] = price
+			}
+
+			fills, err := t.MatchOrders(token, price)
+			if err != nil {
+				log.Printf("Ошибка исполнения заявок пользователя %d: %v", t.UserID, err)
+			}
+
+			for _, fill := range fills {
+				if !m.Quiet {
+					logFill(t.UserID, fill)
+				}
+				if m.OnFill != nil {
+					m.OnFill(t.UserID, fill)
+				}
+			}
+		}
+	}
+}
+
+// logFill записывает исполнение заявки в лог
+func logFill(userID int64, fill trader.Fill) {
+	if fill.Err != nil {
+		log.Printf("Заявка #%d пользователя %d сработала, но не исполнена: %v", fill.Order.ID, userID, fill.Err)
+		return
+	}
+	log.Printf("Исполнена заявка #%d пользователя %d: %s %s %s по $%s",
+		fill.Order.ID, userID, fill.Order.Side, fill.Order.Quantity, fill.Order.Token, fill.Trade.Price)
+}
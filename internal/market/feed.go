@@ -0,0 +1,40 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoPrice возвращается, если для токена еще нет цены
+var ErrNoPrice = errors.New("нет цены")
+
+// ErrNoBook возвращается источником, у которого нет стаканов
+var ErrNoBook = errors.New("нет стакана")
+
+// Ticker содержит последнюю цену и лучшие цены покупки и продажи токена
+type Ticker struct {
+	Token string
+	Last  decimal.Decimal
+	Bid   decimal.Decimal
+	Ask   decimal.Decimal
+	Time  time.Time
+}
+
+// PriceFeed предоставляет рыночные данные независимо от источника.
+// Цены тикеров передаются без потери точности, свечи используются для графиков и бэктестов
+type PriceFeed interface {
+	// Last возвращает последнюю цену токена
+	Last(token string) (decimal.Decimal, error)
+	// Ticker возвращает последнюю цену вместе с лучшими ценами покупки и продажи
+	Ticker(token string) (Ticker, error)
+	// Candles возвращает свечи, открытые в интервале [from, to), по возрастанию времени
+	Candles(ctx context.Context, token string, bar okx.Bar, from, to time.Time) ([]okx.Candle, error)
+	// OrderBook возвращает стакан токена глубиной depth уровней
+	OrderBook(token string, depth int) (okx.OrderBook, error)
+	// Subscribe присылает обновления тикера, пока не отменен контекст; после отмены канал закрывается
+	Subscribe(ctx context.Context, token string) (<-chan Ticker, error)
+}
@@ -0,0 +1,148 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+)
+
+// QuoteCurrency задает валюту котировки торгуемых пар
+const QuoteCurrency = "USDT"
+
+// Instruments — единый справочник спотовых инструментов, периодически обновляемый из источника
+type Instruments struct {
+	Load func() ([]okx.Instrument, error)
+
+	mu       sync.RWMutex
+	byID     map[string]okx.Instrument
+	ids      []string // Отсортированные идентификаторы инструментов
+	loadedAt time.Time
+}
+
+// NewInstruments создает справочник, загружающий инструменты функцией load
+func NewInstruments(load func() ([]okx.Instrument, error)) *Instruments {
+	return &Instruments{Load: load, byID: make(map[string]okx.Instrument)}
+}
+
+// NewStaticInstruments создает справочник с заданным списком инструментов, например для бэктеста
+func NewStaticInstruments(instruments []okx.Instrument) *Instruments {
+	r := NewInstruments(func() ([]okx.Instrument, error) { return instruments, nil })
+	r.Refresh()
+	return r
+}
+
+// Refresh загружает справочник заново; при ошибке сохраняется предыдущий список
+func (r *Instruments) Refresh() error {
+	instruments, err := r.Load()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки инструментов: %w", err)
+	}
+	if len(instruments) == 0 {
+		return fmt.Errorf("источник вернул пустой список инструментов")
+	}
+
+	byID := make(map[string]okx.Instrument, len(instruments))
+	ids := make([]string, 0, len(instruments))
+	for _, instrument := range instruments {
+		byID[instrument.InstID] = instrument
+		ids = append(ids, instrument.InstID)
+	}
+	sort.Strings(ids)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byID, r.ids, r.loadedAt = byID, ids, time.Now()
+	return nil
+}
+
+// Run обновляет справочник с заданным интервалом, пока не будет отменен контекст.
+// Пока справочник пуст, загрузка повторяется не реже раза в минуту
+func (r *Instruments) Run(ctx context.Context, interval time.Duration) {
+	for {
+		wait := interval
+		if r.Len() == 0 {
+			wait = min(interval, time.Minute)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := r.Refresh(); err != nil {
+			log.Printf("Ошибка обновления справочника инструментов: %v", err)
+		}
+	}
+}
+
+// Len возвращает количество инструментов в справочнике
+func (r *Instruments) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.ids)
+}
+
+// Lookup возвращает инструмент по идентификатору вида BTC-USDT
+func (r *Instruments) Lookup(instID string) (okx.Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instrument, ok := r.byID[instID]
+	return instrument, ok
+}
+
+// Valid сообщает, существует ли пара к USDT и доступна ли она для торговли
+func (r *Instruments) Valid(instID string) bool {
+	instrument, ok := r.Lookup(instID)
+	return ok && instrument.Live() && instrument.QuoteCcy == QuoteCurrency
+}
+
+// List возвращает доступные для торговли пары к USDT в алфавитном порядке
+func (r *Instruments) List() []okx.Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var instruments []okx.Instrument
+	for _, id := range r.ids {
+		if instrument := r.byID[id]; instrument.Live() && instrument.QuoteCcy == QuoteCurrency {
+			instruments = append(instruments, instrument)
+		}
+	}
+	return instruments
+}
+
+// Search ищет доступные пары к USDT по части символа без учета регистра.
+// Сначала идут точные совпадения базовой валюты, затем совпадения по началу, затем остальные
+func (r *Instruments) Search(query string, limit int) []okx.Instrument {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var exact, prefix, other []okx.Instrument
+	for _, instrument := range r.List() {
+		switch {
+		case instrument.BaseCcy == query || instrument.InstID == query:
+			exact = append(exact, instrument)
+		case strings.HasPrefix(instrument.BaseCcy, query):
+			prefix = append(prefix, instrument)
+		case strings.Contains(instrument.InstID, query):
+			other = append(other, instrument)
+		}
+	}
+
+	found := append(append(exact, prefix...), other...)
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
@@ -0,0 +1,100 @@
+package market
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+)
+
+// instrument создает спотовый инструмент base-quote в состоянии state
+func instrument(base, quote, state string) okx.Instrument {
+	return okx.Instrument{InstID: base + "-" + quote, BaseCcy: base, QuoteCcy: quote, State: state}
+}
+
+func TestInstrumentsRefresh(t *testing.T) {
+	list := []okx.Instrument{
+		instrument("BTC", "USDT", okx.InstrumentLive),
+		instrument("ETH", "USDT", okx.InstrumentLive),
+		instrument("ETH", "BTC", okx.InstrumentLive),
+		instrument("OLD", "USDT", "suspend"),
+	}
+	var loadErr error
+	registry := NewInstruments(func() ([]okx.Instrument, error) { return list, loadErr })
+	if err := registry.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	tests := []struct {
+		instID    string
+		wantFound bool
+		wantValid bool
+	}{
+		{instID: "BTC-USDT", wantFound: true, wantValid: true},
+		{instID: "ETH-BTC", wantFound: true},
+		{instID: "OLD-USDT", wantFound: true},
+		{instID: "DOGE-USDT"},
+	}
+	for _, test := range tests {
+		if _, ok := registry.Lookup(test.instID); ok != test.wantFound {
+			t.Errorf("Lookup(%s): %v, ожидалось %v", test.instID, ok, test.wantFound)
+		}
+		if got := registry.Valid(test.instID); got != test.wantValid {
+			t.Errorf("Valid(%s): %v, ожидалось %v", test.instID, got, test.wantValid)
+		}
+	}
+	if got := registry.List(); len(got) != 2 || got[0].InstID != "BTC-USDT" || got[1].InstID != "ETH-USDT" {
+		t.Errorf("List: %v, ожидались BTC-USDT и ETH-USDT", got)
+	}
+
+	// При ошибке и при пустом ответе сохраняется предыдущий список
+	loadErr = errors.New("нет связи")
+	if err := registry.Refresh(); err == nil {
+		t.Error("ожидалась ошибка загрузки")
+	}
+	list, loadErr = nil, nil
+	if err := registry.Refresh(); err == nil {
+		t.Error("ожидалась ошибка для пустого списка")
+	}
+	if registry.Len() != 4 || !registry.Valid("BTC-USDT") {
+		t.Errorf("после ошибок в справочнике %d инструментов, ожидалось 4", registry.Len())
+	}
+}
+
+func TestInstrumentsSearch(t *testing.T) {
+	registry := NewStaticInstruments([]okx.Instrument{
+		instrument("BTC", "USDT", okx.InstrumentLive),
+		instrument("BTCST", "USDT", okx.InstrumentLive),
+		instrument("WBTC", "USDT", okx.InstrumentLive),
+		instrument("ETH", "USDT", okx.InstrumentLive),
+		instrument("BTC", "EUR", okx.InstrumentLive),
+		instrument("BTCDOWN", "USDT", "suspend"),
+	})
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		// Точное совпадение, затем начало символа, затем вхождение
+		{query: " btc ", limit: 10, want: []string{"BTC-USDT", "BTCST-USDT", "WBTC-USDT"}},
+		{query: "btc", limit: 2, want: []string{"BTC-USDT", "BTCST-USDT"}},
+		{query: "eth-usdt", limit: 10, want: []string{"ETH-USDT"}},
+		{query: "", limit: 10},
+		{query: "xrp", limit: 10},
+	}
+
+	for _, test := range tests {
+		got := registry.Search(test.query, test.limit)
+		if len(got) != len(test.want) {
+			t.Errorf("%q: найдено %v, ожидалось %v", test.query, got, test.want)
+			continue
+		}
+		for i, want := range test.want {
+			if got[i].InstID != want {
+				t.Errorf("%q: найдено %v, ожидалось %v", test.query, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package market
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// defaultPollInterval задает период опроса тикера для подписок
+const defaultPollInterval = 5 * time.Second
+
+// OKXFeed получает рыночные данные через REST API OKX
+type OKXFeed struct {
+	Client       *okx.Client
+	PollInterval time.Duration // Период опроса тикера для подписок
+}
+
+// NewOKXFeed создает источник цен, обращающийся к OKX через client
+func NewOKXFeed(client *okx.Client) *OKXFeed {
+	return &OKXFeed{Client: client, PollInterval: defaultPollInterval}
+}
+
+// Last возвращает последнюю цену токена на OKX
+func (f *OKXFeed) Last(token string) (decimal.Decimal, error) {
+	return f.Client.GetCurrentPrice(token)
+}
+
+// Ticker возвращает тикер токена на OKX
+func (f *OKXFeed) Ticker(token string) (Ticker, error) {
+	ticker, err := f.Client.GetTicker(token)
+	if err != nil {
+		return Ticker{}, err
+	}
+	return toTicker(token, ticker), nil
+}
+
+// Candles загружает свечи токена из архива OKX
+func (f *OKXFeed) Candles(ctx context.Context, token string, bar okx.Bar, from, to time.Time) ([]okx.Candle, error) {
+	return f.Client.GetCandlesRange(ctx, token, bar, from, to)
+}
+
+// OrderBook загружает стакан токена с OKX
+func (f *OKXFeed) OrderBook(token string, depth int) (okx.OrderBook, error) {
+	return f.Client.GetOrderBook(token, depth)
+}
+
+// Subscribe опрашивает тикер с интервалом PollInterval и присылает его при изменении цены
+func (f *OKXFeed) Subscribe(ctx context.Context, token string) (<-chan Ticker, error) {
+	updates := make(chan Ticker, 1)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(f.PollInterval)
+		defer ticker.Stop()
+
+		var last Ticker
+		for {
+			current, err := f.Ticker(token)
+			if err != nil {
+				log.Printf("Ошибка получения тикера %s: %v", token, err)
+			} else if !current.Last.Equal(last.Last) || !current.Bid.Equal(last.Bid) || !current.Ask.Equal(last.Ask) {
+				last = current
+				select {
+				case updates <- current:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return updates, nil
+}
@@ -0,0 +1,142 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// replayBuffer задает размер буфера подписки; при переполнении старые обновления не ждут читателя
+const replayBuffer = 16
+
+// Replay — детерминированный источник цен: цены задаются вручную или проигрываются из свечей.
+// Используется бэктестом и для проверки логики без сети
+type Replay struct {
+	Spread decimal.Decimal // Доля цены между bid и ask, по умолчанию 0
+
+	mu          sync.Mutex
+	now         time.Time
+	tickers     map[string]Ticker
+	bars        map[string]okx.Bar
+	candles     map[string][]okx.Candle
+	subscribers map[string][]chan Ticker
+}
+
+// NewReplay создает пустой источник цен
+func NewReplay() *Replay {
+	return &Replay{
+		tickers:     make(map[string]Ticker),
+		bars:        make(map[string]okx.Bar),
+		candles:     make(map[string][]okx.Candle),
+		subscribers: make(map[string][]chan Ticker),
+	}
+}
+
+// Load задает исторические свечи токена, отдаваемые Candles
+func (r *Replay) Load(token string, bar okx.Bar, candles []okx.Candle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bars[token] = bar
+	r.candles[token] = candles
+}
+
+// Set устанавливает цену токена на момент at, сдвигает модельное время и уведомляет подписчиков
+func (r *Replay) Set(token string, price decimal.Decimal, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	half := price.Mul(r.Spread).Div(decimal.NewFromInt(2))
+	ticker := Ticker{Token: token, Last: price, Bid: price.Sub(half), Ask: price.Add(half), Time: at}
+	r.tickers[token] = ticker
+	if at.After(r.now) {
+		r.now = at
+	}
+
+	for _, subscriber := range r.subscribers[token] {
+		select {
+		case subscriber <- ticker:
+		default:
+		}
+	}
+}
+
+// Now возвращает модельное время последнего обновления цены
+func (r *Replay) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.now
+}
+
+// Last возвращает последнюю установленную цену токена
+func (r *Replay) Last(token string) (decimal.Decimal, error) {
+	ticker, err := r.Ticker(token)
+	return ticker.Last, err
+}
+
+// Ticker возвращает последний установленный тикер токена
+func (r *Replay) Ticker(token string) (Ticker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ticker, ok := r.tickers[token]
+	if !ok {
+		return Ticker{}, fmt.Errorf("%w для %s", ErrNoPrice, token)
+	}
+	return ticker, nil
+}
+
+// Candles возвращает загруженные свечи токена из интервала [from, to)
+func (r *Replay) Candles(ctx context.Context, token string, bar okx.Bar, from, to time.Time) ([]okx.Candle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if loaded, ok := r.bars[token]; !ok || loaded != bar {
+		return nil, fmt.Errorf("нет свечей %s для %s", bar, token)
+	}
+
+	var candles []okx.Candle
+	for _, candle := range r.candles[token] {
+		if !candle.Time.Before(from) && candle.Time.Before(to) {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// OrderBook всегда возвращает ErrNoBook: истории стаканов у воспроизведения нет
+func (r *Replay) OrderBook(token string, depth int) (okx.OrderBook, error) {
+	return okx.OrderBook{}, fmt.Errorf("%w для %s", ErrNoBook, token)
+}
+
+// Subscribe присылает тикеры, установленные через Set, пока не отменен контекст
+func (r *Replay) Subscribe(ctx context.Context, token string) (<-chan Ticker, error) {
+	updates := make(chan Ticker, replayBuffer)
+
+	r.mu.Lock()
+	r.subscribers[token] = append(r.subscribers[token], updates)
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		subscribers := r.subscribers[token]
+		for i, subscriber := range subscribers {
+			if subscriber == updates {
+				r.subscribers[token] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(updates)
+	}()
+
+	return updates, nil
+}
@@ -0,0 +1,237 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// maxTickerAge задает срок, в течение которого цена из потока считается актуальной
+	maxTickerAge = 30 * time.Second
+	// defaultWatchIdle задает срок, после которого неиспользуемый токен отписывается от потока
+	defaultWatchIdle = 10 * time.Minute
+)
+
+// watch описывает подписку кэша на тикер токена
+type watch struct {
+	cancel      func()
+	used        time.Time // Последнее обращение к цене токена
+	subscribers int       // Открытые подписки Subscribe; пока они есть, токен не отписывается
+}
+
+// StreamFeed читает цены из общего кэша, который наполняет поток тикеров WebSocket OKX.
+// Пока по токену нет свежей цены, запрос передается резервному источнику (REST).
+// На поток подписываются только инструменты из справочника; токены, к которым давно
+// не обращались, отписываются в Run
+type StreamFeed struct {
+	Stream    *okx.Stream
+	Fallback  PriceFeed
+	Valid     func(token string) bool // Проверка токена по справочнику инструментов
+	WatchIdle time.Duration           // Срок без обращений, после которого токен отписывается
+
+	mu       sync.Mutex
+	tickers  map[string]Ticker
+	received map[string]time.Time // Время получения тикера; по нему определяется актуальность кэша
+	watched  map[string]*watch
+}
+
+// NewStreamFeed создает кэш цен поверх потока stream с резервным источником fallback.
+// valid ограничивает подписки на поток существующими инструментами
+func NewStreamFeed(stream *okx.Stream, fallback PriceFeed, valid func(token string) bool) *StreamFeed {
+	return &StreamFeed{
+		Stream:    stream,
+		Fallback:  fallback,
+		Valid:     valid,
+		WatchIdle: defaultWatchIdle,
+		tickers:   make(map[string]Ticker),
+		received:  make(map[string]time.Time),
+		watched:   make(map[string]*watch),
+	}
+}
+
+// Watch подписывает кэш на тикер токена и отмечает обращение к нему.
+// Токены не из справочника не подписываются, и Watch возвращает false
+func (f *StreamFeed) Watch(token string) bool {
+	if !f.Valid(token) {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.watch(token).used = time.Now()
+	return true
+}
+
+// watch возвращает подписку кэша на токен, при необходимости подписываясь на поток.
+// Вызывающий должен удерживать f.mu
+func (f *StreamFeed) watch(token string) *watch {
+	if w, ok := f.watched[token]; ok {
+		return w
+	}
+
+	events, cancel := f.Stream.Subscribe(okx.ChannelTickers, token)
+	w := &watch{cancel: cancel}
+	f.watched[token] = w
+	go func() {
+		for event := range events {
+			if event.Ticker != nil {
+				f.store(toTicker(token, *event.Ticker))
+			}
+		}
+	}()
+	return w
+}
+
+// Run отписывает неиспользуемые токены, пока не будет отменен контекст
+func (f *StreamFeed) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.WatchIdle / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.Prune(time.Now())
+		}
+	}
+}
+
+// Prune отписывает токены без открытых подписок, к которым не обращались дольше WatchIdle,
+// и удаляет их цены из кэша
+func (f *StreamFeed) Prune(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for token, w := range f.watched {
+		if w.subscribers > 0 || now.Sub(w.used) < f.WatchIdle {
+			continue
+		}
+		w.cancel()
+		delete(f.watched, token)
+		delete(f.tickers, token)
+		delete(f.received, token)
+	}
+}
+
+// Last возвращает последнюю цену токена из кэша
+func (f *StreamFeed) Last(token string) (decimal.Decimal, error) {
+	ticker, err := f.Ticker(token)
+	return ticker.Last, err
+}
+
+// Ticker возвращает тикер токена из кэша, а при его отсутствии — из резервного источника
+func (f *StreamFeed) Ticker(token string) (Ticker, error) {
+	if !f.Watch(token) {
+		return f.Fallback.Ticker(token)
+	}
+
+	f.mu.Lock()
+	ticker, ok := f.tickers[token]
+	received := f.received[token]
+	f.mu.Unlock()
+	if ok && time.Since(received) < maxTickerAge {
+		return ticker, nil
+	}
+
+	ticker, err := f.Fallback.Ticker(token)
+	if err != nil {
+		return Ticker{}, err
+	}
+	f.store(ticker)
+	return ticker, nil
+}
+
+// Candles передает запрос свечей резервному источнику
+func (f *StreamFeed) Candles(ctx context.Context, token string, bar okx.Bar, from, to time.Time) ([]okx.Candle, error) {
+	return f.Fallback.Candles(ctx, token, bar, from, to)
+}
+
+// OrderBook передает запрос стакана резервному источнику
+func (f *StreamFeed) OrderBook(token string, depth int) (okx.OrderBook, error) {
+	return f.Fallback.OrderBook(token, depth)
+}
+
+// Subscribe присылает тикеры токена из потока WebSocket, пока не отменен контекст
+func (f *StreamFeed) Subscribe(ctx context.Context, token string) (<-chan Ticker, error) {
+	if !f.hold(token) {
+		return nil, fmt.Errorf("недействительный инструмент %s", token)
+	}
+
+	events, cancel := f.Stream.Subscribe(okx.ChannelTickers, token)
+	updates := make(chan Ticker, 1)
+	go func() {
+		defer close(updates)
+		defer f.release(token)
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				if event.Ticker == nil {
+					continue
+				}
+				select {
+				case updates <- toTicker(token, *event.Ticker):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// hold подписывает кэш на токен и не дает отписать его до вызова release
+func (f *StreamFeed) hold(token string) bool {
+	if !f.Valid(token) {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := f.watch(token)
+	w.subscribers++
+	w.used = time.Now()
+	return true
+}
+
+// release снимает удержание токена, взятое в hold
+func (f *StreamFeed) release(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if w, ok := f.watched[token]; ok {
+		w.subscribers--
+		w.used = time.Now()
+	}
+}
+
+// store сохраняет тикер в кэш, если токен отслеживается и тикер не старее уже сохраненного
+func (f *StreamFeed) store(ticker Ticker) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.watched[ticker.Token]; !ok {
+		return
+	}
+
+	if current, ok := f.tickers[ticker.Token]; !ok || !ticker.Time.Before(current.Time) {
+		f.tickers[ticker.Token] = ticker
+		f.received[ticker.Token] = time.Now()
+	}
+}
+
+// toTicker переводит тикер OKX в общий формат
+func toTicker(token string, ticker okx.Ticker) Ticker {
+	return Ticker{Token: token, Last: ticker.Last, Bid: ticker.Bid, Ask: ticker.Ask, Time: ticker.Time}
+}
@@ -0,0 +1,174 @@
+package market
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx/okxtest"
+	"github.com/shopspring/decimal"
+)
+
+const tickerPath = "/api/v5/market/ticker"
+
+// anyToken считает действительным любой токен
+func anyToken(string) bool { return true }
+
+func TestStreamFeedFallsBackToREST(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+
+	// Поток не запущен, поэтому цена может прийти только из REST
+	feed := NewStreamFeed(okx.NewStream(server.WSURL()), NewOKXFeed(okx.NewClient(server.URL)), anyToken)
+	price, err := feed.Last("BTC-USDT")
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	if !price.Equal(decimal.NewFromInt(60000)) {
+		t.Fatalf("цена %s, ожидалась 60000", price)
+	}
+	if requests := server.Requests(tickerPath); requests != 1 {
+		t.Fatalf("запросов к REST: %d, ожидался 1", requests)
+	}
+
+	// Свежая цена берется из кэша без повторного запроса
+	if _, err := feed.Last("BTC-USDT"); err != nil {
+		t.Fatalf("Last из кэша: %v", err)
+	}
+	if requests := server.Requests(tickerPath); requests != 1 {
+		t.Fatalf("запросов к REST: %d, ожидался 1", requests)
+	}
+}
+
+func TestStreamFeedRESTError(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+	server.FailStatus(tickerPath, 503, 1)
+
+	feed := NewStreamFeed(okx.NewStream(server.WSURL()), NewOKXFeed(okx.NewClient(server.URL)), anyToken)
+	if _, err := feed.Last("BTC-USDT"); err == nil {
+		t.Fatal("ожидалась ошибка резервного источника")
+	}
+	if _, err := feed.Last("BTC-USDT"); err != nil {
+		t.Fatalf("повторный запрос: %v", err)
+	}
+}
+
+func TestStreamFeedUsesStream(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+
+	stream := okx.NewStream(server.WSURL())
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go stream.Run(ctx)
+
+	feed := NewStreamFeed(stream, NewOKXFeed(okx.NewClient(server.URL)), anyToken)
+	updates, err := feed.Subscribe(ctx, "BTC-USDT")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case ticker := <-updates:
+		if !ticker.Last.Equal(decimal.NewFromInt(60000)) {
+			t.Fatalf("цена %s, ожидалась 60000", ticker.Last)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("тикер из потока не получен")
+	}
+
+	// Тикер из потока попадает в кэш, и REST не нужен
+	deadline := time.Now().Add(time.Second)
+	for {
+		feed.mu.Lock()
+		_, cached := feed.tickers["BTC-USDT"]
+		feed.mu.Unlock()
+		if cached {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("тикер из потока не попал в кэш")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := feed.Last("BTC-USDT"); err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	if requests := server.Requests(tickerPath); requests != 0 {
+		t.Fatalf("запросов к REST: %d, ожидалось 0", requests)
+	}
+}
+
+func TestStreamFeedWatchesOnlyValidTokens(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+
+	valid := func(token string) bool { return token == "BTC-USDT" }
+	feed := NewStreamFeed(okx.NewStream(server.WSURL()), NewOKXFeed(okx.NewClient(server.URL)), valid)
+
+	if _, err := feed.Last("NOPE-USDT"); err == nil {
+		t.Fatal("ожидалась ошибка для несуществующего инструмента")
+	}
+	if _, err := feed.Subscribe(context.Background(), "NOPE-USDT"); err == nil {
+		t.Fatal("ожидалась ошибка подписки на несуществующий инструмент")
+	}
+	if _, err := feed.Last("BTC-USDT"); err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+
+	feed.mu.Lock()
+	watched := len(feed.watched)
+	feed.mu.Unlock()
+	if watched != 1 {
+		t.Fatalf("отслеживается токенов: %d, ожидался 1", watched)
+	}
+}
+
+func TestStreamFeedPrunesIdleTokens(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+	server.SetPrice("ETH-USDT", decimal.NewFromInt(3000))
+
+	feed := NewStreamFeed(okx.NewStream(server.WSURL()), NewOKXFeed(okx.NewClient(server.URL)), anyToken)
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := feed.Subscribe(ctx, "ETH-USDT"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := feed.Last("BTC-USDT"); err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+
+	// BTC-USDT давно не запрашивали, а на ETH-USDT есть открытая подписка
+	feed.Prune(time.Now().Add(feed.WatchIdle))
+	feed.mu.Lock()
+	_, btc := feed.watched["BTC-USDT"]
+	_, eth := feed.watched["ETH-USDT"]
+	_, cached := feed.tickers["BTC-USDT"]
+	feed.mu.Unlock()
+	if btc || cached || !eth {
+		t.Fatalf("после очистки: BTC-USDT %v (в кэше %v), ETH-USDT %v", btc, cached, eth)
+	}
+
+	// После отмены подписки ETH-USDT тоже отписывается
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		feed.Prune(time.Now().Add(feed.WatchIdle))
+		feed.mu.Lock()
+		_, eth = feed.watched["ETH-USDT"]
+		feed.mu.Unlock()
+		if !eth {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("ETH-USDT не отписан после отмены подписки")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+)
+
+// fileData описывает содержимое основного файла хранилища: версию схемы и состояние стратегий.
+// Портфель и сделки каждого пользователя хранятся в отдельном журнале
+type fileData struct {
+	Version int                        `json:"version"`
+	State   map[string]json.RawMessage `json:"state"`
+}
+
+// migration приводит данные файла от предыдущей версии схемы к следующей
+type migration func(data *fileData) error
+
+// migrations перечислены по порядку: migrations[i] переводит схему с версии i на i+1
+var migrations = []migration{
+	// Версия 1: состояние стратегий в основном файле, портфели и сделки в журналах пользователей
+	func(data *fileData) error {
+		if data.State == nil {
+			data.State = make(map[string]json.RawMessage)
+		}
+		return nil
+	},
+}
+
+// FileStore хранит состояние стратегий в основном JSON файле, а портфель и сделки каждого
+// пользователя — в отдельном журнале рядом с ним, поэтому запись одного пользователя не затрагивает других
+type FileStore struct {
+	mu    sync.Mutex // Защищает основной файл и список файлов пользователей
+	path  string
+	dir   string // Каталог файлов пользователей
+	data  fileData
+	users map[int64]*userFile
+}
+
+// NewFileStore открывает файл хранилища и применяет недостающие миграции
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:  path,
+		dir:   strings.TrimSuffix(path, filepath.Ext(path)) + ".users",
+		users: make(map[int64]*userFile),
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("ошибка чтения файла хранилища: %w", err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return nil, fmt.Errorf("ошибка разбора файла хранилища: %w", err)
+		}
+	}
+
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate применяет миграции, начиная с текущей версии файла
+func (s *FileStore) migrate() error {
+	if s.data.Version > len(migrations) {
+		return fmt.Errorf("версия хранилища %d новее поддерживаемой %d", s.data.Version, len(migrations))
+	}
+	if s.data.Version == len(migrations) {
+		return nil
+	}
+
+	for version := s.data.Version; version < len(migrations); version++ {
+		if err := migrations[version](&s.data); err != nil {
+			return fmt.Errorf("ошибка миграции хранилища на версию %d: %w", version+1, err)
+		}
+		log.Printf("Хранилище %s обновлено до версии %d", s.path, version+1)
+		s.data.Version = version + 1
+	}
+
+	return s.flush()
+}
+
+// LoadPortfolio загружает последний снимок портфеля из журнала пользователя
+func (s *FileStore) LoadPortfolio(userID int64) (*trader.Trader, error) {
+	u := s.user(userID)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.load(); err != nil {
+		return nil, err
+	}
+	if u.portfolio == nil {
+		return nil, trader.ErrNotFound
+	}
+
+	t := &trader.Trader{}
+	if err := json.Unmarshal(u.portfolio, t); err != nil {
+		return nil, fmt.Errorf("ошибка разбора портфеля: %w", err)
+	}
+	return t, nil
+}
+
+// SavePortfolio дописывает снимок портфеля в журнал пользователя
+func (s *FileStore) SavePortfolio(t *trader.Trader) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	u := s.user(t.UserID)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.load(); err != nil {
+		return err
+	}
+	return u.savePortfolio(raw)
+}
+
+// SaveTrade дописывает сделку вместе с портфелем после нее одной строкой журнала пользователя
+func (s *FileStore) SaveTrade(t *trader.Trader, trade trader.Trade) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	u := s.user(t.UserID)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.load(); err != nil {
+		return err
+	}
+	return u.saveTrade(raw, trade)
+}
+
+// ListTrades возвращает сделки пользователя, начиная с самых новых
+func (s *FileStore) ListTrades(userID int64, offset, limit int) ([]trader.Trade, error) {
+	u := s.user(userID)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.load(); err != nil {
+		return nil, err
+	}
+	return page(u.trades, offset, limit), nil
+}
+
+// ListUsers возвращает идентификаторы всех сохраненных портфелей
+func (s *FileStore) ListUsers() ([]int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога портфелей: %w", err)
+	}
+
+	var userIDs []int64
+	for _, entry := range entries {
+		rawID, ok := strings.CutSuffix(entry.Name(), userFileExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		userID, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный идентификатор портфеля %q: %w", rawID, err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// LoadState загружает сохраненное состояние по ключу и сообщает, было ли оно найдено
+func (s *FileStore) LoadState(key string, v any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.data.State[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("ошибка разбора состояния %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// SaveState сохраняет состояние по ключу
+func (s *FileStore) SaveState(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.data.State[key]
+	s.data.State[key] = raw
+	if err := s.flush(); err != nil {
+		// Несохраненное состояние не должно попасть в файл при следующей перезаписи
+		if existed {
+			s.data.State[key] = previous
+		} else {
+			delete(s.data.State, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// user возвращает файл пользователя; данные читаются с диска при первом обращении
+func (s *FileStore) user(userID int64) *userFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		u = &userFile{path: filepath.Join(s.dir, key(userID)+userFileExt)}
+		s.users[userID] = u
+	}
+	return u
+}
+
+// flush атомарно перезаписывает основной файл хранилища
+func (s *FileStore) flush() error {
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path, raw)
+}
+
+// writeFile атомарно заменяет файл: сначала пишет временный, затем переименовывает его
+func writeFile(path string, raw []byte) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("ошибка создания каталога хранилища: %w", err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи файла хранилища: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func key(userID int64) string {
+	return strconv.FormatInt(userID, 10)
+}
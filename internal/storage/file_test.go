@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+func TestFileStoreMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // Содержимое основного файла; пустая строка — файла нет
+		version int
+		state   map[string]string
+		wantErr string
+	}{
+		{name: "новый файл", version: 1},
+		{name: "пустой файл", file: " ", wantErr: "ошибка разбора"},
+		{name: "файл без версии", file: `{}`, version: 1},
+		{
+			name:    "текущая версия",
+			file:    `{"version":1,"state":{"dca":{"plans":[]}}}`,
+			version: 1,
+			state:   map[string]string{"dca": `{"plans":[]}`},
+		},
+		{name: "версия новее поддерживаемой", file: `{"version":2,"state":{}}`, wantErr: "новее поддерживаемой"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.json")
+			if test.file != "" {
+				if err := os.WriteFile(path, []byte(test.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, err := NewFileStore(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFileStore: %v", err)
+			}
+			if s.data.Version != test.version {
+				t.Errorf("версия %d, ожидалась %d", s.data.Version, test.version)
+			}
+			if s.data.State == nil {
+				t.Fatal("состояние не создано после миграции")
+			}
+			for key, want := range test.state {
+				if got := string(s.data.State[key]); got != want {
+					t.Errorf("состояние %q: %s, ожидалось %s", key, got, want)
+				}
+			}
+
+			// Повторное открытие не должно ничего менять
+			reopened, err := NewFileStore(path)
+			if err != nil {
+				t.Fatalf("повторное открытие: %v", err)
+			}
+			if reopened.data.Version != test.version {
+				t.Errorf("версия после повторного открытия %d, ожидалась %d", reopened.data.Version, test.version)
+			}
+		})
+	}
+}
+
+func TestFileStoreJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	portfolio := trader.NewTrader(7, decimal.NewFromInt(1000))
+	trades := []trader.Trade{
+		{ID: 1, Side: trader.SideBuy, Token: "BTC-USDT", Quantity: decimal.RequireFromString("0.01"), Price: decimal.NewFromInt(50000)},
+		{ID: 2, Side: trader.SideSell, Token: "BTC-USDT", Quantity: decimal.RequireFromString("0.005"), Price: decimal.NewFromInt(51000)},
+	}
+	for _, trade := range trades {
+		portfolio.Capital = portfolio.Capital.Sub(decimal.NewFromInt(1))
+		if err := s.SaveTrade(portfolio, trade); err != nil {
+			t.Fatalf("SaveTrade: %v", err)
+		}
+	}
+	if err := s.SavePortfolio(portfolio); err != nil {
+		t.Fatalf("SavePortfolio: %v", err)
+	}
+
+	// Оборванная запись в конце журнала остается от прерванной записи и отбрасывается при загрузке
+	journal := filepath.Join(s.dir, key(7)+userFileExt)
+	file, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"Trade":{"ID":3`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	loaded, err := reopened.LoadPortfolio(7)
+	if err != nil {
+		t.Fatalf("LoadPortfolio: %v", err)
+	}
+	if !loaded.Capital.Equal(portfolio.Capital) {
+		t.Errorf("капитал %s, ожидался %s", loaded.Capital, portfolio.Capital)
+	}
+
+	got, err := reopened.ListTrades(7, 0, 10)
+	if err != nil {
+		t.Fatalf("ListTrades: %v", err)
+	}
+	if len(got) != len(trades) || got[0].ID != 2 || got[1].ID != 1 {
+		t.Fatalf("сделки %+v, ожидались 2 и 1", got)
+	}
+	if !got[1].Quantity.Equal(trades[0].Quantity) {
+		t.Errorf("количество %s, ожидалось %s", got[1].Quantity, trades[0].Quantity)
+	}
+
+	users, err := reopened.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 1 || users[0] != 7 {
+		t.Errorf("пользователи %v, ожидался 7", users)
+	}
+
+	if _, err := reopened.LoadPortfolio(8); err != trader.ErrNotFound {
+		t.Errorf("портфель 8: ошибка %v, ожидалась ErrNotFound", err)
+	}
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+)
+
+// MemoryStore хранит портфели в памяти процесса и используется в тестах.
+// Портфели хранятся в сериализованном виде, чтобы вызывающий код не мог
+// изменить сохраненное состояние в обход SavePortfolio
+type MemoryStore struct {
+	mu         sync.Mutex
+	portfolios map[int64][]byte
+	trades     map[int64][]trader.Trade
+	state      map[string][]byte
+}
+
+// NewMemoryStore создает пустое хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		portfolios: make(map[int64][]byte),
+		trades:     make(map[int64][]trader.Trade),
+		state:      make(map[string][]byte),
+	}
+}
+
+// LoadPortfolio восстанавливает портфель из сохраненного снимка
+func (s *MemoryStore) LoadPortfolio(userID int64) (*trader.Trader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.portfolios[userID]
+	if !ok {
+		return nil, trader.ErrNotFound
+	}
+
+	t := &trader.Trader{}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SavePortfolio сохраняет снимок портфеля
+func (s *MemoryStore) SavePortfolio(t *trader.Trader) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.portfolios[t.UserID] = raw
+	return nil
+}
+
+// SaveTrade сохраняет снимок портфеля и добавляет сделку в историю пользователя
+func (s *MemoryStore) SaveTrade(t *trader.Trader, trade trader.Trade) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.portfolios[t.UserID] = raw
+	s.trades[t.UserID] = append(s.trades[t.UserID], trade)
+	return nil
+}
+
+// ListTrades возвращает сделки пользователя, начиная с самых новых
+func (s *MemoryStore) ListTrades(userID int64, offset, limit int) ([]trader.Trade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return page(s.trades[userID], offset, limit), nil
+}
+
+// ListUsers возвращает идентификаторы всех сохраненных портфелей
+func (s *MemoryStore) ListUsers() ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userIDs := make([]int64, 0, len(s.portfolios))
+	for userID := range s.portfolios {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// LoadState загружает сохраненное состояние по ключу и сообщает, было ли оно найдено
+func (s *MemoryStore) LoadState(key string, v any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.state[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// SaveState сохраняет снимок состояния по ключу
+func (s *MemoryStore) SaveState(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state[key] = raw
+	return nil
+}
+
+// page возвращает срез сделок в обратном хронологическом порядке
+func page(trades []trader.Trade, offset, limit int) []trader.Trade {
+	result := []trader.Trade{}
+	for i := len(trades) - 1 - offset; i >= 0 && len(result) < limit; i-- {
+		result = append(result, trades[i])
+	}
+	return result
+}
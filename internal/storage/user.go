@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+)
+
+// userFileExt задает расширение журналов пользователей
+const userFileExt = ".jsonl"
+
+// compactAfter задает, после скольких снимков портфеля без сделок журнал переписывается заново.
+// Снимки пишутся при каждом изменении заявок, а для загрузки нужен только последний из них
+const compactAfter = 1000
+
+// journalEntry описывает строку журнала пользователя: снимок портфеля, сделку или сделку вместе
+// с портфелем после нее. Сделка и портфель пишутся одной строкой, поэтому сохраняются вместе или не сохраняются вовсе
+type journalEntry struct {
+	Portfolio json.RawMessage `json:",omitempty"`
+	Trade     *trader.Trade   `json:",omitempty"`
+}
+
+// userFile ведет журнал одного пользователя в формате JSON Lines. Записи только дописываются
+// в конец файла, поэтому стоимость записи не зависит от длины истории, а запись одного пользователя
+// не ждет записи других
+type userFile struct {
+	mu        sync.Mutex // Защищает файл и кэш; методы вызываются под блокировкой
+	path      string
+	loaded    bool
+	size      int64           // Длина файла после последней успешной записи
+	snapshots int             // Снимки портфеля без сделок с момента последнего сжатия
+	portfolio json.RawMessage // nil, если портфель еще не создан
+	trades    []trader.Trade
+}
+
+// load читает журнал пользователя, если он еще не прочитан. Оборванная последняя строка
+// остается от прерванной записи и отбрасывается
+func (u *userFile) load() error {
+	if u.loaded {
+		return nil
+	}
+
+	raw, err := os.ReadFile(u.path)
+	if errors.Is(err, os.ErrNotExist) {
+		u.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения журнала портфеля: %w", err)
+	}
+
+	var (
+		portfolio json.RawMessage
+		trades    []trader.Trade
+		snapshots int
+		offset    int64
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, len(raw)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		end := offset + int64(len(line)) + 1
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if end < int64(len(raw)) {
+				return fmt.Errorf("ошибка разбора журнала портфеля %s: %w", u.path, err)
+			}
+			log.Printf("Журнал %s: отброшена незавершенная запись", u.path)
+			break
+		}
+		if end > int64(len(raw)) {
+			// Строка без перевода строки записана не до конца, даже если разобралась
+			log.Printf("Журнал %s: отброшена незавершенная запись", u.path)
+			break
+		}
+
+		if entry.Portfolio != nil {
+			portfolio = entry.Portfolio
+			if entry.Trade == nil {
+				snapshots++
+			}
+		}
+		if entry.Trade != nil {
+			trades = append(trades, *entry.Trade)
+		}
+		offset = end
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения журнала портфеля: %w", err)
+	}
+
+	if offset < int64(len(raw)) {
+		if err := os.Truncate(u.path, offset); err != nil {
+			return fmt.Errorf("ошибка восстановления журнала портфеля: %w", err)
+		}
+	}
+
+	u.portfolio, u.trades, u.snapshots, u.size, u.loaded = portfolio, trades, snapshots, offset, true
+	return nil
+}
+
+// savePortfolio дописывает в журнал снимок портфеля
+func (u *userFile) savePortfolio(portfolio json.RawMessage) error {
+	if err := u.append(journalEntry{Portfolio: portfolio}); err != nil {
+		return err
+	}
+	u.portfolio = portfolio
+	u.snapshots++
+
+	if u.snapshots >= compactAfter {
+		if err := u.compact(); err != nil {
+			// Журнал остается корректным и без сжатия
+			log.Printf("Ошибка сжатия журнала %s: %v", u.path, err)
+		}
+	}
+	return nil
+}
+
+// saveTrade дописывает в журнал сделку вместе с портфелем после нее
+func (u *userFile) saveTrade(portfolio json.RawMessage, trade trader.Trade) error {
+	if err := u.append(journalEntry{Portfolio: portfolio, Trade: &trade}); err != nil {
+		return err
+	}
+	u.portfolio = portfolio
+	u.trades = append(u.trades, trade)
+	return nil
+}
+
+// append дописывает строку в конец журнала. Если запись не удалась, файл обрезается
+// до прежней длины, чтобы следующая строка не склеилась с оборванной
+func (u *userFile) append(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога хранилища: %w", err)
+	}
+	file, err := os.OpenFile(u.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала портфеля: %w", err)
+	}
+
+	_, err = file.Write(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if truncateErr := os.Truncate(u.path, u.size); truncateErr != nil {
+			log.Printf("Ошибка восстановления журнала %s: %v", u.path, truncateErr)
+		}
+		return fmt.Errorf("ошибка записи журнала портфеля: %w", err)
+	}
+
+	u.size += int64(len(line))
+	return nil
+}
+
+// compact переписывает журнал: сделки по одной в строке и последний снимок портфеля в конце
+func (u *userFile) compact() error {
+	raw, err := encodeJournal(u.portfolio, u.trades)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(u.path, raw); err != nil {
+		return err
+	}
+
+	u.size, u.snapshots = int64(len(raw)), 0
+	return nil
+}
+
+// encodeJournal кодирует сделки и портфель в сжатый журнал
+func encodeJournal(portfolio json.RawMessage, trades []trader.Trade) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for i := range trades {
+		if err := encoder.Encode(journalEntry{Trade: &trades[i]}); err != nil {
+			return nil, err
+		}
+	}
+	if portfolio != nil {
+		if err := encoder.Encode(journalEntry{Portfolio: portfolio}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,345 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+// dcaStateKey задает ключ состояния планов DCA в хранилище
+const dcaStateKey = "dca"
+
+// dcaMaxSkips задает, после скольких пропущенных подряд покупок план останавливается
+const dcaMaxSkips = 5
+
+// dcaBudgetTolerance задает остаток бюджета, меньше которого план считается исчерпанным:
+// покупка округляется вниз до шага лота и может потратить чуть меньше указанной суммы
+var dcaBudgetTolerance = decimal.RequireFromString("0.01")
+
+// DCAConfig описывает план регулярных покупок
+type DCAConfig struct {
+	Token           string
+	Amount          decimal.Decimal // Сумма одной покупки в USDT
+	Schedule        string          // hourly, daily, weekly или cron-выражение
+	BoostPercent    decimal.Decimal // Покупать больше, если цена ниже средней цены позиции на этот процент
+	BoostMultiplier decimal.Decimal // Во сколько раз увеличить покупку при снижении цены
+	Budget          decimal.Decimal // Общий лимит трат в USDT, 0 — без лимита
+}
+
+// Validate проверяет параметры плана
+func (c DCAConfig) Validate() error {
+	if !c.Amount.IsPositive() {
+		return fmt.Errorf("сумма покупки должна быть больше нуля")
+	}
+	if c.Budget.IsNegative() {
+		return fmt.Errorf("бюджет не может быть отрицательным")
+	}
+	if c.BoostPercent.IsNegative() || c.BoostPercent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("порог усиленной покупки должен быть от 0 до 100%%")
+	}
+	if c.BoostPercent.IsPositive() && c.BoostMultiplier.LessThanOrEqual(decimal.NewFromInt(1)) {
+		return fmt.Errorf("множитель усиленной покупки должен быть больше 1")
+	}
+	_, err := ParseSchedule(c.Schedule)
+	return err
+}
+
+// DCABuy описывает покупку, выполненную по плану
+type DCABuy struct {
+	Time     time.Time
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+	Cash     decimal.Decimal
+	Boosted  bool
+}
+
+// DCAPlan хранит состояние плана регулярных покупок пользователя
+type DCAPlan struct {
+	ID        int64
+	UserID    int64
+	Config    DCAConfig
+	Spent     decimal.Decimal
+	Buys      []DCABuy // Только выполненные покупки
+	Skipped   int      // Количество пропущенных покупок
+	SkipRun   int      // Количество пропущенных подряд покупок
+	LastError string   `json:",omitempty"` // Причина последнего пропуска
+	NextRun   time.Time
+	Active    bool
+	Stopped   string `json:",omitempty"` // Причина завершения плана
+	CreatedAt time.Time
+}
+
+// stop завершает план с указанной причиной
+func (p *DCAPlan) stop(reason string) {
+	p.Active = false
+	p.Stopped = reason
+}
+
+// AveragePrice возвращает среднюю цену покупок по плану
+func (p DCAPlan) AveragePrice() decimal.Decimal {
+	quantity, cash := decimal.Zero, decimal.Zero
+	for _, buy := range p.Buys {
+		quantity = quantity.Add(buy.Quantity)
+		cash = cash.Add(buy.Cash)
+	}
+	if quantity.IsZero() {
+		return decimal.Zero
+	}
+	return cash.Div(quantity)
+}
+
+// DCAManager выполняет планы регулярных покупок по расписанию
+type DCAManager struct {
+	Portfolios *trader.Portfolios
+	Feed       market.PriceFeed
+	OnBuy      func(plan DCAPlan, buy DCABuy, err error) // err описывает причину пропуска покупки
+	state      StateStore
+	mu         sync.Mutex // Защищает планы: команды бота и запуски по расписанию приходят из разных горутин
+	plans      map[int64]*DCAPlan
+	nextID     int64
+}
+
+// dcaState описывает сохраняемое состояние менеджера
+type dcaState struct {
+	NextID int64
+	Plans  map[int64]*DCAPlan
+}
+
+// NewDCAManager создает менеджер планов DCA и восстанавливает сохраненное состояние
+func NewDCAManager(portfolios *trader.Portfolios, state StateStore, feed market.PriceFeed) (*DCAManager, error) {
+	saved := dcaState{Plans: make(map[int64]*DCAPlan)}
+	if _, err := state.LoadState(dcaStateKey, &saved); err != nil {
+		return nil, err
+	}
+
+	return &DCAManager{
+		Portfolios: portfolios,
+		Feed:       feed,
+		state:      state,
+		plans:      saved.Plans,
+		nextID:     saved.NextID,
+	}, nil
+}
+
+// Create добавляет план и назначает первый запуск по расписанию
+func (m *DCAManager) Create(userID int64, cfg DCAConfig, now time.Time) (DCAPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := cfg.Validate(); err != nil {
+		return DCAPlan{}, err
+	}
+	next, err := nextRun(cfg.Schedule, now)
+	if err != nil {
+		return DCAPlan{}, err
+	}
+
+	m.nextID++
+	plan := &DCAPlan{
+		ID:        m.nextID,
+		UserID:    userID,
+		Config:    cfg,
+		NextRun:   next,
+		Active:    true,
+		CreatedAt: now,
+	}
+	m.plans[plan.ID] = plan
+	return *plan, m.save()
+}
+
+// Cancel удаляет план пользователя
+func (m *DCAManager) Cancel(userID, id int64) (DCAPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	plan, ok := m.plans[id]
+	if !ok || plan.UserID != userID {
+		return DCAPlan{}, fmt.Errorf("план #%d не найден", id)
+	}
+
+	delete(m.plans, id)
+	return *plan, m.save()
+}
+
+// Plans возвращает планы пользователя в порядке создания
+func (m *DCAManager) Plans(userID int64) []DCAPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var plans []DCAPlan
+	for _, plan := range m.plans {
+		if plan.UserID == userID {
+			plans = append(plans, *plan)
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ID < plans[j].ID })
+	return plans
+}
+
+// Run проверяет планы с заданным интервалом, пока не будет отменен контекст
+func (m *DCAManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Step(now)
+		}
+	}
+}
+
+// Step выполняет покупки по всем планам, время которых наступило. Планы выбираются под блокировкой,
+// а цена и покупка запрашиваются без нее, чтобы медленный источник цен не задерживал команды бота
+func (m *DCAManager) Step(now time.Time) {
+	for _, plan := range m.due(now) {
+		buy, err := m.execute(plan, now)
+		plan = m.finish(plan, buy, err)
+		if m.OnBuy != nil {
+			m.OnBuy(plan, buy, err)
+		}
+	}
+}
+
+// due возвращает копии планов, время которых наступило, и сразу назначает им следующий запуск,
+// чтобы параллельный вызов Step не выполнил ту же покупку дважды. План без следующего запуска
+// выполняет наступившую покупку и завершается
+func (m *DCAManager) due(now time.Time) []DCAPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []DCAPlan
+	changed := false
+	for _, plan := range m.plans {
+		if !plan.Active || now.Before(plan.NextRun) {
+			continue
+		}
+		changed = true
+
+		next, err := nextRun(plan.Config.Schedule, now)
+		if err != nil {
+			log.Printf("План DCA #%d остановлен: %v", plan.ID, err)
+			plan.stop(err.Error())
+		} else {
+			plan.NextRun = next
+		}
+		due = append(due, *plan)
+	}
+
+	if changed {
+		if err := m.save(); err != nil {
+			log.Printf("Ошибка сохранения планов DCA: %v", err)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	return due
+}
+
+// nextRun возвращает время следующего запуска по расписанию; отсутствие запуска считается ошибкой
+func nextRun(spec string, now time.Time) (time.Time, error) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next, ok := schedule.Next(now)
+	if !ok {
+		return time.Time{}, fmt.Errorf("у расписания %q больше нет запусков", spec)
+	}
+	return next, nil
+}
+
+// finish учитывает результат покупки в плане и возвращает его копию. План мог быть удален
+// во время покупки: тогда возвращается завершенная копия переданного, чтобы пользователь все равно узнал о сделке
+func (m *DCAManager) finish(plan DCAPlan, buy DCABuy, err error) DCAPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.plans[plan.ID]
+	if !ok {
+		plan.stop("план удален пользователем")
+		return plan
+	}
+
+	if err == nil {
+		current.Buys = append(current.Buys, buy)
+		current.Spent = current.Spent.Add(buy.Cash)
+		current.SkipRun = 0
+	} else {
+		current.Skipped++
+		current.SkipRun++
+		current.LastError = err.Error()
+	}
+	switch {
+	case !current.Active:
+		// План уже завершен, например, у расписания больше нет запусков
+	case current.Config.Budget.IsPositive() && current.Config.Budget.Sub(current.Spent).LessThan(dcaBudgetTolerance):
+		current.stop("бюджет исчерпан")
+	case current.SkipRun >= dcaMaxSkips:
+		current.stop(fmt.Sprintf("%d покупок подряд пропущено", current.SkipRun))
+	}
+
+	if err := m.save(); err != nil {
+		log.Printf("Ошибка сохранения планов DCA: %v", err)
+	}
+	return *current
+}
+
+// execute выполняет одну покупку по плану с учетом усиления и бюджета; ошибка означает, что покупка пропущена
+func (m *DCAManager) execute(plan DCAPlan, now time.Time) (DCABuy, error) {
+	buy := DCABuy{Time: now}
+
+	t, err := m.Portfolios.Get(plan.UserID)
+	if err != nil {
+		return buy, err
+	}
+
+	price, err := m.Feed.Last(plan.Config.Token)
+	if err != nil {
+		return buy, fmt.Errorf("ошибка получения цены: %w", err)
+	}
+
+	amount := plan.Config.Amount
+	if position, ok := t.Position(plan.Config.Token); ok && plan.Config.BoostPercent.IsPositive() {
+		average := position.AveragePrice()
+		if price.LessThanOrEqual(average.Sub(average.Mul(plan.Config.BoostPercent).Div(decimal.NewFromInt(100)))) {
+			amount = amount.Mul(plan.Config.BoostMultiplier)
+			buy.Boosted = true
+		}
+	}
+
+	// Последняя покупка ограничивается остатком бюджета, после чего план завершается
+	if plan.Config.Budget.IsPositive() {
+		amount = decimal.Min(amount, plan.Config.Budget.Sub(plan.Spent))
+		if !amount.IsPositive() {
+			return buy, fmt.Errorf("бюджет плана исчерпан")
+		}
+	}
+
+	quote, err := t.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: plan.Config.Token, Mode: trader.ByNotional, Value: amount}, price)
+	if err != nil {
+		return buy, err
+	}
+	trade, err := t.Execute(quote)
+	if err != nil {
+		return buy, err
+	}
+
+	buy.Quantity = trade.Quantity
+	buy.Price = trade.Price
+	buy.Cash = trade.QuoteAmount
+	return buy, nil
+}
+
+// save сохраняет все планы
+func (m *DCAManager) save() error {
+	return m.state.SaveState(dcaStateKey, dcaState{NextID: m.nextID, Plans: m.plans})
+}
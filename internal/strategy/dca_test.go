@@ -0,0 +1,208 @@
+package strategy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+const token = "BTC-USDT"
+
+// fakeFeed отдает заданную цену токена; остальные методы источника цен в тестах не вызываются
+type fakeFeed struct {
+	market.PriceFeed
+	price decimal.Decimal
+	err   error
+}
+
+func (f *fakeFeed) Last(string) (decimal.Decimal, error) {
+	return f.price, f.err
+}
+
+// newPortfolios создает портфели в памяти без комиссий и проскальзывания и открывает портфель пользователя 1.
+// instruments задают торговые правила; без них правила не проверяются
+func newPortfolios(t *testing.T, store *storage.MemoryStore, instruments ...okx.Instrument) *trader.Portfolios {
+	t.Helper()
+	costs := trader.CostModel{Slippage: trader.NoSlippage{}}
+	portfolios := trader.NewPortfolios(store, decimal.NewFromInt(1000), trader.AverageCost, costs)
+	if len(instruments) > 0 {
+		portfolios.Instruments = market.NewStaticInstruments(instruments).Lookup
+	}
+	if _, err := portfolios.Open(1); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return portfolios
+}
+
+func TestDCAStep(t *testing.T) {
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		cfg        DCAConfig
+		prices     []string // Цена на каждом запуске; пустая строка — ошибка источника цен
+		wantCash   []string // Сумма каждой выполненной покупки
+		wantSkips  int
+		wantActive bool
+		wantStop   string
+	}{
+		{
+			name:       "без лимита",
+			cfg:        DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly"},
+			prices:     []string{"100", "100", "100"},
+			wantCash:   []string{"10", "10", "10"},
+			wantActive: true,
+		},
+		{
+			name:     "последняя покупка ограничена остатком бюджета",
+			cfg:      DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly", Budget: decimal.NewFromInt(25)},
+			prices:   []string{"100", "100", "100", "100"},
+			wantCash: []string{"10", "10", "5"},
+			wantStop: "бюджет исчерпан",
+		},
+		{
+			name:       "усиленная покупка при снижении цены",
+			cfg:        DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly", BoostPercent: decimal.NewFromInt(10), BoostMultiplier: decimal.NewFromInt(3)},
+			prices:     []string{"100", "125", "80"},
+			wantCash:   []string{"10", "10", "30"},
+			wantActive: true,
+		},
+		{
+			name:     "усиленная покупка не превышает бюджет",
+			cfg:      DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly", Budget: decimal.NewFromInt(20), BoostPercent: decimal.NewFromInt(10), BoostMultiplier: decimal.NewFromInt(3)},
+			prices:   []string{"100", "50"},
+			wantCash: []string{"10", "10"},
+			wantStop: "бюджет исчерпан",
+		},
+		{
+			name:       "пропуск покупки без цены",
+			cfg:        DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly"},
+			prices:     []string{"100", "", "100"},
+			wantCash:   []string{"10", "10"},
+			wantSkips:  1,
+			wantActive: true,
+		},
+		{
+			name:      "остановка после пропусков подряд",
+			cfg:       DCAConfig{Amount: decimal.NewFromInt(10), Schedule: "hourly"},
+			prices:    []string{"", "", "", "", "", "100"},
+			wantSkips: dcaMaxSkips,
+			wantStop:  "5 покупок подряд пропущено",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			feed := &fakeFeed{}
+			manager, err := NewDCAManager(newPortfolios(t, store), store, feed)
+			if err != nil {
+				t.Fatalf("NewDCAManager: %v", err)
+			}
+			test.cfg.Token = token
+
+			var stops []string
+			manager.OnBuy = func(plan DCAPlan, _ DCABuy, _ error) {
+				if !plan.Active {
+					stops = append(stops, plan.Stopped)
+				}
+			}
+
+			plan, err := manager.Create(1, test.cfg, start)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			now := start
+			for _, price := range test.prices {
+				now = now.Add(time.Hour)
+				feed.price, feed.err = decimal.Zero, errors.New("нет цены")
+				if price != "" {
+					feed.price, feed.err = decimal.RequireFromString(price), nil
+				}
+				manager.Step(now)
+			}
+
+			plan = manager.Plans(1)[0]
+			if len(plan.Buys) != len(test.wantCash) {
+				t.Fatalf("покупок %d, ожидалось %d", len(plan.Buys), len(test.wantCash))
+			}
+			for i, want := range test.wantCash {
+				if got := plan.Buys[i].Cash; !got.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("покупка %d на %s, ожидалось %s", i, got, want)
+				}
+			}
+			if plan.Skipped != test.wantSkips {
+				t.Errorf("пропущено %d, ожидалось %d", plan.Skipped, test.wantSkips)
+			}
+			if plan.Active != test.wantActive || plan.Stopped != test.wantStop {
+				t.Errorf("активен %v, завершен %q, ожидалось %v и %q", plan.Active, plan.Stopped, test.wantActive, test.wantStop)
+			}
+			if test.wantStop != "" && (len(stops) != 1 || stops[0] != test.wantStop) {
+				t.Errorf("уведомления о завершении %q, ожидалось одно %q", stops, test.wantStop)
+			}
+		})
+	}
+}
+
+func TestDCAStepWithoutNextRun(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	// Расписание могло испортиться в сохраненном состоянии: наступившая покупка выполняется, а план завершается
+	saved := dcaState{NextID: 1, Plans: map[int64]*DCAPlan{1: {
+		ID:      1,
+		UserID:  1,
+		Config:  DCAConfig{Token: token, Amount: decimal.NewFromInt(10), Schedule: "0 0 30 2 *"},
+		NextRun: now,
+		Active:  true,
+	}}}
+	if err := store.SaveState(dcaStateKey, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewDCAManager(newPortfolios(t, store), store, &fakeFeed{price: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatalf("NewDCAManager: %v", err)
+	}
+	manager.Step(now)
+	manager.Step(now.Add(time.Hour))
+
+	plan := manager.Plans(1)[0]
+	if len(plan.Buys) != 1 {
+		t.Errorf("покупок %d, ожидалась 1", len(plan.Buys))
+	}
+	if plan.Active || !strings.Contains(plan.Stopped, "никогда не срабатывает") {
+		t.Errorf("активен %v, завершен %q, ожидался завершенный план", plan.Active, plan.Stopped)
+	}
+}
+
+func TestDCACancelDuringBuy(t *testing.T) {
+	store := storage.NewMemoryStore()
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	manager, err := NewDCAManager(newPortfolios(t, store), store, &fakeFeed{price: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatalf("NewDCAManager: %v", err)
+	}
+
+	plan, err := manager.Create(1, DCAConfig{Token: token, Amount: decimal.NewFromInt(10), Schedule: "hourly"}, start)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	due := manager.due(start.Add(time.Hour))
+	if _, err := manager.Cancel(1, plan.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	buy, err := manager.execute(due[0], start.Add(time.Hour))
+	finished := manager.finish(due[0], buy, err)
+	if finished.Active || finished.Stopped != "план удален пользователем" {
+		t.Errorf("активен %v, завершен %q, ожидалось удаление пользователем", finished.Active, finished.Stopped)
+	}
+}
@@ -0,0 +1,398 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+// Состояния сеточной стратегии
+const (
+	GridRunning = "running"
+	GridPaused  = "paused"
+)
+
+// GridTag отмечает заявки, выставленные сеточной стратегией
+const GridTag = "grid"
+
+// gridStateKey задает ключ состояния сеток в хранилище
+const gridStateKey = "grids"
+
+// gridPrecision задает количество знаков после запятой при расчете уровней сетки
+const gridPrecision = 16
+
+// GridConfig описывает параметры сетки
+type GridConfig struct {
+	Token     string
+	Lower     decimal.Decimal
+	Upper     decimal.Decimal
+	Levels    int
+	Geometric bool            // Геометрический шаг (равный процент) вместо арифметического (равная разница цен)
+	Quantity  decimal.Decimal // Количество токенов на один уровень
+}
+
+// Validate проверяет параметры сетки
+func (c GridConfig) Validate() error {
+	if !c.Lower.IsPositive() || c.Upper.LessThanOrEqual(c.Lower) {
+		return fmt.Errorf("нижняя граница должна быть больше нуля и меньше верхней")
+	}
+	if c.Levels < 2 || c.Levels > 100 {
+		return fmt.Errorf("количество уровней должно быть от 2 до 100")
+	}
+	if !c.Quantity.IsPositive() {
+		return fmt.Errorf("размер уровня должен быть больше нуля")
+	}
+	return nil
+}
+
+// Prices возвращает цены уровней сетки по возрастанию, рассчитанные с точностью gridPrecision знаков.
+// Геометрический уровень i равен Lower * exp(ln(Upper/Lower) * i / (Levels-1)); крайние уровни совпадают с границами.
+// К шагу цены инструмента уровни приводятся при выставлении заявок
+func (c GridConfig) Prices() []decimal.Decimal {
+	prices := make([]decimal.Decimal, c.Levels)
+	intervals := decimal.NewFromInt(int64(c.Levels - 1))
+
+	// Validate гарантирует, что границы положительны, поэтому логарифм определен
+	ratio, _ := c.Upper.Div(c.Lower).Ln(gridPrecision)
+	for i := range prices {
+		step := decimal.NewFromInt(int64(i))
+		switch {
+		case i == 0:
+			prices[i] = c.Lower
+		case i == len(prices)-1:
+			prices[i] = c.Upper
+		case c.Geometric:
+			factor, _ := ratio.Mul(step).DivRound(intervals, gridPrecision).ExpTaylor(gridPrecision)
+			prices[i] = c.Lower.Mul(factor).Round(gridPrecision)
+		default:
+			prices[i] = c.Lower.Add(c.Upper.Sub(c.Lower).Mul(step).DivRound(intervals, gridPrecision))
+		}
+	}
+	return prices
+}
+
+// Grid хранит состояние запущенной сетки пользователя
+type Grid struct {
+	UserID    int64
+	Config    GridConfig
+	Status    string
+	Orders    map[int64]int // Номер заявки -> индекс уровня
+	Fills     int
+	Profit    decimal.Decimal // Реализованный PnL продаж сетки
+	Fees      decimal.Decimal
+	StartedAt time.Time
+}
+
+// GridManager запускает сетки пользователей и переставляет заявки при их исполнении
+type GridManager struct {
+	Portfolios *trader.Portfolios
+	state      StateStore
+	mu         sync.Mutex // Защищает grids: команды бота и исполнения заявок приходят из разных горутин
+	grids      map[int64]*Grid
+}
+
+// NewGridManager создает менеджер сеток и восстанавливает сохраненное состояние
+func NewGridManager(portfolios *trader.Portfolios, state StateStore) (*GridManager, error) {
+	m := &GridManager{
+		Portfolios: portfolios,
+		state:      state,
+		grids:      make(map[int64]*Grid),
+	}
+
+	if _, err := state.LoadState(gridStateKey, &m.grids); err != nil {
+		return nil, err
+	}
+	for _, grid := range m.grids {
+		m.prune(grid)
+	}
+	return m, nil
+}
+
+// Start запускает сетку пользователя при текущей цене price
+func (m *GridManager) Start(userID int64, cfg GridConfig, price decimal.Decimal) (Grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := cfg.Validate(); err != nil {
+		return Grid{}, err
+	}
+	if existing, ok := m.grids[userID]; ok {
+		return Grid{}, fmt.Errorf("у вас уже есть сетка %s, а одновременно допускается только одна: остановите ее командой /grid stop", existing.Config.Token)
+	}
+
+	grid := &Grid{
+		UserID:    userID,
+		Config:    cfg,
+		Status:    GridRunning,
+		Orders:    make(map[int64]int),
+		StartedAt: m.Portfolios.Now(),
+	}
+	if err := m.arm(grid, price); err != nil {
+		return Grid{}, err
+	}
+
+	m.grids[userID] = grid
+	return *grid, m.save()
+}
+
+// Pause снимает заявки сетки, сохраняя ее параметры и статистику
+func (m *GridManager) Pause(userID int64) (Grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return Grid{}, fmt.Errorf("сетка не запущена")
+	}
+	if grid.Status == GridPaused {
+		return Grid{}, fmt.Errorf("сетка уже на паузе")
+	}
+
+	if err := m.disarm(grid); err != nil {
+		return Grid{}, err
+	}
+	grid.Status = GridPaused
+	return *grid, m.save()
+}
+
+// Resume заново выставляет заявки сетки при текущей цене price
+func (m *GridManager) Resume(userID int64, price decimal.Decimal) (Grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return Grid{}, fmt.Errorf("сетка не запущена")
+	}
+	if grid.Status == GridRunning {
+		return Grid{}, fmt.Errorf("сетка уже работает")
+	}
+
+	if err := m.arm(grid, price); err != nil {
+		return Grid{}, err
+	}
+	grid.Status = GridRunning
+	return *grid, m.save()
+}
+
+// Stop снимает заявки сетки и удаляет ее
+func (m *GridManager) Stop(userID int64) (Grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return Grid{}, fmt.Errorf("сетка не запущена")
+	}
+
+	if err := m.disarm(grid); err != nil {
+		return Grid{}, err
+	}
+	delete(m.grids, userID)
+	return *grid, m.save()
+}
+
+// Status возвращает состояние сетки пользователя
+func (m *GridManager) Status(userID int64) (Grid, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return Grid{}, false
+	}
+	return *grid, true
+}
+
+// OnCancel освобождает уровень, заявку которого пользователь отменил сам. Уровень будет выставлен снова
+// после исполнения соседнего уровня, как если бы он был пустым с самого начала
+func (m *GridManager) OnCancel(userID int64, order trader.Order) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return
+	}
+	if _, ok := grid.Orders[order.ID]; !ok {
+		return
+	}
+	delete(grid.Orders, order.ID)
+
+	if err := m.save(); err != nil {
+		log.Printf("Ошибка сохранения состояния сеток: %v", err)
+	}
+}
+
+// OnFill переставляет заявку на соседний уровень после исполнения заявки сетки
+func (m *GridManager) OnFill(userID int64, fill trader.Fill) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grid, ok := m.grids[userID]
+	if !ok {
+		return
+	}
+	// Заявки, отмененные вместе со сработавшей связанной заявкой, освобождают свои уровни.
+	// Остальные закрытые заявки сетки не трогаем: их исполнения еще могут прийти в этом же проходе
+	for _, canceled := range fill.Canceled {
+		delete(grid.Orders, canceled.ID)
+	}
+	level, ok := grid.Orders[fill.Order.ID]
+	if !ok {
+		if len(fill.Canceled) > 0 {
+			if err := m.save(); err != nil {
+				log.Printf("Ошибка сохранения состояния сеток: %v", err)
+			}
+		}
+		return
+	}
+	delete(grid.Orders, fill.Order.ID)
+
+	if fill.Err == nil {
+		grid.Fills++
+		grid.Fees = grid.Fees.Add(fill.Trade.Fee)
+		if fill.Trade.Side == trader.SideSell {
+			grid.Profit = grid.Profit.Add(fill.Trade.RealizedPnL)
+		}
+	}
+
+	// Исполненная покупка выставляет продажу уровнем выше, продажа — покупку уровнем ниже
+	if grid.Status == GridRunning && fill.Err == nil {
+		next, side := level+1, trader.SideSell
+		if fill.Order.Side == trader.SideSell {
+			next, side = level-1, trader.SideBuy
+		}
+
+		if err := m.place(grid, next, side); err != nil {
+			log.Printf("Ошибка перестановки заявки сетки пользователя %d: %v", userID, err)
+		}
+	}
+
+	if err := m.save(); err != nil {
+		log.Printf("Ошибка сохранения состояния сеток: %v", err)
+	}
+}
+
+// arm выставляет лестницу заявок: покупки ниже цены и продажи выше, оставляя ближайший к цене уровень пустым
+func (m *GridManager) arm(grid *Grid, price decimal.Decimal) error {
+	t, err := m.Portfolios.Get(grid.UserID)
+	if err != nil {
+		return err
+	}
+	prices, err := levels(grid.Config, t)
+	if err != nil {
+		return err
+	}
+
+	nearest := 0
+	for i, level := range prices {
+		if level.Sub(price).Abs().LessThan(prices[nearest].Sub(price).Abs()) {
+			nearest = i
+		}
+	}
+
+	for i, level := range prices {
+		if i == nearest {
+			continue
+		}
+
+		side := trader.SideBuy
+		if level.GreaterThan(price) {
+			side = trader.SideSell
+		}
+
+		err := m.place(grid, i, side)
+		// Продажи без токенов пропускаем: уровень займет продажа после исполнения покупки
+		if err != nil && side == trader.SideBuy {
+			return errors.Join(fmt.Errorf("не удалось выставить сетку: %w", err), m.disarm(grid))
+		}
+	}
+	return nil
+}
+
+// disarm отменяет все открытые заявки сетки
+func (m *GridManager) disarm(grid *Grid) error {
+	t, err := m.Portfolios.Get(grid.UserID)
+	if err != nil {
+		return err
+	}
+
+	for id := range grid.Orders {
+		if _, err := t.CancelOrder(id); err != nil {
+			log.Printf("Заявка сетки #%d уже не активна: %v", id, err)
+		}
+		delete(grid.Orders, id)
+	}
+	return nil
+}
+
+// prune освобождает уровни, заявки которых больше не открыты в портфеле. Вызывается при загрузке
+// состояния: заявки могли отменить или исполнить до перезапуска бота, не известив сетку
+func (m *GridManager) prune(grid *Grid) {
+	t, err := m.Portfolios.Get(grid.UserID)
+	if err != nil {
+		return
+	}
+
+	open := make(map[int64]bool)
+	for _, order := range t.OpenOrders() {
+		open[order.ID] = true
+	}
+	for id := range grid.Orders {
+		if !open[id] {
+			delete(grid.Orders, id)
+		}
+	}
+}
+
+// place выставляет заявку сетки на уровне level, если он существует и свободен
+func (m *GridManager) place(grid *Grid, level int, side string) error {
+	if level < 0 || level >= grid.Config.Levels {
+		return nil
+	}
+	for _, occupied := range grid.Orders {
+		if occupied == level {
+			return nil
+		}
+	}
+
+	t, err := m.Portfolios.Get(grid.UserID)
+	if err != nil {
+		return err
+	}
+	prices, err := levels(grid.Config, t)
+	if err != nil {
+		return err
+	}
+
+	order, err := t.PlaceLimit(side, grid.Config.Token, grid.Config.Quantity, prices[level], GridTag)
+	if err != nil {
+		return err
+	}
+	grid.Orders[order.ID] = level
+	return nil
+}
+
+// levels возвращает цены уровней сетки, округленные до шага цены инструмента. Если шаг сетки
+// меньше шага цены, соседние уровни совпадают после округления и сетка не выставляется
+func levels(cfg GridConfig, t *trader.Trader) ([]decimal.Decimal, error) {
+	prices := cfg.Prices()
+	for i := range prices {
+		prices[i] = t.RoundPrice(cfg.Token, prices[i])
+		if i > 0 && !prices[i].GreaterThan(prices[i-1]) {
+			return nil, fmt.Errorf("уровни сетки сливаются после округления до шага цены %s: уменьшите количество уровней или расширьте диапазон", prices[i])
+		}
+	}
+	return prices, nil
+}
+
+// save сохраняет состояние всех сеток
+func (m *GridManager) save() error {
+	return m.state.SaveState(gridStateKey, m.grids)
+}
@@ -0,0 +1,191 @@
+package strategy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+func TestGridConfigPrices(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  GridConfig
+		want []string
+	}{
+		{
+			name: "арифметическая",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(90), Upper: decimal.NewFromInt(110), Levels: 5},
+			want: []string{"90", "95", "100", "105", "110"},
+		},
+		{
+			name: "арифметическая с дробным шагом",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(1), Upper: decimal.NewFromInt(2), Levels: 4},
+			want: []string{"1", "1.3333333333333333", "1.6666666666666667", "2"},
+		},
+		{
+			name: "геометрическая",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(1), Upper: decimal.NewFromInt(16), Levels: 5, Geometric: true},
+			want: []string{"1", "2", "4", "8", "16"},
+		},
+		{
+			name: "геометрическая, два уровня",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(100), Upper: decimal.NewFromInt(400), Levels: 2, Geometric: true},
+			want: []string{"100", "400"},
+		},
+		{
+			name: "геометрическая с иррациональным шагом",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(100), Upper: decimal.NewFromInt(200), Levels: 3, Geometric: true},
+			want: []string{"100", "141.4213562373095", "200"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.cfg.Prices()
+			if len(got) != len(test.want) {
+				t.Fatalf("уровней %d, ожидалось %d", len(got), len(test.want))
+			}
+			for i, want := range test.want {
+				// Геометрические уровни сравниваются с точностью до 13 знаков: так записаны ожидаемые значения
+				if !got[i].Round(13).Equal(decimal.RequireFromString(want).Round(13)) {
+					t.Errorf("уровень %d: %s, ожидалось %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGridLevelsTick(t *testing.T) {
+	instrument := okx.Instrument{
+		InstID:   token,
+		BaseCcy:  "BTC",
+		QuoteCcy: "USDT",
+		LotSz:    decimal.RequireFromString("0.001"),
+		TickSz:   decimal.RequireFromString("0.5"),
+		MinSz:    decimal.RequireFromString("0.001"),
+		State:    okx.InstrumentLive,
+	}
+
+	tests := []struct {
+		name    string
+		cfg     GridConfig
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "геометрические уровни округляются до шага цены",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(100), Upper: decimal.NewFromInt(200), Levels: 3, Geometric: true},
+			want: []string{"100", "141.5", "200"},
+		},
+		{
+			name: "арифметические уровни округляются до шага цены",
+			cfg:  GridConfig{Lower: decimal.NewFromInt(100), Upper: decimal.NewFromInt(101), Levels: 4},
+			want: []string{"100", "100.5", "100.5", "101"},
+			// Второй и третий уровни сливаются
+			wantErr: "сливаются",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			portfolios := newPortfolios(t, storage.NewMemoryStore(), instrument)
+			portfolio, err := portfolios.Get(1)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			test.cfg.Token = token
+
+			got, err := levels(test.cfg, portfolio)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("levels: %v", err)
+			}
+			for i, want := range test.want {
+				if !got[i].Equal(decimal.RequireFromString(want)) {
+					t.Errorf("уровень %d: %s, ожидалось %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGridFills(t *testing.T) {
+	store := storage.NewMemoryStore()
+	portfolios := newPortfolios(t, store)
+	manager, err := NewGridManager(portfolios, store)
+	if err != nil {
+		t.Fatalf("NewGridManager: %v", err)
+	}
+	portfolio, err := portfolios.Get(1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	// Уровни 90, 95, 100, 105 и 110: при цене 100 выставляются покупки на 90 и 95,
+	// а продажи пропускаются, пока нет токенов
+	cfg := GridConfig{Token: token, Lower: decimal.NewFromInt(90), Upper: decimal.NewFromInt(110), Levels: 5, Quantity: decimal.NewFromInt(1)}
+	if _, err := manager.Start(1, cfg, decimal.NewFromInt(100)); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	steps := []struct {
+		price      string
+		wantLevels map[int]string // Уровень -> сторона открытой заявки
+		wantFills  int
+		wantProfit string
+	}{
+		{price: "100", wantLevels: map[int]string{0: trader.SideBuy, 1: trader.SideBuy}},
+		{price: "95", wantLevels: map[int]string{0: trader.SideBuy, 2: trader.SideSell}, wantFills: 1, wantProfit: "0"},
+		{price: "100", wantLevels: map[int]string{0: trader.SideBuy, 1: trader.SideBuy}, wantFills: 2, wantProfit: "5"},
+		// Покупка на 90 исполняется первой, пока уровень 95 еще занят своей исполненной покупкой,
+		// поэтому обе покупки оставляют одну продажу на 100
+		{price: "89", wantLevels: map[int]string{2: trader.SideSell}, wantFills: 4, wantProfit: "5"},
+	}
+
+	for _, step := range steps {
+		fills, err := portfolio.MatchOrders(token, decimal.RequireFromString(step.price))
+		if err != nil {
+			t.Fatalf("цена %s: MatchOrders: %v", step.price, err)
+		}
+		for _, fill := range fills {
+			manager.OnFill(1, fill)
+		}
+
+		grid, ok := manager.Status(1)
+		if !ok {
+			t.Fatalf("цена %s: сетка не найдена", step.price)
+		}
+		sides := make(map[int64]string)
+		for _, order := range portfolio.OpenOrders() {
+			sides[order.ID] = order.Side
+		}
+		got := make(map[int]string)
+		for id, level := range grid.Orders {
+			got[level] = sides[id]
+		}
+		if len(got) != len(step.wantLevels) {
+			t.Errorf("цена %s: уровни %v, ожидалось %v", step.price, got, step.wantLevels)
+		}
+		for level, side := range step.wantLevels {
+			if got[level] != side {
+				t.Errorf("цена %s: уровни %v, ожидалось %v", step.price, got, step.wantLevels)
+				break
+			}
+		}
+		if grid.Fills != step.wantFills {
+			t.Errorf("цена %s: исполнений %d, ожидалось %d", step.price, grid.Fills, step.wantFills)
+		}
+		if step.wantProfit != "" && !grid.Profit.Equal(decimal.RequireFromString(step.wantProfit)) {
+			t.Errorf("цена %s: прибыль %s, ожидалась %s", step.price, grid.Profit, step.wantProfit)
+		}
+	}
+}
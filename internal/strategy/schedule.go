@@ -0,0 +1,170 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears ограничивает поиск следующего запуска cron-расписания. За это время встречаются
+// и 29 февраля, и любое сочетание дня месяца с днем недели
+const cronSearchYears = 50
+
+// Schedule рассчитывает время следующего запуска
+type Schedule interface {
+	// Next возвращает первый запуск после after; false означает, что запусков больше не будет
+	Next(after time.Time) (time.Time, bool)
+}
+
+// ParseSchedule разбирает расписание: hourly, daily, weekly или cron-выражение из пяти полей
+// "минута час день-месяца месяц день-недели" с поддержкой *, списков, диапазонов и шага (*/15).
+// Воскресенье обозначается 0 или 7; ограниченные день месяца и день недели объединяются по ИЛИ.
+// Выражения, которые никогда не срабатывают, например "0 0 30 2 *", отклоняются
+func ParseSchedule(spec string) (Schedule, error) {
+	switch strings.TrimSpace(spec) {
+	case "hourly":
+		return everySchedule(time.Hour), nil
+	case "daily":
+		return everySchedule(24 * time.Hour), nil
+	case "weekly":
+		return everySchedule(7 * 24 * time.Hour), nil
+	}
+	return parseCron(spec)
+}
+
+// everySchedule запускается через равные интервалы
+type everySchedule time.Duration
+
+func (s everySchedule) Next(after time.Time) (time.Time, bool) {
+	return after.Add(time.Duration(s)), true
+}
+
+// cronSchedule хранит допустимые значения каждого поля cron-выражения
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+
+	// Как и в стандартном cron, если ограничены и день месяца, и день недели,
+	// подходит день, совпавший хотя бы с одним из них
+	anyDay, anyWeekday bool
+}
+
+// parseCron разбирает cron-выражение из пяти полей
+func parseCron(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("расписание должно быть hourly, daily, weekly или cron-выражением из 5 полей: %q", spec)
+	}
+
+	// День недели 7, как и 0, обозначает воскресенье
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("некорректное поле cron %q: %w", field, err)
+		}
+		sets[i] = set
+	}
+
+	if sets[4][7] {
+		delete(sets[4], 7)
+		sets[4][0] = true
+	}
+
+	schedule := cronSchedule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	// Поиск с начала високосного года находит любую существующую дату, включая 29 февраля
+	if _, ok := schedule.Next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)); !ok {
+		return nil, fmt.Errorf("расписание %q никогда не срабатывает", spec)
+	}
+	return schedule, nil
+}
+
+// parseCronField разбирает одно поле cron: "*", "5", "1,15", "9-17", "*/10" или "0-30/5"
+func parseCronField(field string, low, high int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("неверный шаг %q", stepPart)
+			}
+		}
+
+		from, to := low, high
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = strconv.Atoi(startPart); err != nil {
+				return nil, fmt.Errorf("неверное значение %q", startPart)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(endPart); err != nil {
+					return nil, fmt.Errorf("неверное значение %q", endPart)
+				}
+			} else if hasStep {
+				to = high
+			}
+		}
+		if from < low || to > high || from > to {
+			return nil, fmt.Errorf("значение вне диапазона %d-%d", low, high)
+		}
+
+		for value := from; value <= to; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+// Next перебирает дни после after, пропуская неподходящие месяцы и дни, и в первом подходящем
+// дне ищет ближайшие час и минуту. Ищет не дальше чем на cronSearchYears лет вперед
+func (s cronSchedule) Next(after time.Time) (time.Time, bool) {
+	start := after.Truncate(time.Minute).Add(time.Minute)
+	year, month, day := start.Date()
+	limit := start.AddDate(cronSearchYears, 0, 0)
+
+	for date := time.Date(year, month, day, 0, 0, 0, 0, start.Location()); date.Before(limit); date = date.AddDate(0, 0, 1) {
+		if !s.months[int(date.Month())] || !s.dayMatches(date) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !s.hours[hour] {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !s.minutes[minute] {
+					continue
+				}
+				next := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location())
+				// Время, пропущенное при переводе часов, нормализуется в другое; такой запуск не выполняется
+				if next.Hour() != hour || next.Minute() != minute || next.Before(start) {
+					continue
+				}
+				return next, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayMatches проверяет день месяца и день недели
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
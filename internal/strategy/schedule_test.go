@@ -0,0 +1,112 @@
+package strategy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{spec: "hourly"},
+		{spec: " daily "},
+		{spec: "weekly"},
+		{spec: "*/15 9-17 * * 1-5"},
+		{spec: "0 12 29 2 *"},
+		{spec: "0 0 31 1,2 *"},
+		{spec: "0 0 * * 7"},
+		{spec: "monthly", wantErr: "5 полей"},
+		{spec: "0 0 * *", wantErr: "5 полей"},
+		{spec: "60 * * * *", wantErr: "вне диапазона"},
+		{spec: "*/0 * * * *", wantErr: "неверный шаг"},
+		{spec: "5-1 * * * *", wantErr: "вне диапазона"},
+		{spec: "a * * * *", wantErr: "неверное значение"},
+		{spec: "0 0 30 2 *", wantErr: "никогда не срабатывает"},
+		{spec: "0 0 31 4,6,9,11 *", wantErr: "никогда не срабатывает"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := ParseSchedule(test.spec)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("%q: %v", test.spec, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("%q: ошибка %v, ожидалась %q", test.spec, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		after string
+		want  string
+	}{
+		{name: "каждый час", spec: "hourly", after: "2024-03-10 10:20:30", want: "2024-03-10 11:20:30"},
+		{name: "каждую неделю", spec: "weekly", after: "2024-03-10 10:00:00", want: "2024-03-17 10:00:00"},
+		{name: "следующая минута", spec: "* * * * *", after: "2024-03-10 10:20:30", want: "2024-03-10 10:21:00"},
+		{name: "ровно в момент запуска", spec: "30 10 * * *", after: "2024-03-10 10:30:00", want: "2024-03-11 10:30:00"},
+		{name: "шаг по минутам", spec: "*/15 * * * *", after: "2024-03-10 10:16:00", want: "2024-03-10 10:30:00"},
+		{name: "переход через сутки", spec: "0 9 * * *", after: "2024-03-10 23:59:00", want: "2024-03-11 09:00:00"},
+		{name: "день недели", spec: "0 9 * * 1", after: "2024-03-10 10:00:00", want: "2024-03-11 09:00:00"},
+		{name: "воскресенье как 7", spec: "0 9 * * 7", after: "2024-03-11 10:00:00", want: "2024-03-17 09:00:00"},
+		{name: "день месяца или недели", spec: "0 0 15 * 1", after: "2024-03-12 00:00:00", want: "2024-03-15 00:00:00"},
+		{name: "31 число пропускает короткие месяцы", spec: "0 0 31 * *", after: "2024-04-01 00:00:00", want: "2024-05-31 00:00:00"},
+		{name: "29 февраля", spec: "0 12 29 2 *", after: "2024-03-01 00:00:00", want: "2028-02-29 12:00:00"},
+		{name: "29 февраля через невисокосный 2100 год", spec: "0 12 29 2 *", after: "2096-03-01 00:00:00", want: "2104-02-29 12:00:00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.spec)
+			if err != nil {
+				t.Fatalf("%q: %v", test.spec, err)
+			}
+			got, ok := schedule.Next(at(test.after))
+			if !ok {
+				t.Fatalf("после %s запуск не найден", test.after)
+			}
+			if want := at(test.want); !got.Equal(want) {
+				t.Errorf("после %s запуск %s, ожидался %s", test.after, got, want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextSkipsMissingTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("нет данных часовых поясов: %v", err)
+	}
+
+	schedule, err := ParseSchedule("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 31 марта 2024 года в Берлине часы переводятся с 02:00 сразу на 03:00
+	got, ok := schedule.Next(time.Date(2024, time.March, 30, 12, 0, 0, 0, berlin))
+	if !ok {
+		t.Fatal("запуск не найден")
+	}
+	if want := time.Date(2024, time.April, 1, 2, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("запуск %s, ожидался %s", got, want)
+	}
+}
@@ -0,0 +1,9 @@
+package strategy
+
+// StateStore сохраняет состояние стратегий между перезапусками
+type StateStore interface {
+	// LoadState загружает состояние по ключу и сообщает, было ли оно найдено
+	LoadState(key string, v any) (bool, error)
+	// SaveState сохраняет состояние по ключу
+	SaveState(key string, v any) error
+}
@@ -0,0 +1,136 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// Ставки комиссий OKX на спотовом рынке для базового уровня Regular user (LV1)
+var (
+	DefaultMakerFee = decimal.RequireFromString("0.0008")
+	DefaultTakerFee = decimal.RequireFromString("0.001")
+)
+
+// bookDepth задает количество уровней стакана для расчета проскальзывания
+const bookDepth = 50
+
+// hundred переводит проценты в доли
+var hundred = decimal.NewFromInt(100)
+
+// CostModel описывает издержки исполнения: комиссии биржи и проскальзывание
+type CostModel struct {
+	MakerFee decimal.Decimal // Доля от суммы сделки для лимитных заявок
+	TakerFee decimal.Decimal // Доля от суммы сделки для рыночных заявок
+	Slippage Slippage
+}
+
+// DefaultCostModel возвращает комиссии OKX по умолчанию без проскальзывания
+func DefaultCostModel() CostModel {
+	return CostModel{
+		MakerFee: DefaultMakerFee,
+		TakerFee: DefaultTakerFee,
+		Slippage: NoSlippage{},
+	}
+}
+
+// Slippage рассчитывает цену исполнения рыночной заявки с учетом проскальзывания
+type Slippage interface {
+	Apply(side, token string, quantity, price decimal.Decimal) (decimal.Decimal, error)
+}
+
+// NoSlippage исполняет заявки точно по рыночной цене
+type NoSlippage struct{}
+
+func (NoSlippage) Apply(side, token string, quantity, price decimal.Decimal) (decimal.Decimal, error) {
+	return price, nil
+}
+
+// FixedSlippage ухудшает цену исполнения на фиксированную величину в USDT
+type FixedSlippage struct {
+	Amount decimal.Decimal
+}
+
+func (s FixedSlippage) Apply(side, token string, quantity, price decimal.Decimal) (decimal.Decimal, error) {
+	if side == SideBuy {
+		return price.Add(s.Amount), nil
+	}
+	return decimal.Max(price.Sub(s.Amount), decimal.Zero), nil
+}
+
+// PercentSlippage ухудшает цену исполнения на процент от рыночной цены
+type PercentSlippage struct {
+	Percent decimal.Decimal
+}
+
+func (s PercentSlippage) Apply(side, token string, quantity, price decimal.Decimal) (decimal.Decimal, error) {
+	shift := price.Mul(s.Percent).Div(hundred)
+	if side == SideBuy {
+		return price.Add(shift), nil
+	}
+	return price.Sub(shift), nil
+}
+
+// BookSource возвращает стакан токена глубиной depth уровней, например market.PriceFeed.OrderBook
+type BookSource func(token string, depth int) (okx.OrderBook, error)
+
+// DepthSlippage рассчитывает среднюю цену исполнения по уровням стакана
+type DepthSlippage struct {
+	Book BookSource
+}
+
+func (s DepthSlippage) Apply(side, token string, quantity, price decimal.Decimal) (decimal.Decimal, error) {
+	book, err := s.Book(token, bookDepth)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	levels := book.Asks
+	if side == SideSell {
+		levels = book.Bids
+	}
+
+	// Проходим по уровням стакана, пока не наберем нужное количество
+	remaining := quantity
+	cost := decimal.Zero
+	for _, level := range levels {
+		filled := decimal.Min(level.Size, remaining)
+		cost = cost.Add(filled.Mul(level.Price))
+		remaining = remaining.Sub(filled)
+		if !remaining.IsPositive() {
+			return cost.Div(quantity), nil
+		}
+	}
+	return decimal.Zero, fmt.Errorf("недостаточно ликвидности в стакане %s для %s токенов", token, quantity)
+}
+
+// ParseSlippage разбирает настройку проскальзывания: none, depth, процент ("0.05%") или сумму в USDT ("1.5").
+// Для depth стаканы берутся из books; без источника стаканов такая настройка недоступна
+func ParseSlippage(spec string, books BookSource) (Slippage, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "", "none":
+		return NoSlippage{}, nil
+	case "depth":
+		if books == nil {
+			return nil, fmt.Errorf("проскальзывание по стакану недоступно без источника стаканов")
+		}
+		return DepthSlippage{Book: books}, nil
+	}
+
+	if percent, ok := strings.CutSuffix(spec, "%"); ok {
+		value, err := decimal.NewFromString(percent)
+		if err != nil || value.IsNegative() {
+			return nil, fmt.Errorf("некорректное проскальзывание: %s", spec)
+		}
+		return PercentSlippage{Percent: value}, nil
+	}
+
+	value, err := decimal.NewFromString(spec)
+	if err != nil || value.IsNegative() {
+		return nil, fmt.Errorf("некорректное проскальзывание: %s", spec)
+	}
+	return FixedSlippage{Amount: value}, nil
+}
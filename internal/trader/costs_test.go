@@ -0,0 +1,278 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// dec разбирает десятичное число из строки
+func dec(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// book возвращает источник стакана с заданными уровнями "цена, объем"
+func book(asks, bids [][2]string) trader.BookSource {
+	levels := func(raw [][2]string) []okx.BookLevel {
+		var result []okx.BookLevel
+		for _, level := range raw {
+			result = append(result, okx.BookLevel{Price: dec(level[0]), Size: dec(level[1])})
+		}
+		return result
+	}
+	return func(token string, depth int) (okx.OrderBook, error) {
+		return okx.OrderBook{Asks: levels(asks), Bids: levels(bids)}, nil
+	}
+}
+
+func TestSlippageApply(t *testing.T) {
+	depth := trader.DepthSlippage{Book: book(
+		[][2]string{{"100", "1"}, {"101", "1"}, {"105", "2"}},
+		[][2]string{{"99", "0.5"}, {"98", "1"}},
+	)}
+
+	tests := []struct {
+		name     string
+		slippage trader.Slippage
+		side     string
+		quantity string
+		want     string
+		wantErr  bool
+	}{
+		{name: "без проскальзывания", slippage: trader.NoSlippage{}, side: trader.SideBuy, quantity: "1", want: "100"},
+		{name: "фиксированное, покупка", slippage: trader.FixedSlippage{Amount: dec("1.5")}, side: trader.SideBuy, quantity: "1", want: "101.5"},
+		{name: "фиксированное, продажа", slippage: trader.FixedSlippage{Amount: dec("1.5")}, side: trader.SideSell, quantity: "1", want: "98.5"},
+		{name: "фиксированное больше цены", slippage: trader.FixedSlippage{Amount: dec("150")}, side: trader.SideSell, quantity: "1", want: "0"},
+		{name: "процент, покупка", slippage: trader.PercentSlippage{Percent: dec("0.5")}, side: trader.SideBuy, quantity: "1", want: "100.5"},
+		{name: "процент, продажа", slippage: trader.PercentSlippage{Percent: dec("0.5")}, side: trader.SideSell, quantity: "1", want: "99.5"},
+		{name: "стакан, первый уровень", slippage: depth, side: trader.SideBuy, quantity: "0.5", want: "100"},
+		{name: "стакан, несколько уровней", slippage: depth, side: trader.SideBuy, quantity: "3", want: "102"},
+		{name: "стакан, продажа", slippage: depth, side: trader.SideSell, quantity: "1", want: "98.5"},
+		{name: "стакан, не хватает ликвидности", slippage: depth, side: trader.SideSell, quantity: "2", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.slippage.Apply(test.side, token, dec(test.quantity), dec("100"))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ожидалась ошибка, получена цена %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if !got.Equal(dec(test.want)) {
+				t.Errorf("цена %s, ожидалась %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSlippage(t *testing.T) {
+	books := book(nil, nil)
+
+	tests := []struct {
+		spec    string
+		books   trader.BookSource
+		want    trader.Slippage
+		wantErr bool
+	}{
+		{spec: "", want: trader.NoSlippage{}},
+		{spec: "none", want: trader.NoSlippage{}},
+		{spec: " 0.05% ", want: trader.PercentSlippage{Percent: dec("0.05")}},
+		{spec: "1.5", want: trader.FixedSlippage{Amount: dec("1.5")}},
+		{spec: "depth", books: books},
+		{spec: "depth", wantErr: true},
+		{spec: "-1", wantErr: true},
+		{spec: "-1%", wantErr: true},
+		{spec: "много", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := trader.ParseSlippage(test.spec, test.books)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: ожидалась ошибка, получено %#v", test.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+
+		switch want := test.want.(type) {
+		case nil:
+			if _, ok := got.(trader.DepthSlippage); !ok {
+				t.Errorf("%q: получено %#v, ожидалось проскальзывание по стакану", test.spec, got)
+			}
+		case trader.PercentSlippage:
+			if got, ok := got.(trader.PercentSlippage); !ok || !got.Percent.Equal(want.Percent) {
+				t.Errorf("%q: получено %#v, ожидалось %#v", test.spec, got, want)
+			}
+		case trader.FixedSlippage:
+			if got, ok := got.(trader.FixedSlippage); !ok || !got.Amount.Equal(want.Amount) {
+				t.Errorf("%q: получено %#v, ожидалось %#v", test.spec, got, want)
+			}
+		default:
+			if got != test.want {
+				t.Errorf("%q: получено %#v, ожидалось %#v", test.spec, got, want)
+			}
+		}
+	}
+}
+
+func TestQuoteCosts(t *testing.T) {
+	tests := []struct {
+		name         string
+		intent       trader.OrderIntent
+		slippage     trader.Slippage
+		wantPrice    string
+		wantQuantity string
+		wantFee      string
+		wantCash     string
+	}{
+		{
+			name:         "покупка количества",
+			intent:       trader.OrderIntent{Side: trader.SideBuy, Mode: trader.ByQuantity, Value: dec("2")},
+			slippage:     trader.NoSlippage{},
+			wantPrice:    "100",
+			wantQuantity: "2",
+			wantFee:      "0.2",
+			wantCash:     "200.2",
+		},
+		{
+			name:         "покупка на сумму включает комиссию",
+			intent:       trader.OrderIntent{Side: trader.SideBuy, Mode: trader.ByNotional, Value: dec("100.1")},
+			slippage:     trader.NoSlippage{},
+			wantPrice:    "100",
+			wantQuantity: "1",
+			wantFee:      "0.1",
+			wantCash:     "100.1",
+		},
+		{
+			name:         "покупка с проскальзыванием",
+			intent:       trader.OrderIntent{Side: trader.SideBuy, Mode: trader.ByQuantity, Value: dec("1")},
+			slippage:     trader.PercentSlippage{Percent: dec("1")},
+			wantPrice:    "101",
+			wantQuantity: "1",
+			wantFee:      "0.101",
+			wantCash:     "101.101",
+		},
+		{
+			name:         "продажа на сумму вычитает комиссию",
+			intent:       trader.OrderIntent{Side: trader.SideSell, Mode: trader.ByNotional, Value: dec("50")},
+			slippage:     trader.NoSlippage{},
+			wantPrice:    "100",
+			wantQuantity: "0.5",
+			wantFee:      "0.05",
+			wantCash:     "49.95",
+		},
+		{
+			name:         "продажа с проскальзыванием",
+			intent:       trader.OrderIntent{Side: trader.SideSell, Mode: trader.ByQuantity, Value: dec("1")},
+			slippage:     trader.FixedSlippage{Amount: dec("2")},
+			wantPrice:    "98",
+			wantQuantity: "1",
+			wantFee:      "0.098",
+			wantCash:     "97.902",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			costs := trader.CostModel{TakerFee: dec("0.001"), Slippage: test.slippage}
+			portfolios := trader.NewPortfolios(storage.NewMemoryStore(), dec("1000"), trader.AverageCost, costs)
+			portfolio, err := portfolios.Open(1)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if test.intent.Side == trader.SideSell {
+				// Позиция для продажи покупается без издержек
+				portfolio.Costs = trader.CostModel{Slippage: trader.NoSlippage{}}
+				buy(t, portfolio, "1", "100")
+				portfolio.Costs = costs
+			}
+
+			test.intent.Token = token
+			quote, err := portfolio.Quote(test.intent, dec("100"))
+			if err != nil {
+				t.Fatalf("Quote: %v", err)
+			}
+			for _, check := range []struct {
+				name      string
+				got, want decimal.Decimal
+			}{
+				{"цена", quote.Price, dec(test.wantPrice)},
+				{"количество", quote.Quantity, dec(test.wantQuantity)},
+				{"комиссия", quote.Fee, dec(test.wantFee)},
+				{"сумма", quote.Cash, dec(test.wantCash)},
+			} {
+				if !check.got.Equal(check.want) {
+					t.Errorf("%s %s, ожидалось %s", check.name, check.got, check.want)
+				}
+			}
+
+			capital := portfolio.GetCapital()
+			trade, err := portfolio.Execute(quote)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if !trade.Fee.Equal(quote.Fee) || !trade.QuoteAmount.Equal(quote.Cash) {
+				t.Errorf("сделка с комиссией %s и суммой %s, ожидалось %s и %s", trade.Fee, trade.QuoteAmount, quote.Fee, quote.Cash)
+			}
+			change := quote.Cash
+			if test.intent.Side == trader.SideBuy {
+				change = change.Neg()
+			}
+			if want := capital.Add(change); !portfolio.GetCapital().Equal(want) {
+				t.Errorf("капитал %s, ожидался %s", portfolio.GetCapital(), want)
+			}
+		})
+	}
+}
+
+func TestQuoteRejects(t *testing.T) {
+	portfolios, _ := newPortfolios()
+	portfolio, err := portfolios.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		intent trader.OrderIntent
+		price  string
+	}{
+		{name: "нулевая цена", intent: trader.OrderIntent{Side: trader.SideBuy, Token: token, Value: dec("1")}, price: "0"},
+		{name: "нулевой размер", intent: trader.OrderIntent{Side: trader.SideBuy, Token: token, Value: dec("0")}, price: "100"},
+		{name: "неизвестная сторона", intent: trader.OrderIntent{Side: "hold", Token: token, Value: dec("1")}, price: "100"},
+		{name: "не хватает USDT", intent: trader.OrderIntent{Side: trader.SideBuy, Token: token, Value: dec("101")}, price: "100"},
+		{name: "не хватает токенов", intent: trader.OrderIntent{Side: trader.SideSell, Token: token, Value: dec("1")}, price: "100"},
+	}
+
+	for _, test := range tests {
+		if _, err := portfolio.Quote(test.intent, dec(test.price)); err == nil {
+			t.Errorf("%s: ожидалась ошибка", test.name)
+		}
+	}
+}
+
+// buy покупает quantity токенов по цене price
+func buy(t *testing.T, portfolio *trader.Trader, quantity, price string) trader.Trade {
+	t.Helper()
+	quote, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: token, Mode: trader.ByQuantity, Value: dec(quantity)}, dec(price))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	trade, err := portfolio.Execute(quote)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	return trade
+}
@@ -0,0 +1,289 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Типы отложенных заявок
+const (
+	OrderLimit = "limit"
+)
+
+// Order описывает отложенную заявку, ожидающую исполнения
+type Order struct {
+	ID           int64
+	Type         string
+	Side         string
+	Token        string
+	Quantity     decimal.Decimal
+	Price        decimal.Decimal // Лимитная цена
+	TriggerPrice decimal.Decimal // Цена срабатывания условной заявки
+	TrailPercent decimal.Decimal
+	TrailAmount  decimal.Decimal
+	Extreme      decimal.Decimal // Максимум (для продажи) или минимум (для покупки) цены с момента создания трейлинг-стопа
+	OCOGroup     int64           // Заявки с одинаковой группой отменяют друг друга при срабатывании
+	Tag          string          // Стратегия, создавшая заявку; пусто для заявок пользователя
+	Reserved     decimal.Decimal // Зарезервированные USDT для покупки или токены для продажи
+	CreatedAt    time.Time
+}
+
+// PlaceLimit создает лимитную заявку и резервирует под нее USDT или токены. tag отмечает заявки стратегий
+func (t *Trader) PlaceLimit(side, token string, quantity, price decimal.Decimal, tag string) (_ Order, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.rollback(t.snapshot(), &err)
+
+	if !quantity.IsPositive() {
+		return Order{}, fmt.Errorf("количество должно быть больше нуля")
+	}
+	if !price.IsPositive() {
+		return Order{}, fmt.Errorf("цена должна быть больше нуля")
+	}
+	if err := t.checkQuantity(token, quantity); err != nil {
+		return Order{}, err
+	}
+	if err := t.checkPrice(token, price); err != nil {
+		return Order{}, err
+	}
+
+	order := &Order{
+		Type:      OrderLimit,
+		Side:      side,
+		Token:     token,
+		Quantity:  quantity,
+		Price:     price,
+		Tag:       tag,
+		CreatedAt: t.now(),
+	}
+
+	switch side {
+	case SideBuy:
+		// Резервируем сумму покупки вместе с комиссией мейкера; при исполнении спишется ровно резерв
+		notional := quantity.Mul(price)
+		order.Reserved = roundCash(notional.Add(roundCash(notional.Mul(t.costs().MakerFee))))
+		if order.Reserved.GreaterThan(t.Capital) {
+			return Order{}, fmt.Errorf("недостаточно средств: нужно $%s, доступно $%s", order.Reserved.StringFixed(2), t.Capital.StringFixed(2))
+		}
+		t.Capital = t.Capital.Sub(order.Reserved)
+	case SideSell:
+		if available := t.holding(token); quantity.GreaterThan(available) {
+			return Order{}, fmt.Errorf("недостаточно токенов %s: нужно %s, доступно %s", token, quantity, available)
+		}
+		order.Reserved = quantity
+	default:
+		return Order{}, fmt.Errorf("неизвестная сторона заявки: %s", side)
+	}
+
+	t.addOrder(order)
+	return *order, t.save()
+}
+
+// CancelOrder отменяет заявку и освобождает зарезервированные средства
+func (t *Trader) CancelOrder(id int64) (_ Order, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.rollback(t.snapshot(), &err)
+
+	order, ok := t.removeOrder(id)
+	if !ok {
+		return Order{}, fmt.Errorf("заявка #%d не найдена", id)
+	}
+
+	if order.Side == SideBuy {
+		t.Capital = t.Capital.Add(order.Reserved)
+	}
+	return *order, t.save()
+}
+
+// OpenOrders возвращает копии открытых заявок в порядке создания
+func (t *Trader) OpenOrders() []Order {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.openOrders()
+}
+
+// openOrders возвращает копии открытых заявок, отсортированные по номеру
+func (t *Trader) openOrders() []Order {
+	orders := make([]Order, 0, len(t.Orders))
+	for _, order := range t.Orders {
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders
+}
+
+// OrderTokens возвращает токены, по которым есть открытые заявки
+func (t *Trader) OrderTokens() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, order := range t.Orders {
+		if !seen[order.Token] {
+			seen[order.Token] = true
+			tokens = append(tokens, order.Token)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// MatchOrders исполняет заявки по токену, условия которых выполнены при рыночной цене price
+func (t *Trader) MatchOrders(token string, price decimal.Decimal) ([]Fill, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var fills []Fill
+	// trailed отмечает сдвиги трейлинг-стопов, которые еще не попали в хранилище вместе с исполнением
+	trailed := false
+
+	for _, listed := range t.openOrders() {
+		if listed.Token != token {
+			continue
+		}
+
+		// Заявка могла быть отменена вместе со сработавшей связанной заявкой,
+		// а после отката несохраненного исполнения список заявок заменяется копией
+		order, open := t.order(listed.ID)
+		if !open {
+			continue
+		}
+
+		if order.Type == OrderLimit {
+			if !crossed(*order, price) {
+				continue
+			}
+
+			trade, err := t.fillOrder(order.ID)
+			if err != nil {
+				return fills, err
+			}
+			// Сделка сохраняется вместе со всем портфелем, включая уже сдвинутые уровни
+			fills = append(fills, Fill{Order: *order, Trade: trade})
+			trailed = false
+			continue
+		}
+
+		if trail(order, price) {
+			trailed = true
+		}
+		if triggered(order, price) {
+			fill := t.triggerOrder(order.ID, price)
+			fills = append(fills, fill)
+			// Неудачное исполнение могло не сохранить портфель, поэтому сдвиги сохраняются отдельно
+			if fill.Err == nil {
+				trailed = false
+			}
+		}
+	}
+
+	// Сохраняем сдвинутые уровни трейлинг-стопов, если их не сохранило исполнение после сдвига
+	if trailed {
+		return fills, t.save()
+	}
+	return fills, nil
+}
+
+// Fill описывает исполнение отложенной заявки
+type Fill struct {
+	Order    Order
+	Trade    Trade
+	Canceled []Order // Связанные заявки, отмененные при срабатывании
+	Err      error   // Ошибка исполнения сработавшей условной заявки
+}
+
+// crossed проверяет, достигла ли рыночная цена лимитной цены заявки
+func crossed(order Order, price decimal.Decimal) bool {
+	if order.Side == SideBuy {
+		return price.LessThanOrEqual(order.Price)
+	}
+	return price.GreaterThanOrEqual(order.Price)
+}
+
+// fillOrder исполняет лимитную заявку по ее цене с комиссией мейкера
+func (t *Trader) fillOrder(id int64) (_ Trade, err error) {
+	defer t.rollback(t.snapshot(), &err)
+
+	order, ok := t.removeOrder(id)
+	if !ok {
+		return Trade{}, fmt.Errorf("заявка #%d не найдена", id)
+	}
+
+	notional := order.Quantity.Mul(order.Price)
+	fee := roundCash(notional.Mul(t.costs().MakerFee))
+	quote := Quote{
+		Intent:    OrderIntent{Side: order.Side, Token: order.Token, Mode: ByQuantity, Value: order.Quantity},
+		MarkPrice: order.Price,
+		Price:     order.Price,
+		Quantity:  order.Quantity,
+		Fee:       fee,
+	}
+
+	if order.Side == SideBuy {
+		// Возвращаем резерв и списываем фактическую стоимость покупки
+		t.Capital = t.Capital.Add(order.Reserved)
+		quote.Cash = roundCash(notional.Add(fee))
+		return t.buy(quote, order.ID)
+	}
+
+	quote.Cash = roundCash(notional.Sub(fee))
+	return t.sell(quote, order.ID)
+}
+
+// order возвращает открытую заявку по номеру
+func (t *Trader) order(id int64) (*Order, bool) {
+	for _, order := range t.Orders {
+		if order.ID == id {
+			return order, true
+		}
+	}
+	return nil, false
+}
+
+// removeOrder удаляет заявку из списка открытых
+func (t *Trader) removeOrder(id int64) (*Order, bool) {
+	for i, order := range t.Orders {
+		if order.ID == id {
+			t.Orders = append(t.Orders[:i], t.Orders[i+1:]...)
+			return order, true
+		}
+	}
+	return nil, false
+}
+
+// reservedCash возвращает USDT, зарезервированные под заявки на покупку
+func (t *Trader) reservedCash() decimal.Decimal {
+	reserved := decimal.Zero
+	for _, order := range t.Orders {
+		if order.Side == SideBuy {
+			reserved = reserved.Add(order.Reserved)
+		}
+	}
+	return reserved
+}
+
+// reservedTokens возвращает количество токенов, зарезервированных под заявки на продажу.
+// Связанные OCO заявки резервируют одни и те же токены, поэтому учитываются один раз
+func (t *Trader) reservedTokens(token string) decimal.Decimal {
+	reserved := decimal.Zero
+	groups := make(map[int64]bool)
+	for _, order := range t.Orders {
+		if order.Side != SideSell || order.Token != token {
+			continue
+		}
+		if order.OCOGroup != 0 {
+			if groups[order.OCOGroup] {
+				continue
+			}
+			groups[order.OCOGroup] = true
+		}
+		reserved = reserved.Add(order.Reserved)
+	}
+	return reserved
+}
@@ -0,0 +1,254 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+)
+
+// trailing выставляет трейлинг-стоп на продажу одного токена с отступом 10% от цены 100
+func trailing(t *testing.T, portfolio *trader.Trader) {
+	t.Helper()
+	if _, err := portfolio.PlaceTrigger(trader.TriggerRequest{
+		Type: trader.OrderTrailingStop, Side: trader.SideSell, Token: token, Quantity: dec("1"), TrailPercent: dec("10"), MarkPrice: dec("100"),
+	}); err != nil {
+		t.Fatalf("PlaceTrigger: %v", err)
+	}
+}
+
+// trigger выставляет условную заявку на один токен при цене 100
+func trigger(t *testing.T, portfolio *trader.Trader, kind, side, price string) {
+	t.Helper()
+	if _, err := portfolio.PlaceTrigger(trader.TriggerRequest{
+		Type: kind, Side: side, Token: token, Quantity: dec("1"), TriggerPrice: dec(price), MarkPrice: dec("100"),
+	}); err != nil {
+		t.Fatalf("PlaceTrigger: %v", err)
+	}
+}
+
+// limit выставляет лимитную заявку на один токен
+func limit(t *testing.T, portfolio *trader.Trader, side, price string) {
+	t.Helper()
+	if _, err := portfolio.PlaceLimit(side, token, dec("1"), dec(price), ""); err != nil {
+		t.Fatalf("PlaceLimit: %v", err)
+	}
+}
+
+func TestMatchOrders(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup        func(t *testing.T, portfolio *trader.Trader)
+		prices       []string
+		wantFilled   map[int64]string // Номер исполненной заявки -> цена сделки
+		wantCanceled []int64
+		wantFailed   []int64
+		wantOpen     []int64
+	}{
+		{
+			name:       "покупка исполняется по лимитной цене при снижении",
+			setup:      func(t *testing.T, p *trader.Trader) { limit(t, p, trader.SideBuy, "90") },
+			prices:     []string{"95", "89"},
+			wantFilled: map[int64]string{1: "90"},
+		},
+		{
+			name: "продажа исполняется по лимитной цене при росте",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				limit(t, p, trader.SideSell, "110")
+			},
+			prices:     []string{"105", "111"},
+			wantFilled: map[int64]string{1: "110"},
+		},
+		{
+			name: "лимитная заявка ждет цену",
+			setup: func(t *testing.T, p *trader.Trader) {
+				limit(t, p, trader.SideBuy, "90")
+			},
+			prices:   []string{"95", "90.01"},
+			wantOpen: []int64{1},
+		},
+		{
+			name: "стоп-лосс исполняется по рынку при падении",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				trigger(t, p, trader.OrderStop, trader.SideSell, "90")
+			},
+			prices:     []string{"95", "89"},
+			wantFilled: map[int64]string{1: "89"},
+		},
+		{
+			name: "тейк-профит исполняется по рынку при росте",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				trigger(t, p, trader.OrderTakeProfit, trader.SideSell, "110")
+			},
+			prices:     []string{"105", "112"},
+			wantFilled: map[int64]string{1: "112"},
+		},
+		{
+			name: "трейлинг-стоп следует за максимумом цены",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				trailing(t, p)
+			},
+			// Уровень поднимается с 90 до 108 и не опускается при откате до 109
+			prices:     []string{"120", "109", "107"},
+			wantFilled: map[int64]string{1: "107"},
+		},
+		{
+			name: "OCO: тейк-профит отменяет стоп-лосс",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				if _, err := p.PlaceOCO(token, dec("1"), dec("90"), dec("110"), dec("100")); err != nil {
+					t.Fatalf("PlaceOCO: %v", err)
+				}
+			},
+			prices:       []string{"111"},
+			wantFilled:   map[int64]string{2: "111"},
+			wantCanceled: []int64{1},
+		},
+		{
+			name: "неисполнимая условная заявка снимается с ошибкой",
+			setup: func(t *testing.T, p *trader.Trader) {
+				if _, err := p.PlaceTrigger(trader.TriggerRequest{
+					Type: trader.OrderStop, Side: trader.SideBuy, Token: token, Quantity: dec("100"), TriggerPrice: dec("110"), MarkPrice: dec("100"),
+				}); err != nil {
+					t.Fatalf("PlaceTrigger: %v", err)
+				}
+			},
+			prices:     []string{"120"},
+			wantFailed: []int64{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			portfolios, _ := newPortfolios()
+			portfolio, err := portfolios.Open(1)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			test.setup(t, portfolio)
+
+			filled := make(map[int64]string)
+			var canceled, failed []int64
+			for _, price := range test.prices {
+				fills, err := portfolio.MatchOrders(token, dec(price))
+				if err != nil {
+					t.Fatalf("цена %s: MatchOrders: %v", price, err)
+				}
+				for _, fill := range fills {
+					if fill.Err != nil {
+						failed = append(failed, fill.Order.ID)
+						continue
+					}
+					filled[fill.Order.ID] = fill.Trade.Price.String()
+					for _, order := range fill.Canceled {
+						canceled = append(canceled, order.ID)
+					}
+				}
+			}
+
+			if len(filled) != len(test.wantFilled) {
+				t.Errorf("исполнены %v, ожидалось %v", filled, test.wantFilled)
+			}
+			for id, price := range test.wantFilled {
+				if got, ok := filled[id]; !ok || !dec(got).Equal(dec(price)) {
+					t.Errorf("исполнены %v, ожидалось %v", filled, test.wantFilled)
+				}
+			}
+			if !equalIDs(canceled, test.wantCanceled) {
+				t.Errorf("отменены %v, ожидалось %v", canceled, test.wantCanceled)
+			}
+			if !equalIDs(failed, test.wantFailed) {
+				t.Errorf("с ошибкой %v, ожидалось %v", failed, test.wantFailed)
+			}
+			var open []int64
+			for _, order := range portfolio.OpenOrders() {
+				open = append(open, order.ID)
+			}
+			if !equalIDs(open, test.wantOpen) {
+				t.Errorf("открыты %v, ожидалось %v", open, test.wantOpen)
+			}
+		})
+	}
+}
+
+// Сдвиг трейлинг-стопа должен попасть в хранилище, даже если в том же проходе были другие исполнения
+func TestMatchOrdersSavesTrailing(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, p *trader.Trader)
+	}{
+		{
+			name:  "без исполнений",
+			setup: trailing,
+		},
+		{
+			name: "после исполнения лимитной заявки",
+			setup: func(t *testing.T, p *trader.Trader) {
+				buy(t, p, "1", "100")
+				limit(t, p, trader.SideSell, "115")
+				trailing(t, p)
+			},
+		},
+		{
+			name: "вместе с неисполнимой условной заявкой",
+			setup: func(t *testing.T, p *trader.Trader) {
+				trailing(t, p)
+				if _, err := p.PlaceTrigger(trader.TriggerRequest{
+					Type: trader.OrderStop, Side: trader.SideBuy, Token: token, Quantity: dec("100"), TriggerPrice: dec("110"), MarkPrice: dec("100"),
+				}); err != nil {
+					t.Fatalf("PlaceTrigger: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			portfolios, store := newPortfolios()
+			portfolio, err := portfolios.Open(1)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			buy(t, portfolio, "1", "100")
+			test.setup(t, portfolio)
+
+			if _, err := portfolio.MatchOrders(token, dec("120")); err != nil {
+				t.Fatalf("MatchOrders: %v", err)
+			}
+
+			saved, err := store.LoadPortfolio(1)
+			if err != nil {
+				t.Fatalf("LoadPortfolio: %v", err)
+			}
+			found := false
+			for _, order := range saved.Orders {
+				if order.Type != trader.OrderTrailingStop {
+					continue
+				}
+				found = true
+				if !order.TriggerPrice.Equal(dec("108")) || !order.Extreme.Equal(dec("120")) {
+					t.Errorf("сохранен уровень %s от максимума %s, ожидалось 108 от 120", order.TriggerPrice, order.Extreme)
+				}
+			}
+			if !found {
+				t.Fatalf("трейлинг-стоп не сохранен")
+			}
+		})
+	}
+}
+
+// equalIDs сравнивает номера заявок с учетом порядка
+func equalIDs(got, want []int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
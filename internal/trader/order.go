@@ -0,0 +1,158 @@
+package trader
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderMode определяет, в чем пользователь указал размер заявки
+type OrderMode int
+
+const (
+	// ByQuantity означает размер заявки в токенах ("купить Q единиц")
+	ByQuantity OrderMode = iota
+	// ByNotional означает размер заявки в USDT ("потратить $N")
+	ByNotional
+)
+
+// OrderIntent описывает намерение пользователя купить или продать токен
+type OrderIntent struct {
+	Side  string
+	Token string
+	Mode  OrderMode
+	Value decimal.Decimal
+}
+
+// Quote описывает результат заявки, рассчитанный до ее исполнения
+type Quote struct {
+	Intent    OrderIntent
+	MarkPrice decimal.Decimal // Рыночная цена на момент расчета
+	Price     decimal.Decimal // Цена исполнения с учетом проскальзывания
+	Quantity  decimal.Decimal // Количество токенов
+	Fee       decimal.Decimal // Комиссия в USDT
+	Cash      decimal.Decimal // USDT, которые спишутся при покупке или поступят при продаже, с учетом комиссии
+}
+
+// Quote рассчитывает количество токенов, издержки и движение денег по рыночной заявке при указанной цене
+func (t *Trader) Quote(intent OrderIntent, price decimal.Decimal) (Quote, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.makeQuote(intent, price)
+}
+
+// makeQuote рассчитывает котировку рыночной заявки
+func (t *Trader) makeQuote(intent OrderIntent, price decimal.Decimal) (Quote, error) {
+	if !price.IsPositive() {
+		return Quote{}, fmt.Errorf("некорректная цена: %s", price)
+	}
+	if !intent.Value.IsPositive() {
+		return Quote{}, fmt.Errorf("размер заявки должен быть больше нуля")
+	}
+	if intent.Side != SideBuy && intent.Side != SideSell {
+		return Quote{}, fmt.Errorf("неизвестная сторона заявки: %s", intent.Side)
+	}
+
+	costs := t.costs()
+	quantity := intent.Value
+	if intent.Mode == ByNotional {
+		quantity = intent.Value.Div(price)
+	}
+
+	fillPrice, err := costs.Slippage.Apply(intent.Side, intent.Token, quantity, price)
+	if err != nil {
+		return Quote{}, fmt.Errorf("ошибка расчета проскальзывания: %w", err)
+	}
+	if !fillPrice.IsPositive() {
+		return Quote{}, fmt.Errorf("некорректная цена исполнения: %s", fillPrice)
+	}
+
+	// При покупке на сумму комиссия входит в указанный бюджет
+	switch {
+	case intent.Mode == ByNotional && intent.Side == SideBuy:
+		quantity = intent.Value.Div(fillPrice.Mul(decimal.NewFromInt(1).Add(costs.TakerFee)))
+	case intent.Mode == ByNotional:
+		quantity = intent.Value.Div(fillPrice)
+	}
+
+	// Заявка на сумму исполняется количеством, округленным вниз до шага лота, как на бирже;
+	// количество, указанное явно, должно соответствовать правилам инструмента
+	if intent.Mode == ByNotional {
+		quantity = t.RoundQuantity(intent.Token, quantity)
+	}
+	if err := t.checkQuantity(intent.Token, quantity); err != nil {
+		return Quote{}, err
+	}
+	if !quantity.IsPositive() {
+		return Quote{}, fmt.Errorf("сумма слишком мала для покупки %s", intent.Token)
+	}
+
+	notional := quantity.Mul(fillPrice)
+	fee := roundCash(notional.Mul(costs.TakerFee))
+
+	quote := Quote{
+		Intent:    intent,
+		MarkPrice: price,
+		Price:     fillPrice,
+		Quantity:  quantity,
+		Fee:       fee,
+	}
+
+	if intent.Side == SideBuy {
+		quote.Cash = roundCash(notional.Add(fee))
+		if quote.Cash.GreaterThan(t.Capital) {
+			return Quote{}, fmt.Errorf("недостаточно средств: нужно $%s, доступно $%s", quote.Cash.StringFixed(2), t.Capital.StringFixed(2))
+		}
+		return quote, nil
+	}
+
+	if holding := t.holding(intent.Token); quantity.GreaterThan(holding) {
+		return Quote{}, fmt.Errorf("недостаточно токенов %s: нужно %s, доступно %s", intent.Token, quantity, holding)
+	}
+	quote.Cash = roundCash(notional.Sub(fee))
+	return quote, nil
+}
+
+// Execute исполняет заявку по цене котировки и возвращает запись журнала
+func (t *Trader) Execute(quote Quote) (_ Trade, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.rollback(t.snapshot(), &err)
+
+	// Пересчитываем котировку: портфель мог измениться после ее получения
+	checked, err := t.makeQuote(quote.Intent, quote.MarkPrice)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if checked.Intent.Side == SideBuy {
+		return t.buy(checked, 0)
+	}
+	return t.sell(checked, 0)
+}
+
+// costs возвращает модель издержек портфеля или модель по умолчанию
+func (t *Trader) costs() CostModel {
+	if t.Costs.Slippage == nil {
+		return DefaultCostModel()
+	}
+	return t.Costs
+}
+
+// Holding возвращает количество токенов, доступных для продажи, за вычетом зарезервированных заявками
+func (t *Trader) Holding(token string) decimal.Decimal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.holding(token)
+}
+
+// holding возвращает количество токенов, не зарезервированных заявками
+func (t *Trader) holding(token string) decimal.Decimal {
+	position, ok := t.Positions[token]
+	if !ok {
+		return decimal.Zero
+	}
+	return position.Quantity().Sub(t.reservedTokens(token))
+}
@@ -0,0 +1,69 @@
+package trader
+
+import "github.com/shopspring/decimal"
+
+// PositionPnL содержит оценку позиции по рыночной цене
+type PositionPnL struct {
+	Token                string
+	Quantity             decimal.Decimal
+	AveragePrice         decimal.Decimal
+	MarkPrice            decimal.Decimal
+	MarketValue          decimal.Decimal
+	UnrealizedPnL        decimal.Decimal
+	UnrealizedPnLPercent decimal.Decimal
+	Priced               bool // false, если рыночная цена неизвестна и позиция оценена по себестоимости
+}
+
+// Report содержит итоги портфеля: позиции, реализованный и нереализованный PnL и капитал
+type Report struct {
+	Cash            decimal.Decimal // Свободные USDT
+	ReservedCash    decimal.Decimal // USDT, зарезервированные под заявки на покупку
+	Positions       []PositionPnL
+	RealizedPnL     decimal.Decimal
+	UnrealizedPnL   decimal.Decimal
+	Equity          decimal.Decimal
+	StartingCapital decimal.Decimal
+	ReturnPercent   decimal.Decimal // Изменение капитала относительно начального, %
+}
+
+// Report оценивает портфель по рыночным ценам marks, заданным по токенам
+func (t *Trader) Report(marks map[string]decimal.Decimal) Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{
+		Cash:            t.Capital,
+		ReservedCash:    t.reservedCash(),
+		RealizedPnL:     t.RealizedPnL,
+		Equity:          t.Capital.Add(t.reservedCash()),
+		StartingCapital: t.StartingCapital,
+	}
+
+	for _, position := range sortedPositions(t.Positions) {
+		row := PositionPnL{
+			Token:        position.Token,
+			Quantity:     position.Quantity(),
+			AveragePrice: position.AveragePrice(),
+			MarketValue:  position.Cost(),
+		}
+
+		if mark, ok := marks[position.Token]; ok && mark.IsPositive() {
+			row.Priced = true
+			row.MarkPrice = mark
+			row.MarketValue = row.Quantity.Mul(mark)
+			row.UnrealizedPnL = row.MarketValue.Sub(position.Cost())
+			if cost := position.Cost(); cost.IsPositive() {
+				row.UnrealizedPnLPercent = row.UnrealizedPnL.Div(cost).Mul(hundred)
+			}
+		}
+
+		report.Positions = append(report.Positions, row)
+		report.UnrealizedPnL = report.UnrealizedPnL.Add(row.UnrealizedPnL)
+		report.Equity = report.Equity.Add(row.MarketValue)
+	}
+
+	if report.StartingCapital.IsPositive() {
+		report.ReturnPercent = report.Equity.Sub(report.StartingCapital).Div(report.StartingCapital).Mul(hundred)
+	}
+	return report
+}
@@ -0,0 +1,97 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+func TestReport(t *testing.T) {
+	portfolios, _ := newPortfolios()
+	portfolio, err := portfolios.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// Покупка 2 токенов по 100, продажа одного по 150 и лимитная покупка по 50 с резервом
+	buy(t, portfolio, "2", "100")
+	sell(t, portfolio, "1", "150")
+	if _, err := portfolio.PlaceLimit(trader.SideBuy, token, dec("1"), dec("50"), ""); err != nil {
+		t.Fatalf("PlaceLimit: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		marks           map[string]decimal.Decimal
+		wantPriced      bool
+		wantMarketValue string
+		wantUnrealized  string
+		wantPercent     string
+		wantEquity      string
+		wantReturn      string
+	}{
+		{
+			name:            "по рыночной цене",
+			marks:           map[string]decimal.Decimal{token: dec("120")},
+			wantPriced:      true,
+			wantMarketValue: "120",
+			wantUnrealized:  "20",
+			wantPercent:     "20",
+			wantEquity:      "10070",
+			wantReturn:      "0.7",
+		},
+		{
+			name:            "ниже себестоимости",
+			marks:           map[string]decimal.Decimal{token: dec("80")},
+			wantPriced:      true,
+			wantMarketValue: "80",
+			wantUnrealized:  "-20",
+			wantPercent:     "-20",
+			wantEquity:      "10030",
+			wantReturn:      "0.3",
+		},
+		{
+			name:            "без цены позиция оценивается по себестоимости",
+			marks:           map[string]decimal.Decimal{},
+			wantMarketValue: "100",
+			wantUnrealized:  "0",
+			wantPercent:     "0",
+			wantEquity:      "10050",
+			wantReturn:      "0.5",
+		},
+	}
+
+	for _, test := range tests {
+		report := portfolio.Report(test.marks)
+		if !report.Cash.Equal(dec("9900")) || !report.ReservedCash.Equal(dec("50")) {
+			t.Errorf("%s: свободно %s, в резерве %s, ожидалось 9900 и 50", test.name, report.Cash, report.ReservedCash)
+		}
+		if !report.RealizedPnL.Equal(dec("50")) {
+			t.Errorf("%s: реализованный PnL %s, ожидался 50", test.name, report.RealizedPnL)
+		}
+		if len(report.Positions) != 1 {
+			t.Fatalf("%s: позиций %d, ожидалась одна", test.name, len(report.Positions))
+		}
+
+		position := report.Positions[0]
+		if position.Priced != test.wantPriced {
+			t.Errorf("%s: Priced %v, ожидалось %v", test.name, position.Priced, test.wantPriced)
+		}
+		for _, check := range []struct {
+			name      string
+			got, want decimal.Decimal
+		}{
+			{"стоимость позиции", position.MarketValue, dec(test.wantMarketValue)},
+			{"нереализованный PnL позиции", position.UnrealizedPnL, dec(test.wantUnrealized)},
+			{"нереализованный PnL, %", position.UnrealizedPnLPercent, dec(test.wantPercent)},
+			{"нереализованный PnL портфеля", report.UnrealizedPnL, dec(test.wantUnrealized)},
+			{"капитал", report.Equity, dec(test.wantEquity)},
+			{"доходность, %", report.ReturnPercent, dec(test.wantReturn)},
+		} {
+			if !check.got.Equal(check.want) {
+				t.Errorf("%s: %s %s, ожидалось %s", test.name, check.name, check.got, check.want)
+			}
+		}
+	}
+}
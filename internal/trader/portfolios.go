@@ -0,0 +1,134 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// Portfolios хранит изолированные портфели пользователей
+type Portfolios struct {
+	StartingCapital decimal.Decimal
+	CostBasis       CostBasis
+	Costs           CostModel
+	Clock           func() time.Time                          // Источник времени сделок и заявок; бэктест подставляет модельное время
+	Instruments     func(token string) (okx.Instrument, bool) // Торговые правила инструментов; без справочника не проверяются
+	store           Store
+	mu              sync.Mutex // Защищает traders: портфели открывают бот и фоновые задачи
+	traders         map[int64]*Trader
+}
+
+// NewPortfolios создает хранилище портфелей с заданным начальным капиталом
+func NewPortfolios(store Store, startingCapital decimal.Decimal, costBasis CostBasis, costs CostModel) *Portfolios {
+	return &Portfolios{
+		StartingCapital: startingCapital,
+		CostBasis:       costBasis,
+		Costs:           costs,
+		Clock:           time.Now,
+		store:           store,
+		traders:         make(map[int64]*Trader),
+	}
+}
+
+// Open возвращает портфель пользователя, создавая его при первом обращении
+func (p *Portfolios) Open(userID int64) (*Trader, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, err := p.get(userID)
+	if err == nil {
+		return t, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	t = NewTrader(userID, p.StartingCapital)
+	t.CostBasis = p.CostBasis
+	t.Costs = p.Costs
+	t.clock = p.Clock
+	t.instruments = p.Instruments
+	t.store = p.store
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+
+	p.traders[userID] = t
+	return t, nil
+}
+
+// Get возвращает портфель пользователя, загружая его из хранилища при необходимости
+func (p *Portfolios) Get(userID int64) (*Trader, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.get(userID)
+}
+
+// get возвращает загруженный портфель или загружает его из хранилища
+func (p *Portfolios) get(userID int64) (*Trader, error) {
+	if t, ok := p.traders[userID]; ok {
+		return t, nil
+	}
+
+	t, err := p.store.LoadPortfolio(userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ошибка загрузки портфеля %d: %w", userID, err)
+	}
+
+	// Метод учета себестоимости и издержки задаются конфигурацией для всех портфелей
+	t.CostBasis = p.CostBasis
+	t.Costs = p.Costs
+	if t.Positions == nil {
+		t.Positions = make(map[string]*Position)
+	}
+	t.clock = p.Clock
+	t.instruments = p.Instruments
+	t.store = p.store
+	p.traders[userID] = t
+	return t, nil
+}
+
+// Now возвращает текущее время по часам портфелей
+func (p *Portfolios) Now() time.Time {
+	return p.Clock()
+}
+
+// History возвращает сделки пользователя, начиная с самых новых
+func (p *Portfolios) History(userID int64, offset, limit int) ([]Trade, error) {
+	return p.store.ListTrades(userID, offset, limit)
+}
+
+// LoadAll загружает в память все сохраненные портфели
+func (p *Portfolios) LoadAll() error {
+	userIDs, err := p.store.ListUsers()
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка портфелей: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if _, err := p.Get(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// All возвращает все загруженные портфели
+func (p *Portfolios) All() []*Trader {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	traders := make([]*Trader, 0, len(p.traders))
+	for _, t := range p.traders {
+		traders = append(traders, t)
+	}
+	return traders
+}
@@ -0,0 +1,146 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CostBasis определяет, из каких партий списываются токены при продаже
+type CostBasis string
+
+const (
+	// AverageCost списывает все партии пропорционально по средневзвешенной цене
+	AverageCost CostBasis = "average"
+	// FIFO списывает сначала самые ранние партии
+	FIFO CostBasis = "fifo"
+	// LIFO списывает сначала самые поздние партии
+	LIFO CostBasis = "lifo"
+)
+
+// ParseCostBasis проверяет название метода учета себестоимости
+func ParseCostBasis(name string) (CostBasis, error) {
+	switch basis := CostBasis(name); basis {
+	case AverageCost, FIFO, LIFO:
+		return basis, nil
+	default:
+		return "", fmt.Errorf("неизвестный метод учета себестоимости: %s", name)
+	}
+}
+
+// Lot хранит партию токенов, купленную одной сделкой
+type Lot struct {
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+	BoughtAt time.Time
+}
+
+// Position объединяет все партии одного токена
+type Position struct {
+	Token string
+	Lots  []Lot
+}
+
+// Quantity возвращает общее количество токенов в позиции
+func (p *Position) Quantity() decimal.Decimal {
+	quantity := decimal.Zero
+	for _, lot := range p.Lots {
+		quantity = quantity.Add(lot.Quantity)
+	}
+	return quantity
+}
+
+// Cost возвращает себестоимость позиции в USDT
+func (p *Position) Cost() decimal.Decimal {
+	cost := decimal.Zero
+	for _, lot := range p.Lots {
+		cost = cost.Add(lot.Quantity.Mul(lot.Price))
+	}
+	return cost
+}
+
+// AveragePrice возвращает средневзвешенную цену входа
+func (p *Position) AveragePrice() decimal.Decimal {
+	quantity := p.Quantity()
+	if quantity.IsZero() {
+		return decimal.Zero
+	}
+	return p.Cost().Div(quantity)
+}
+
+// add добавляет купленную партию
+func (p *Position) add(lot Lot) {
+	p.Lots = append(p.Lots, lot)
+}
+
+// remove списывает проданное количество согласно методу учета и возвращает себестоимость проданного
+func (p *Position) remove(quantity decimal.Decimal, basis CostBasis) decimal.Decimal {
+	if basis == AverageCost {
+		total := p.Quantity()
+		cost := p.Cost().Mul(quantity).Div(total)
+
+		// Партии уменьшаются пропорционально, а последняя получает точный остаток,
+		// чтобы сумма партий совпадала с количеством позиции после продажи
+		remaining := total.Sub(quantity)
+		left := remaining
+		for i := range p.Lots {
+			if i == len(p.Lots)-1 {
+				p.Lots[i].Quantity = left
+				break
+			}
+			p.Lots[i].Quantity = p.Lots[i].Quantity.Mul(remaining).Div(total)
+			left = left.Sub(p.Lots[i].Quantity)
+		}
+		p.compact()
+		return cost
+	}
+
+	// Партии хранятся в порядке покупки: для LIFO идем с конца
+	order := make([]int, len(p.Lots))
+	for i := range order {
+		order[i] = i
+		if basis == LIFO {
+			order[i] = len(p.Lots) - 1 - i
+		}
+	}
+
+	cost := decimal.Zero
+	remaining := quantity
+	for _, i := range order {
+		if !remaining.IsPositive() {
+			break
+		}
+		sold := decimal.Min(p.Lots[i].Quantity, remaining)
+		cost = cost.Add(sold.Mul(p.Lots[i].Price))
+		p.Lots[i].Quantity = p.Lots[i].Quantity.Sub(sold)
+		remaining = remaining.Sub(sold)
+	}
+	p.compact()
+	return cost
+}
+
+// compact удаляет полностью проданные партии
+func (p *Position) compact() {
+	lots := p.Lots[:0]
+	for _, lot := range p.Lots {
+		if lot.Quantity.IsPositive() {
+			lots = append(lots, lot)
+		}
+	}
+	p.Lots = lots
+}
+
+// sortedPositions возвращает позиции портфеля, упорядоченные по токену
+func sortedPositions(positions map[string]*Position) []Position {
+	result := make([]Position, 0, len(positions))
+	for _, position := range positions {
+		result = append(result, Position{
+			Token: position.Token,
+			Lots:  append([]Lot{}, position.Lots...),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Token < result[j].Token })
+	return result
+}
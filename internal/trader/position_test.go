@@ -0,0 +1,97 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+)
+
+func TestCostBasis(t *testing.T) {
+	// Партии 1 токен по 100 и 1 токен по 200, продажа по 300
+	tests := []struct {
+		basis        trader.CostBasis
+		sell         string
+		wantRealized string
+		wantAverage  string
+		wantLots     int
+	}{
+		{basis: trader.FIFO, sell: "1", wantRealized: "200", wantAverage: "200", wantLots: 1},
+		{basis: trader.LIFO, sell: "1", wantRealized: "100", wantAverage: "100", wantLots: 1},
+		{basis: trader.AverageCost, sell: "1", wantRealized: "150", wantAverage: "150", wantLots: 2},
+		{basis: trader.FIFO, sell: "1.5", wantRealized: "250", wantAverage: "200", wantLots: 1},
+		{basis: trader.LIFO, sell: "1.5", wantRealized: "200", wantAverage: "100", wantLots: 1},
+		{basis: trader.AverageCost, sell: "1.5", wantRealized: "225", wantAverage: "150", wantLots: 2},
+		{basis: trader.FIFO, sell: "2", wantRealized: "300", wantLots: 0},
+	}
+
+	for _, test := range tests {
+		portfolios := trader.NewPortfolios(storage.NewMemoryStore(), dec("10000"), test.basis, trader.CostModel{Slippage: trader.NoSlippage{}})
+		portfolio, err := portfolios.Open(1)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		buy(t, portfolio, "1", "100")
+		buy(t, portfolio, "1", "200")
+
+		trade := sell(t, portfolio, test.sell, "300")
+		if !trade.RealizedPnL.Equal(dec(test.wantRealized)) || !portfolio.RealizedPnL.Equal(dec(test.wantRealized)) {
+			t.Errorf("%s, продажа %s: PnL сделки %s, портфеля %s, ожидалось %s", test.basis, test.sell, trade.RealizedPnL, portfolio.RealizedPnL, test.wantRealized)
+		}
+
+		position, ok := portfolio.Position(token)
+		if test.wantLots == 0 {
+			if ok {
+				t.Errorf("%s, продажа %s: осталась позиция %+v", test.basis, test.sell, position)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("%s, продажа %s: позиция не найдена", test.basis, test.sell)
+		}
+		if want := dec("2").Sub(dec(test.sell)); !position.Quantity().Equal(want) {
+			t.Errorf("%s, продажа %s: осталось %s, ожидалось %s", test.basis, test.sell, position.Quantity(), want)
+		}
+		if !position.AveragePrice().Equal(dec(test.wantAverage)) {
+			t.Errorf("%s, продажа %s: средняя цена %s, ожидалась %s", test.basis, test.sell, position.AveragePrice(), test.wantAverage)
+		}
+		if len(position.Lots) != test.wantLots {
+			t.Errorf("%s, продажа %s: партий %d, ожидалось %d", test.basis, test.sell, len(position.Lots), test.wantLots)
+		}
+	}
+}
+
+func TestParseCostBasis(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    trader.CostBasis
+		wantErr bool
+	}{
+		{name: "average", want: trader.AverageCost},
+		{name: "fifo", want: trader.FIFO},
+		{name: "lifo", want: trader.LIFO},
+		{name: "FIFO", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := trader.ParseCostBasis(test.name)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("%q: получено %q, %v, ожидалось %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+// sell продает quantity токенов по цене price
+func sell(t *testing.T, portfolio *trader.Trader, quantity, price string) trader.Trade {
+	t.Helper()
+	quote, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideSell, Token: token, Mode: trader.ByQuantity, Value: dec(quantity)}, dec(price))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	trade, err := portfolio.Execute(quote)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	return trade
+}
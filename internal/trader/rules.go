@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// Точность учета, если инструмент не найден в справочнике: OKX ведет балансы с точностью до 8 знаков,
+// поэтому количество округляется вниз до 8 знаков, а суммы в USDT — математически до 8 знаков
+const (
+	defaultQuantityPlaces = 8
+	cashPlaces            = 8
+)
+
+// Нарушения торговых правил инструмента, при которых биржа отклоняет заявку
+var (
+	ErrLotSize  = errors.New("количество не кратно шагу лота")
+	ErrTickSize = errors.New("цена не кратна шагу цены")
+	ErrMinSize  = errors.New("количество меньше минимального")
+)
+
+// RuleError описывает нарушение торгового правила и допустимое значение рядом с указанным
+type RuleError struct {
+	Err       error // ErrLotSize, ErrTickSize или ErrMinSize
+	Token     string
+	Value     decimal.Decimal // Указанное количество или цена
+	Limit     decimal.Decimal // Шаг или минимум, заданный биржей
+	Suggested decimal.Decimal // Ближайшее допустимое значение
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s %s: %s (ограничение %s, допустимо %s)", e.Err, e.Token, e.Value, e.Limit, e.Suggested)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// instrument возвращает параметры инструмента, если справочник задан и знает токен
+func (t *Trader) instrument(token string) (okx.Instrument, bool) {
+	if t.instruments == nil {
+		return okx.Instrument{}, false
+	}
+	return t.instruments(token)
+}
+
+// RoundQuantity округляет количество вниз до шага лота инструмента
+func (t *Trader) RoundQuantity(token string, quantity decimal.Decimal) decimal.Decimal {
+	instrument, ok := t.instrument(token)
+	if !ok {
+		return quantity.RoundDown(defaultQuantityPlaces)
+	}
+	return instrument.RoundQuantity(quantity)
+}
+
+// RoundPrice округляет цену до шага цены инструмента
+func (t *Trader) RoundPrice(token string, price decimal.Decimal) decimal.Decimal {
+	instrument, ok := t.instrument(token)
+	if !ok {
+		return price
+	}
+	return instrument.RoundPrice(price)
+}
+
+// checkQuantity проверяет количество по правилам инструмента, если он есть в справочнике
+func (t *Trader) checkQuantity(token string, quantity decimal.Decimal) error {
+	instrument, ok := t.instrument(token)
+	if !ok {
+		return nil
+	}
+	return CheckQuantity(instrument, quantity)
+}
+
+// checkPrice проверяет цену по правилам инструмента, если он есть в справочнике
+func (t *Trader) checkPrice(token string, price decimal.Decimal) error {
+	instrument, ok := t.instrument(token)
+	if !ok {
+		return nil
+	}
+	return CheckPrice(instrument, price)
+}
+
+// CheckQuantity проверяет, что количество кратно шагу лота и не меньше минимального, как это делает OKX
+func CheckQuantity(instrument okx.Instrument, quantity decimal.Decimal) error {
+	if !onStep(quantity, instrument.LotSz) {
+		return &RuleError{Err: ErrLotSize, Token: instrument.InstID, Value: quantity, Limit: instrument.LotSz,
+			Suggested: decimal.Max(instrument.RoundQuantity(quantity), instrument.MinSz)}
+	}
+	if quantity.LessThan(instrument.MinSz) {
+		return &RuleError{Err: ErrMinSize, Token: instrument.InstID, Value: quantity, Limit: instrument.MinSz, Suggested: instrument.MinSz}
+	}
+	return nil
+}
+
+// CheckPrice проверяет, что цена кратна шагу цены инструмента
+func CheckPrice(instrument okx.Instrument, price decimal.Decimal) error {
+	if !onStep(price, instrument.TickSz) {
+		return &RuleError{Err: ErrTickSize, Token: instrument.InstID, Value: price, Limit: instrument.TickSz, Suggested: instrument.RoundPrice(price)}
+	}
+	return nil
+}
+
+// onStep сообщает, кратно ли значение шагу
+func onStep(value, step decimal.Decimal) bool {
+	if !step.IsPositive() {
+		return true
+	}
+	return value.Mod(step).IsZero()
+}
+
+// roundCash округляет сумму в USDT до точности учета баланса
+func roundCash(value decimal.Decimal) decimal.Decimal {
+	return value.Round(cashPlaces)
+}
@@ -0,0 +1,133 @@
+package trader_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/market"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+)
+
+// btc задает торговые правила BTC-USDT: лот 0.001, минимум 0.01, шаг цены 0.1
+var btc = okx.Instrument{
+	InstID:   token,
+	BaseCcy:  "BTC",
+	QuoteCcy: "USDT",
+	LotSz:    dec("0.001"),
+	MinSz:    dec("0.01"),
+	TickSz:   dec("0.1"),
+	State:    okx.InstrumentLive,
+}
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		check         func() error
+		wantErr       error
+		wantSuggested string
+	}{
+		{name: "количество кратно лоту", check: func() error { return trader.CheckQuantity(btc, dec("0.015")) }},
+		{name: "количество не кратно лоту", check: func() error { return trader.CheckQuantity(btc, dec("0.0155")) }, wantErr: trader.ErrLotSize, wantSuggested: "0.015"},
+		{name: "количество меньше лота", check: func() error { return trader.CheckQuantity(btc, dec("0.0005")) }, wantErr: trader.ErrLotSize, wantSuggested: "0.01"},
+		{name: "количество меньше минимума", check: func() error { return trader.CheckQuantity(btc, dec("0.005")) }, wantErr: trader.ErrMinSize, wantSuggested: "0.01"},
+		{name: "цена кратна шагу", check: func() error { return trader.CheckPrice(btc, dec("60000.1")) }},
+		{name: "цена не кратна шагу", check: func() error { return trader.CheckPrice(btc, dec("60000.16")) }, wantErr: trader.ErrTickSize, wantSuggested: "60000.2"},
+	}
+
+	for _, test := range tests {
+		err := test.check()
+		if test.wantErr == nil {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+
+		var rule *trader.RuleError
+		if !errors.Is(err, test.wantErr) || !errors.As(err, &rule) {
+			t.Errorf("%s: ошибка %v, ожидалась %v", test.name, err, test.wantErr)
+			continue
+		}
+		if !rule.Suggested.Equal(dec(test.wantSuggested)) {
+			t.Errorf("%s: предложено %s, ожидалось %s", test.name, rule.Suggested, test.wantSuggested)
+		}
+	}
+}
+
+func TestTraderRules(t *testing.T) {
+	portfolios, _ := newPortfolios()
+	portfolios.Instruments = market.NewStaticInstruments([]okx.Instrument{btc}).Lookup
+	portfolio, err := portfolios.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// Покупка на сумму округляется вниз до шага лота: $1000 / $60000 = 0.01666... → 0.016
+	quote, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: token, Mode: trader.ByNotional, Value: dec("1000")}, dec("60000"))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if !quote.Quantity.Equal(dec("0.016")) {
+		t.Errorf("количество %s, ожидалось 0.016", quote.Quantity)
+	}
+
+	tests := []struct {
+		name    string
+		place   func() error
+		wantErr error
+	}{
+		{
+			name: "рыночная покупка ниже минимума",
+			place: func() error {
+				_, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: token, Mode: trader.ByQuantity, Value: dec("0.005")}, dec("60000"))
+				return err
+			},
+			wantErr: trader.ErrMinSize,
+		},
+		{
+			name: "рыночная покупка не кратна лоту",
+			place: func() error {
+				_, err := portfolio.Quote(trader.OrderIntent{Side: trader.SideBuy, Token: token, Mode: trader.ByQuantity, Value: dec("0.0101")}, dec("60000"))
+				return err
+			},
+			wantErr: trader.ErrLotSize,
+		},
+		{
+			name: "лимитная цена не кратна шагу",
+			place: func() error {
+				_, err := portfolio.PlaceLimit(trader.SideBuy, token, dec("0.01"), dec("59000.05"), "")
+				return err
+			},
+			wantErr: trader.ErrTickSize,
+		},
+		{
+			name: "стоп не кратен шагу",
+			place: func() error {
+				_, err := portfolio.PlaceTrigger(trader.TriggerRequest{
+					Type: trader.OrderStop, Side: trader.SideBuy, Token: token, Quantity: dec("0.01"), TriggerPrice: dec("61000.01"), MarkPrice: dec("60000"),
+				})
+				return err
+			},
+			wantErr: trader.ErrTickSize,
+		},
+		{
+			name: "лимитная заявка по правилам",
+			place: func() error {
+				_, err := portfolio.PlaceLimit(trader.SideBuy, token, dec("0.01"), dec("59000.1"), "")
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.place(); !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: ошибка %v, ожидалась %v", test.name, err, test.wantErr)
+		}
+	}
+
+	// Токен без правил в справочнике не проверяется
+	if _, err := portfolio.PlaceLimit(trader.SideBuy, "ETH-USDT", dec("0.0001"), dec("3000.005"), ""); err != nil {
+		t.Errorf("заявка на токен без правил: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNotFound возвращается хранилищем, если портфель пользователя еще не создан
+var ErrNotFound = errors.New("портфель не найден")
+
+// Стороны сделки
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Trade описывает запись журнала сделок. Записи только добавляются и никогда не изменяются
+type Trade struct {
+	ID          int64
+	OrderID     int64 // Номер исполненной отложенной заявки, 0 для рыночных сделок
+	Time        time.Time
+	Side        string
+	Token       string
+	Quantity    decimal.Decimal // Количество токенов
+	Price       decimal.Decimal // Цена исполнения
+	QuoteAmount decimal.Decimal // Сумма сделки в USDT
+	Fee         decimal.Decimal
+	RealizedPnL decimal.Decimal
+	CashAfter   decimal.Decimal // Остаток USDT после сделки
+}
+
+// Store описывает хранилище портфелей и истории сделок
+type Store interface {
+	// LoadPortfolio загружает портфель пользователя или возвращает ErrNotFound
+	LoadPortfolio(userID int64) (*Trader, error)
+	// SavePortfolio сохраняет текущее состояние портфеля
+	SavePortfolio(t *Trader) error
+	// SaveTrade сохраняет портфель вместе с новой сделкой одной записью: после сбоя
+	// в хранилище не остается сделки без обновленного портфеля или наоборот
+	SaveTrade(t *Trader, trade Trade) error
+	// ListTrades возвращает сделки пользователя, начиная с самых новых
+	ListTrades(userID int64, offset, limit int) ([]Trade, error)
+	// ListUsers возвращает идентификаторы всех сохраненных портфелей
+	ListUsers() ([]int64, error)
+}
@@ -2,123 +2,215 @@ package trader
 
 import (
 	"fmt"
-	"strconv"
+	"sync"
+	"time"
 
 	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
 )
 
-// Investment хранит данные о конкретной инвестиции в токен
-type Investment struct {
-	Token    string
-	Amount   float64
-	BuyPrice float64
-}
-
 // Balance содержит информацию о текущем состоянии инвестиций
 type Balance struct {
-	Investments []Investment
-	TotalValue  float64 // Общая стоимость в долларах
+	Positions  []Position      // По одной строке на каждый токен
+	TotalValue decimal.Decimal // Общая стоимость в долларах
 }
 
-// Trader управляет капиталом и выполняет торговые операции
+// Trader управляет капиталом и выполняет торговые операции. Методы безопасны для параллельного вызова:
+// бот, исполнение заявок и стратегии работают с одним портфелем из разных горутин
 type Trader struct {
-	Capital     float64
-	Investments []Investment
+	UserID          int64
+	StartingCapital decimal.Decimal
+	Capital         decimal.Decimal
+	Positions       map[string]*Position
+	CostBasis       CostBasis
+	RealizedPnL     decimal.Decimal // Накопленный реализованный PnL по закрытым сделкам
+	TradeCount      int64           // Количество записей в журнале сделок
+	Orders          []*Order
+	NextOrderID     int64
+
+	// Costs задается конфигурацией и не сохраняется вместе с портфелем
+	Costs CostModel `json:"-"`
+
+	mu          sync.Mutex // Защищает состояние портфеля; неэкспортируемые методы вызываются под блокировкой
+	store       Store
+	clock       func() time.Time
+	instruments func(token string) (okx.Instrument, bool)
 }
 
-// BuyToken выполняет покупку токена по текущей цене
-func (t *Trader) BuyToken(token string, amount float64, price float64) error {
-	if amount > t.Capital {
-		return fmt.Errorf("недостаточно капитала для покупки")
+// NewTrader создает портфель пользователя с начальным капиталом
+func NewTrader(userID int64, capital decimal.Decimal) *Trader {
+	return &Trader{
+		UserID:          userID,
+		StartingCapital: capital,
+		Capital:         capital,
+		Positions:       make(map[string]*Position),
+		CostBasis:       AverageCost,
+		Costs:           DefaultCostModel(),
 	}
+}
 
-	investment := Investment{
-		Token:    token,
-		Amount:   amount,
-		BuyPrice: price,
+// buy зачисляет купленные токены и списывает USDT. orderID равен нулю для рыночных заявок
+func (t *Trader) buy(quote Quote, orderID int64) (Trade, error) {
+	position, ok := t.Positions[quote.Intent.Token]
+	if !ok {
+		position = &Position{Token: quote.Intent.Token}
+		t.Positions[quote.Intent.Token] = position
 	}
 
-	// Добавляем инвестицию и уменьшаем капитал
-	t.Investments = append(t.Investments, investment)
-	t.Capital -= amount
+	// Комиссия покупки входит в себестоимость партии
+	position.add(Lot{
+		Quantity: quote.Quantity,
+		Price:    quote.Cash.Div(quote.Quantity),
+		BoughtAt: t.now(),
+	})
+	t.Capital = t.Capital.Sub(quote.Cash)
+
+	return t.record(Trade{
+		OrderID:     orderID,
+		Side:        SideBuy,
+		Token:       quote.Intent.Token,
+		Quantity:    quote.Quantity,
+		Price:       quote.Price,
+		QuoteAmount: quote.Cash,
+		Fee:         quote.Fee,
+	})
+}
+
+// sell списывает проданные токены согласно методу учета себестоимости и зачисляет USDT
+func (t *Trader) sell(quote Quote, orderID int64) (Trade, error) {
+	position := t.Positions[quote.Intent.Token]
+	cost := roundCash(position.remove(quote.Quantity, t.CostBasis))
 
-	return nil
+	// Полностью проданную позицию удаляем: количества точные, поэтому остаток равен нулю без допусков
+	if len(position.Lots) == 0 {
+		delete(t.Positions, quote.Intent.Token)
+	}
+	t.Capital = t.Capital.Add(quote.Cash)
+	t.RealizedPnL = t.RealizedPnL.Add(quote.Cash.Sub(cost))
+
+	return t.record(Trade{
+		OrderID:     orderID,
+		Side:        SideSell,
+		Token:       quote.Intent.Token,
+		Quantity:    quote.Quantity,
+		Price:       quote.Price,
+		QuoteAmount: quote.Cash,
+		Fee:         quote.Fee,
+		RealizedPnL: quote.Cash.Sub(cost),
+	})
 }
 
-// SellToken выполняет продажу токена
-func (t *Trader) SellToken(token string, amount float64, currentPrice float64) (float64, error) {
-	for i, investment := range t.Investments {
-		if investment.Token == token {
-			if amount > investment.Amount {
-				return 0, fmt.Errorf("недостаточно токенов для продажи")
-			}
+// GetBalance возвращает позиции по токенам и их общую себестоимость вместе с USDT
+func (t *Trader) GetBalance() (*Balance, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-			// Рассчитываем прибыль
-			profit := amount * (currentPrice / investment.BuyPrice)
+	totalValue := t.Capital.Add(t.reservedCash())
+	for _, position := range t.Positions {
+		totalValue = totalValue.Add(position.Cost())
+	}
 
-			// Возвращаем прибыль в капитал и обновляем инвестицию
-			t.Capital += profit
-			investment.Amount -= amount
+	return &Balance{
+		Positions:  sortedPositions(t.Positions),
+		TotalValue: totalValue,
+	}, nil
+}
 
-			// Если инвестиция полностью продана, удаляем её
-			if investment.Amount == 0 {
-				t.Investments = append(t.Investments[:i], t.Investments[i+1:]...)
-			}
+// Position возвращает копию позиции по токену вместе с разбивкой на партии
+func (t *Trader) Position(token string) (Position, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-			return profit, nil
-		}
+	position, ok := t.Positions[token]
+	if !ok {
+		return Position{}, false
 	}
-	return 0, fmt.Errorf("инвестиция в токен %s не найдена", token)
+	return Position{Token: position.Token, Lots: append([]Lot{}, position.Lots...)}, true
+}
+
+func (t *Trader) GetCapital() decimal.Decimal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.Capital
 }
 
-// ExecuteGridStrategy выполняет сеточную стратегию покупки/продажи
-func (t *Trader) ExecuteGridStrategy(token string, priceDropPercent, priceRisePercent, amount float64) error {
-	currentPrice, err := okx.GetCurrentPrice(token + "-USDT")
-	if err != nil {
-		return err
+// now возвращает текущее время по часам портфеля
+func (t *Trader) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
 	}
+	return t.clock()
+}
 
-	price, _ := strconv.ParseFloat(currentPrice, 64)
+// record сохраняет портфель вместе со сделкой одной записью хранилища
+func (t *Trader) record(trade Trade) (Trade, error) {
+	t.TradeCount++
+	trade.ID = t.TradeCount
+	trade.Time = t.now()
+	trade.CashAfter = t.Capital
 
-	// Покупка при падении цены
-	for _, investment := range t.Investments {
-		if investment.Token == token && price <= investment.BuyPrice*(1-priceDropPercent/100) {
-			return t.BuyToken(token, amount, price) // Например, покупаем на указанное количество
-		}
+	if t.store == nil {
+		return trade, nil
 	}
+	if err := t.store.SaveTrade(t, trade); err != nil {
+		return trade, fmt.Errorf("не удалось сохранить сделку: %w", err)
+	}
+	return trade, nil
+}
 
-	// Продажа при росте цены
-	for _, investment := range t.Investments {
-		if investment.Token == token && price >= investment.BuyPrice*(1+priceRisePercent/100) {
-			_, err := t.SellToken(token, amount, price) // Продажа указанного количества
-			if err != nil {
-				return err
-			}
-		}
+// save сохраняет портфель в хранилище, если оно подключено
+func (t *Trader) save() error {
+	if t.store == nil {
+		return nil
 	}
 
+	if err := t.store.SavePortfolio(t); err != nil {
+		return fmt.Errorf("не удалось сохранить портфель: %w", err)
+	}
 	return nil
 }
 
-func (t *Trader) GetBalance() (*Balance, error) {
-	if len(t.Investments) == 0 {
-		return &Balance{
-			Investments: []Investment{},
-			TotalValue:  100.00,
-		}, nil
-	}
+// snapshot хранит изменяемое состояние портфеля, чтобы отменить операцию, которую не удалось сохранить
+type snapshot struct {
+	capital     decimal.Decimal
+	realizedPnL decimal.Decimal
+	tradeCount  int64
+	nextOrderID int64
+	positions   map[string]*Position
+	orders      []*Order
+}
 
-	totalValue := 0.0
-	for _, investment := range t.Investments {
-		totalValue += investment.Amount * investment.BuyPrice
+// snapshot копирует состояние портфеля. Позиции и заявки меняются на месте, поэтому копируются целиком
+func (t *Trader) snapshot() snapshot {
+	s := snapshot{
+		capital:     t.Capital,
+		realizedPnL: t.RealizedPnL,
+		tradeCount:  t.TradeCount,
+		nextOrderID: t.NextOrderID,
+		positions:   make(map[string]*Position, len(t.Positions)),
+		orders:      make([]*Order, 0, len(t.Orders)),
 	}
-
-	return &Balance{
-		Investments: t.Investments,
-		TotalValue:  totalValue,
-	}, nil
+	for token, position := range t.Positions {
+		s.positions[token] = &Position{Token: position.Token, Lots: append([]Lot{}, position.Lots...)}
+	}
+	for _, order := range t.Orders {
+		copied := *order
+		s.orders = append(s.orders, &copied)
+	}
+	return s
 }
 
-func (t *Trader) GetCapital() float64 {
-	return t.Capital
+// rollback возвращает портфель к снимку, если операция завершилась ошибкой: состояние в памяти
+// не должно расходиться с хранилищем, иначе после перезапуска сделка исчезнет или задвоится
+func (t *Trader) rollback(before snapshot, err *error) {
+	if *err == nil {
+		return
+	}
+	t.Capital = before.capital
+	t.RealizedPnL = before.realizedPnL
+	t.TradeCount = before.tradeCount
+	t.NextOrderID = before.nextOrderID
+	t.Positions = before.positions
+	t.Orders = before.orders
 }
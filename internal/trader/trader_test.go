@@ -0,0 +1,147 @@
+package trader_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/storage"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/internal/trader"
+	"github.com/shopspring/decimal"
+)
+
+const token = "BTC-USDT"
+
+// newPortfolios создает портфели в памяти без комиссий и проскальзывания
+func newPortfolios() (*trader.Portfolios, *storage.MemoryStore) {
+	store := storage.NewMemoryStore()
+	costs := trader.CostModel{Slippage: trader.NoSlippage{}}
+	return trader.NewPortfolios(store, decimal.NewFromInt(10000), trader.AverageCost, costs), store
+}
+
+// Рыночные заявки, выставление лимитных заявок и их сверка с ценой идут из разных горутин
+// (бот, сетка, DCA, проверка заявок) над одним портфелем. Тест запускается с -race
+func TestTraderConcurrentOrders(t *testing.T) {
+	portfolios, store := newPortfolios()
+	portfolio, err := portfolios.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	const workers = 20
+	var (
+		wg       sync.WaitGroup
+		executed sync.Map
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			intent := trader.OrderIntent{Side: trader.SideBuy, Token: token, Mode: trader.ByNotional, Value: decimal.NewFromInt(10)}
+			quote, err := portfolio.Quote(intent, decimal.NewFromInt(100))
+			if err != nil {
+				t.Errorf("Quote: %v", err)
+				return
+			}
+			trade, err := portfolio.Execute(quote)
+			if err != nil {
+				t.Errorf("Execute: %v", err)
+				return
+			}
+			executed.Store(trade.ID, true)
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := portfolio.PlaceLimit(trader.SideBuy, token, decimal.RequireFromString("0.1"), decimal.NewFromInt(90), ""); err != nil {
+				t.Errorf("PlaceLimit: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := portfolio.MatchOrders(token, decimal.NewFromInt(89)); err != nil {
+				t.Errorf("MatchOrders: %v", err)
+			}
+			portfolio.OpenOrders()
+			portfolio.Report(map[string]decimal.Decimal{token: decimal.NewFromInt(89)})
+		}()
+	}
+	wg.Wait()
+
+	// Заявки, выставленные после последней сверки, исполняются следующей
+	if _, err := portfolio.MatchOrders(token, decimal.NewFromInt(89)); err != nil {
+		t.Fatalf("MatchOrders: %v", err)
+	}
+	if open := portfolio.OpenOrders(); len(open) != 0 {
+		t.Fatalf("осталось открытых заявок: %d", len(open))
+	}
+
+	// 20 покупок по $10 и 20 покупок 0.1 BTC по $90 = $200 + $180
+	if want := decimal.NewFromInt(10000 - 200 - 180); !portfolio.GetCapital().Equal(want) {
+		t.Fatalf("капитал %s, ожидался %s", portfolio.GetCapital(), want)
+	}
+	if want := decimal.NewFromInt(2 + 2); !portfolio.Holding(token).Equal(want) {
+		t.Fatalf("позиция %s, ожидалась %s", portfolio.Holding(token), want)
+	}
+
+	trades, err := store.ListTrades(1, 0, 1000)
+	if err != nil {
+		t.Fatalf("ListTrades: %v", err)
+	}
+	if len(trades) != 2*workers || portfolio.TradeCount != 2*workers {
+		t.Fatalf("сделок в журнале %d, в портфеле %d, ожидалось %d", len(trades), portfolio.TradeCount, 2*workers)
+	}
+	ids := make(map[int64]bool)
+	for _, trade := range trades {
+		if ids[trade.ID] {
+			t.Fatalf("повторный номер сделки %d", trade.ID)
+		}
+		ids[trade.ID] = true
+	}
+}
+
+// Портфель пользователя открывается один раз, даже если первые команды приходят одновременно
+func TestPortfoliosConcurrentOpen(t *testing.T) {
+	portfolios, store := newPortfolios()
+
+	const users, callers = 5, 10
+	opened := make([][]*trader.Trader, users)
+	for i := range opened {
+		opened[i] = make([]*trader.Trader, callers)
+	}
+
+	var wg sync.WaitGroup
+	for user := 0; user < users; user++ {
+		for caller := 0; caller < callers; caller++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				portfolio, err := portfolios.Open(int64(user + 1))
+				if err != nil {
+					t.Errorf("Open: %v", err)
+					return
+				}
+				if got, err := portfolios.Get(int64(user + 1)); err != nil || got != portfolio {
+					t.Errorf("Get вернул другой портфель: %v", err)
+				}
+				opened[user][caller] = portfolio
+			}()
+		}
+	}
+	wg.Wait()
+
+	for user, portfolios := range opened {
+		for _, portfolio := range portfolios {
+			if portfolio != portfolios[0] {
+				t.Fatalf("пользователь %d получил несколько портфелей", user+1)
+			}
+		}
+	}
+
+	saved, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(saved) != users {
+		t.Fatalf("сохранено портфелей %d, ожидалось %d", len(saved), users)
+	}
+}
@@ -0,0 +1,221 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Типы условных заявок, исполняемых по рынку при срабатывании
+const (
+	OrderStop         = "stop"
+	OrderTakeProfit   = "take_profit"
+	OrderTrailingStop = "trailing_stop"
+)
+
+// TriggerRequest описывает условную заявку: стоп, тейк-профит или трейлинг-стоп
+type TriggerRequest struct {
+	Type         string
+	Side         string
+	Token        string
+	Quantity     decimal.Decimal
+	TriggerPrice decimal.Decimal // Цена срабатывания стопа и тейк-профита
+	TrailPercent decimal.Decimal // Отступ трейлинг-стопа в процентах
+	TrailAmount  decimal.Decimal // Отступ трейлинг-стопа в USDT
+	MarkPrice    decimal.Decimal // Текущая рыночная цена
+}
+
+// PlaceTrigger создает условную заявку. Заявки на продажу резервируют токены позиции
+func (t *Trader) PlaceTrigger(req TriggerRequest) (_ Order, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.rollback(t.snapshot(), &err)
+
+	order, err := t.newTrigger(req)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if req.Side == SideSell {
+		if available := t.holding(req.Token); req.Quantity.GreaterThan(available) {
+			return Order{}, fmt.Errorf("недостаточно токенов %s: нужно %s, доступно %s", req.Token, req.Quantity, available)
+		}
+		order.Reserved = req.Quantity
+	}
+
+	t.addOrder(order)
+	return *order, t.save()
+}
+
+// PlaceOCO создает связанные стоп-лосс и тейк-профит на продажу позиции: срабатывание одной заявки отменяет другую
+func (t *Trader) PlaceOCO(token string, quantity, stopPrice, takeProfitPrice, markPrice decimal.Decimal) (_ []Order, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.rollback(t.snapshot(), &err)
+
+	stop, err := t.newTrigger(TriggerRequest{
+		Type: OrderStop, Side: SideSell, Token: token, Quantity: quantity, TriggerPrice: stopPrice, MarkPrice: markPrice,
+	})
+	if err != nil {
+		return nil, err
+	}
+	takeProfit, err := t.newTrigger(TriggerRequest{
+		Type: OrderTakeProfit, Side: SideSell, Token: token, Quantity: quantity, TriggerPrice: takeProfitPrice, MarkPrice: markPrice,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if available := t.holding(token); quantity.GreaterThan(available) {
+		return nil, fmt.Errorf("недостаточно токенов %s: нужно %s, доступно %s", token, quantity, available)
+	}
+
+	// Обе заявки резервируют одни и те же токены, поэтому резерв учитывается один раз на группу
+	stop.Reserved = quantity
+	takeProfit.Reserved = quantity
+	t.addOrder(stop)
+	t.addOrder(takeProfit)
+	stop.OCOGroup = stop.ID
+	takeProfit.OCOGroup = stop.ID
+
+	return []Order{*stop, *takeProfit}, t.save()
+}
+
+// newTrigger проверяет параметры условной заявки
+func (t *Trader) newTrigger(req TriggerRequest) (*Order, error) {
+	if !req.Quantity.IsPositive() {
+		return nil, fmt.Errorf("количество должно быть больше нуля")
+	}
+	if req.Side != SideBuy && req.Side != SideSell {
+		return nil, fmt.Errorf("неизвестная сторона заявки: %s", req.Side)
+	}
+	if !req.MarkPrice.IsPositive() {
+		return nil, fmt.Errorf("неизвестна текущая цена %s", req.Token)
+	}
+	if err := t.checkQuantity(req.Token, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	order := &Order{
+		Type:         req.Type,
+		Side:         req.Side,
+		Token:        req.Token,
+		Quantity:     req.Quantity,
+		TriggerPrice: req.TriggerPrice,
+		TrailPercent: req.TrailPercent,
+		TrailAmount:  req.TrailAmount,
+		CreatedAt:    t.now(),
+	}
+
+	switch req.Type {
+	case OrderStop, OrderTakeProfit:
+		if !req.TriggerPrice.IsPositive() {
+			return nil, fmt.Errorf("цена срабатывания должна быть больше нуля")
+		}
+		if err := t.checkPrice(req.Token, req.TriggerPrice); err != nil {
+			return nil, err
+		}
+		// Заявка не должна срабатывать сразу после создания
+		if triggered(order, req.MarkPrice) {
+			return nil, fmt.Errorf("цена срабатывания $%s уже достигнута: текущая цена $%s", req.TriggerPrice, req.MarkPrice)
+		}
+	case OrderTrailingStop:
+		if req.TrailPercent.IsPositive() == req.TrailAmount.IsPositive() {
+			return nil, fmt.Errorf("укажите отступ трейлинг-стопа в процентах или в USDT")
+		}
+		if req.TrailPercent.GreaterThanOrEqual(hundred) {
+			return nil, fmt.Errorf("отступ трейлинг-стопа должен быть меньше 100%%")
+		}
+		order.Extreme = req.MarkPrice
+		order.TriggerPrice = trailingStopPrice(order)
+	default:
+		return nil, fmt.Errorf("неизвестный тип условной заявки: %s", req.Type)
+	}
+	return order, nil
+}
+
+// addOrder присваивает заявке номер и добавляет ее в список открытых
+func (t *Trader) addOrder(order *Order) {
+	t.NextOrderID++
+	order.ID = t.NextOrderID
+	t.Orders = append(t.Orders, order)
+}
+
+// trail сдвигает уровень трейлинг-стопа вслед за ценой и сообщает, изменился ли он
+func trail(order *Order, price decimal.Decimal) bool {
+	if order.Type != OrderTrailingStop {
+		return false
+	}
+
+	// Для продажи отслеживаем максимум цены, для покупки — минимум
+	if (order.Side == SideSell && price.GreaterThan(order.Extreme)) || (order.Side == SideBuy && price.LessThan(order.Extreme)) {
+		order.Extreme = price
+		order.TriggerPrice = trailingStopPrice(order)
+		return true
+	}
+	return false
+}
+
+// trailingStopPrice рассчитывает уровень срабатывания трейлинг-стопа от экстремума цены
+func trailingStopPrice(order *Order) decimal.Decimal {
+	distance := order.TrailAmount
+	if order.TrailPercent.IsPositive() {
+		distance = order.Extreme.Mul(order.TrailPercent).Div(hundred)
+	}
+
+	if order.Side == SideSell {
+		return order.Extreme.Sub(distance)
+	}
+	return order.Extreme.Add(distance)
+}
+
+// triggered проверяет, достигла ли цена уровня срабатывания условной заявки
+func triggered(order *Order, price decimal.Decimal) bool {
+	// Стоп на продажу и тейк-профит на покупку срабатывают при падении цены
+	falling := (order.Type == OrderTakeProfit) == (order.Side == SideBuy)
+	if falling {
+		return price.LessThanOrEqual(order.TriggerPrice)
+	}
+	return price.GreaterThanOrEqual(order.TriggerPrice)
+}
+
+// triggerOrder исполняет сработавшую условную заявку по рынку и отменяет связанные с ней заявки.
+// Если результат не удалось сохранить, заявки остаются открытыми до следующей проверки
+func (t *Trader) triggerOrder(id int64, price decimal.Decimal) Fill {
+	before := t.snapshot()
+	order, ok := t.removeOrder(id)
+	if !ok {
+		return Fill{Err: fmt.Errorf("заявка #%d не найдена", id)}
+	}
+
+	fill := Fill{Order: *order}
+	if order.OCOGroup != 0 {
+		for _, linked := range t.openOrders() {
+			if linked.OCOGroup == order.OCOGroup {
+				t.removeOrder(linked.ID)
+				fill.Canceled = append(fill.Canceled, linked)
+			}
+		}
+	}
+
+	quote, err := t.makeQuote(OrderIntent{Side: order.Side, Token: order.Token, Mode: ByQuantity, Value: order.Quantity}, price)
+	if err != nil {
+		// Неисполнимая заявка снимается вместе со связанными
+		saveErr := t.save()
+		t.rollback(before, &saveErr)
+		fill.Err = errors.Join(err, saveErr)
+		return fill
+	}
+
+	if order.Side == SideBuy {
+		fill.Trade, fill.Err = t.buy(quote, order.ID)
+	} else {
+		fill.Trade, fill.Err = t.sell(quote, order.ID)
+	}
+	if fill.Err != nil {
+		t.rollback(before, &fill.Err)
+		fill.Canceled = nil
+	}
+	return fill
+}
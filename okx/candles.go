@@ -0,0 +1,225 @@
+package okx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	candlesPageSize        = 300 // Максимум свечей за запрос к /market/candles
+	historyCandlesPageSize = 100 // Максимум свечей за запрос к /market/history-candles
+	historyPagePause       = 100 * time.Millisecond
+)
+
+// Bar задает размер свечи в формате OKX
+type Bar string
+
+// Поддерживаемые размеры свечей
+const (
+	Bar1m  Bar = "1m"
+	Bar3m  Bar = "3m"
+	Bar5m  Bar = "5m"
+	Bar15m Bar = "15m"
+	Bar30m Bar = "30m"
+	Bar1H  Bar = "1H"
+	Bar2H  Bar = "2H"
+	Bar4H  Bar = "4H"
+	Bar6H  Bar = "6H"
+	Bar12H Bar = "12H"
+	Bar1D  Bar = "1D"
+	Bar1W  Bar = "1W"
+	Bar1M  Bar = "1M"
+)
+
+// barDurations хранит длительность каждого размера свечи; месяц считается равным 30 дням
+var barDurations = map[Bar]time.Duration{
+	Bar1m:  time.Minute,
+	Bar3m:  3 * time.Minute,
+	Bar5m:  5 * time.Minute,
+	Bar15m: 15 * time.Minute,
+	Bar30m: 30 * time.Minute,
+	Bar1H:  time.Hour,
+	Bar2H:  2 * time.Hour,
+	Bar4H:  4 * time.Hour,
+	Bar6H:  6 * time.Hour,
+	Bar12H: 12 * time.Hour,
+	Bar1D:  24 * time.Hour,
+	Bar1W:  7 * 24 * time.Hour,
+	Bar1M:  30 * 24 * time.Hour,
+}
+
+// ParseBar разбирает размер свечи; часы, дни и недели принимаются в любом регистре ("4h", "1d"),
+// а "1m" и "1M" различаются как минута и месяц
+func ParseBar(raw string) (Bar, error) {
+	if _, ok := barDurations[Bar(raw)]; ok {
+		return Bar(raw), nil
+	}
+	if bar := Bar(strings.ToUpper(raw)); strings.ContainsAny(string(bar), "HDW") {
+		if _, ok := barDurations[bar]; ok {
+			return bar, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный размер свечи %s", raw)
+}
+
+// Duration возвращает длительность свечи
+func (b Bar) Duration() time.Duration {
+	return barDurations[b]
+}
+
+// Candle описывает свечу OHLCV
+type Candle struct {
+	Time        time.Time // Время открытия
+	Open        decimal.Decimal
+	High        decimal.Decimal
+	Low         decimal.Decimal
+	Close       decimal.Decimal
+	Volume      decimal.Decimal // Объем в базовой валюте
+	QuoteVolume decimal.Decimal // Объем в валюте котировки
+	Confirmed   bool            // Свеча закрыта
+}
+
+// GetCandles возвращает последние limit свечей актива в порядке возрастания времени
+func (c *Client) GetCandles(ctx context.Context, symbol string, bar Bar, limit int) ([]Candle, error) {
+	if _, ok := barDurations[bar]; !ok {
+		return nil, fmt.Errorf("неизвестный размер свечи %s", bar)
+	}
+
+	var (
+		candles []Candle
+		after   time.Time
+	)
+	for len(candles) < limit {
+		page, err := c.fetchCandles(ctx, "/api/v5/market/candles", symbol, bar, after, min(limit-len(candles), candlesPageSize))
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		candles = append(candles, page...)
+		after = page[len(page)-1].Time
+	}
+
+	sortCandles(candles)
+	return candles, nil
+}
+
+// GetCandlesRange возвращает свечи актива, открытые в интервале [from, to), в порядке возрастания времени.
+// Длинные интервалы загружаются постранично из архива /market/history-candles
+func (c *Client) GetCandlesRange(ctx context.Context, symbol string, bar Bar, from, to time.Time) ([]Candle, error) {
+	if _, ok := barDurations[bar]; !ok {
+		return nil, fmt.Errorf("неизвестный размер свечи %s", bar)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("начало интервала должно быть раньше конца")
+	}
+
+	var candles []Candle
+	after := to
+	for {
+		page, err := c.fetchCandles(ctx, "/api/v5/market/history-candles", symbol, bar, after, historyCandlesPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		for _, candle := range page {
+			if !candle.Time.Before(from) && candle.Time.Before(to) {
+				candles = append(candles, candle)
+			}
+		}
+
+		oldest := page[len(page)-1].Time
+		if !oldest.After(from) || !oldest.Before(after) {
+			break
+		}
+		after = oldest
+
+		// Архивные свечи ограничены 20 запросами за 2 секунды
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(historyPagePause):
+		}
+	}
+
+	sortCandles(candles)
+	return candles, nil
+}
+
+// fetchCandles загружает одну страницу свечей, открытых раньше after (без ограничения, если after нулевое).
+// OKX возвращает свечи от новых к старым
+func (c *Client) fetchCandles(ctx context.Context, path, symbol string, bar Bar, after time.Time, limit int) ([]Candle, error) {
+	query := fmt.Sprintf("%s?instId=%s&bar=%s&limit=%d", path, symbol, bar, limit)
+	if !after.IsZero() {
+		query += fmt.Sprintf("&after=%d", after.UnixMilli())
+	}
+
+	// Свечи OKX приходят массивами строк [время, open, high, low, close, объем, объем в валюте, объем в котировке, закрыта]
+	var candlesResponse struct {
+		Code string     `json:"code"`
+		Msg  string     `json:"msg"`
+		Data [][]string `json:"data"`
+	}
+
+	if err := c.getJSON(ctx, query, &candlesResponse); err != nil {
+		return nil, err
+	}
+
+	if candlesResponse.Code != "0" {
+		return nil, fmt.Errorf("не удалось получить свечи для актива %s: %s", symbol, candlesResponse.Msg)
+	}
+
+	candles := make([]Candle, 0, len(candlesResponse.Data))
+	for _, raw := range candlesResponse.Data {
+		candle, err := parseCandle(raw)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// parseCandle разбирает свечу из строкового представления OKX
+func parseCandle(raw []string) (Candle, error) {
+	if len(raw) < 9 {
+		return Candle{}, fmt.Errorf("некорректная свеча: %v", raw)
+	}
+
+	ms, err := strconv.ParseInt(raw[0], 10, 64)
+	if err != nil {
+		return Candle{}, fmt.Errorf("некорректное время свечи: %w", err)
+	}
+
+	values, err := parseDecimals(raw[1:8]...)
+	if err != nil {
+		return Candle{}, fmt.Errorf("некорректное значение свечи: %w", err)
+	}
+
+	return Candle{
+		Time:        time.UnixMilli(ms),
+		Open:        values[0],
+		High:        values[1],
+		Low:         values[2],
+		Close:       values[3],
+		Volume:      values[4],
+		QuoteVolume: values[6],
+		Confirmed:   raw[8] == "1",
+	}, nil
+}
+
+// sortCandles упорядочивает свечи по возрастанию времени
+func sortCandles(candles []Candle) {
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+}
@@ -0,0 +1,117 @@
+package okx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx/okxtest"
+	"github.com/shopspring/decimal"
+)
+
+// hourlyCandles создает count часовых свечей подряд, начиная со start; цена закрытия равна номеру свечи
+func hourlyCandles(start time.Time, count int) []okx.Candle {
+	candles := make([]okx.Candle, count)
+	for i := range candles {
+		candles[i] = okx.Candle{Time: start.Add(time.Duration(i) * time.Hour), Close: decimal.NewFromInt(int64(i)), Confirmed: true}
+	}
+	return candles
+}
+
+func TestGetCandlesRange(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(i int) time.Time { return start.Add(time.Duration(i) * time.Hour) }
+
+	tests := []struct {
+		name         string
+		from, to     time.Time
+		wantFirst    int
+		wantCount    int
+		wantRequests int
+	}{
+		// Архив отдает по 100 свечей: три полные страницы и пустая, после которой загрузка заканчивается
+		{name: "весь архив", from: hour(-10), to: hour(300), wantFirst: 0, wantCount: 250, wantRequests: 4},
+		{name: "середина на нескольких страницах", from: hour(20), to: hour(170), wantFirst: 20, wantCount: 150, wantRequests: 2},
+		{name: "одна страница", from: hour(230), to: hour(240), wantFirst: 230, wantCount: 10, wantRequests: 1},
+		{name: "конец интервала не входит", from: hour(5), to: hour(6), wantFirst: 5, wantCount: 1, wantRequests: 1},
+		{name: "интервал раньше архива", from: hour(-50), to: hour(-10), wantCount: 0, wantRequests: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := okxtest.NewServer()
+			defer server.Close()
+			server.SetCandles("BTC-USDT", okx.Bar1H, hourlyCandles(start, 250))
+
+			candles, err := okx.NewClient(server.URL).GetCandlesRange(context.Background(), "BTC-USDT", okx.Bar1H, test.from, test.to)
+			if err != nil {
+				t.Fatalf("GetCandlesRange: %v", err)
+			}
+			if len(candles) != test.wantCount {
+				t.Fatalf("свечей %d, ожидалось %d", len(candles), test.wantCount)
+			}
+			for i, candle := range candles {
+				if want := hour(test.wantFirst + i); !candle.Time.Equal(want) {
+					t.Fatalf("свеча %d открыта %s, ожидалось %s", i, candle.Time, want)
+				}
+			}
+			if got := server.Requests("/api/v5/market/history-candles"); got != test.wantRequests {
+				t.Errorf("запросов %d, ожидалось %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestGetCandlesRangeRejects(t *testing.T) {
+	client := okx.NewClient("http://127.0.0.1:0")
+	now := time.Now()
+
+	if _, err := client.GetCandlesRange(context.Background(), "BTC-USDT", okx.Bar("7m"), now.Add(-time.Hour), now); err == nil {
+		t.Error("ожидалась ошибка для неизвестного размера свечи")
+	}
+	if _, err := client.GetCandlesRange(context.Background(), "BTC-USDT", okx.Bar1H, now, now); err == nil {
+		t.Error("ожидалась ошибка для пустого интервала")
+	}
+}
+
+func TestGetCandlesPages(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		limit        int
+		wantCount    int
+		wantRequests int
+	}{
+		{name: "одна страница", limit: 50, wantCount: 50, wantRequests: 1},
+		// Последние свечи отдаются по 300 за запрос
+		{name: "две страницы", limit: 350, wantCount: 350, wantRequests: 2},
+		{name: "больше, чем есть", limit: 500, wantCount: 400, wantRequests: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := okxtest.NewServer()
+			defer server.Close()
+			server.SetCandles("BTC-USDT", okx.Bar1H, hourlyCandles(start, 400))
+
+			candles, err := okx.NewClient(server.URL).GetCandles(context.Background(), "BTC-USDT", okx.Bar1H, test.limit)
+			if err != nil {
+				t.Fatalf("GetCandles: %v", err)
+			}
+			if len(candles) != test.wantCount {
+				t.Fatalf("свечей %d, ожидалось %d", len(candles), test.wantCount)
+			}
+			// Возвращаются самые новые свечи по возрастанию времени
+			for i, candle := range candles {
+				if want := int64(400 - test.wantCount + i); !candle.Close.Equal(decimal.NewFromInt(want)) {
+					t.Fatalf("свеча %d с закрытием %s, ожидалось %d", i, candle.Close, want)
+				}
+			}
+			if got := server.Requests("/api/v5/market/candles"); got != test.wantRequests {
+				t.Errorf("запросов %d, ожидалось %d", got, test.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultBaseURL задает адрес REST API OKX
+const DefaultBaseURL = "https://www.okx.com"
+
+// defaultTimeout ограничивает время одного запроса к REST API
+const defaultTimeout = 30 * time.Second
+
+// Client обращается к REST API OKX по адресу BaseURL.
+// Адрес задается конфигурацией, например адрес локального тестового сервера okxtest
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient создает клиент REST API с адресом baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, HTTP: &http.Client{Timeout: defaultTimeout}}
+}
+
+// ErrTooManyRequests возвращается, когда OKX ограничивает частоту запросов (HTTP 429)
+var ErrTooManyRequests = errors.New("слишком много запросов к OKX")
+
+// getJSON выполняет GET-запрос к REST API и декодирует ответ в v; запрос прерывается при отмене ctx
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		log.Printf("Ошибка при выполнении запроса: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %s", ErrTooManyRequests, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ошибка при запросе: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		log.Printf("Ошибка при декодировании JSON: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package okx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx/okxtest"
+	"github.com/shopspring/decimal"
+)
+
+func TestClientTooManyRequests(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+	server.FailStatus("/api/v5/market/ticker", 429, 1)
+
+	client := okx.NewClient(server.URL)
+	if _, err := client.GetCurrentPrice("BTC-USDT"); !errors.Is(err, okx.ErrTooManyRequests) {
+		t.Fatalf("ожидалась ErrTooManyRequests, получено %v", err)
+	}
+
+	price, err := client.GetCurrentPrice("BTC-USDT")
+	if err != nil {
+		t.Fatalf("повторный запрос: %v", err)
+	}
+	if !price.Equal(decimal.NewFromInt(60000)) {
+		t.Fatalf("цена %s, ожидалась 60000", price)
+	}
+}
+
+func TestClientServerError(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+	server.FailStatus("/api/v5/market/ticker", 503, 1)
+	server.FailStatus("/api/v5/market/books", 500, 1)
+
+	client := okx.NewClient(server.URL)
+	_, err := client.GetTicker("BTC-USDT")
+	if err == nil || errors.Is(err, okx.ErrTooManyRequests) {
+		t.Fatalf("ожидалась ошибка сервера, получено %v", err)
+	}
+	if _, err := client.GetOrderBook("BTC-USDT", 5); err == nil {
+		t.Fatal("ожидалась ошибка сервера для стакана")
+	}
+}
+
+func TestClientErrorCode(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+	server.SetBook("BTC-USDT", okx.OrderBook{
+		Asks: []okx.BookLevel{{Price: decimal.NewFromInt(60001), Size: decimal.NewFromInt(1)}},
+		Bids: []okx.BookLevel{{Price: decimal.NewFromInt(59999), Size: decimal.NewFromInt(1)}},
+	})
+	server.SetCandles("BTC-USDT", okx.Bar1H, []okx.Candle{{Time: time.Now().Add(-time.Hour).Truncate(time.Hour)}})
+
+	client := okx.NewClient(server.URL)
+	tests := []struct {
+		path string
+		call func() error
+	}{
+		{"/api/v5/market/ticker", func() error {
+			_, err := client.GetTicker("BTC-USDT")
+			return err
+		}},
+		{"/api/v5/market/books", func() error {
+			_, err := client.GetOrderBook("BTC-USDT", 5)
+			return err
+		}},
+		{"/api/v5/market/candles", func() error {
+			_, err := client.GetCandles(context.Background(), "BTC-USDT", okx.Bar1H, 10)
+			return err
+		}},
+		{"/api/v5/public/instruments", func() error {
+			_, err := client.GetInstruments()
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		server.FailCode(test.path, "50011", "Rate limit reached", 1)
+		if err := test.call(); err == nil {
+			t.Errorf("%s: ожидалась ошибка для кода 50011", test.path)
+		}
+		if err := test.call(); err != nil {
+			t.Errorf("%s: повторный запрос: %v", test.path, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package okx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// InstrumentLive обозначает инструмент, доступный для торговли
+const InstrumentLive = "live"
+
+// Instrument описывает спотовый инструмент OKX и его торговые ограничения
+type Instrument struct {
+	InstID   string
+	BaseCcy  string
+	QuoteCcy string
+	LotSz    decimal.Decimal // Шаг количества
+	TickSz   decimal.Decimal // Шаг цены
+	MinSz    decimal.Decimal // Минимальное количество в заявке
+	State    string          // live, suspend, preopen и т.д.
+}
+
+// Live сообщает, доступен ли инструмент для торговли
+func (i Instrument) Live() bool {
+	return i.State == InstrumentLive
+}
+
+// RoundQuantity округляет количество вниз до шага lotSz
+func (i Instrument) RoundQuantity(quantity decimal.Decimal) decimal.Decimal {
+	if !i.LotSz.IsPositive() {
+		return quantity
+	}
+	return quantity.Div(i.LotSz).Floor().Mul(i.LotSz)
+}
+
+// RoundPrice округляет цену до ближайшего шага tickSz
+func (i Instrument) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	if !i.TickSz.IsPositive() {
+		return price
+	}
+	return price.Div(i.TickSz).Round(0).Mul(i.TickSz)
+}
+
+// GetInstruments возвращает спотовые инструменты OKX
+func (c *Client) GetInstruments() ([]Instrument, error) {
+	var instrumentsResponse struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID   string `json:"instId"`
+			BaseCcy  string `json:"baseCcy"`
+			QuoteCcy string `json:"quoteCcy"`
+			LotSz    string `json:"lotSz"`
+			TickSz   string `json:"tickSz"`
+			MinSz    string `json:"minSz"`
+			State    string `json:"state"`
+		} `json:"data"`
+	}
+
+	if err := c.getJSON(context.Background(), "/api/v5/public/instruments?instType=SPOT", &instrumentsResponse); err != nil {
+		return nil, err
+	}
+
+	if instrumentsResponse.Code != "0" {
+		return nil, fmt.Errorf("не удалось получить список инструментов: %s", instrumentsResponse.Msg)
+	}
+
+	instruments := make([]Instrument, 0, len(instrumentsResponse.Data))
+	for _, data := range instrumentsResponse.Data {
+		values, err := parseDecimals(data.LotSz, data.TickSz, data.MinSz)
+		if err != nil {
+			return nil, fmt.Errorf("некорректные параметры инструмента %s: %w", data.InstID, err)
+		}
+
+		instruments = append(instruments, Instrument{
+			InstID:   data.InstID,
+			BaseCcy:  data.BaseCcy,
+			QuoteCcy: data.QuoteCcy,
+			LotSz:    values[0],
+			TickSz:   values[1],
+			MinSz:    values[2],
+			State:    data.State,
+		})
+	}
+	return instruments, nil
+}
@@ -1,10 +1,13 @@
 package okx
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // Структура для ответа API
@@ -15,57 +18,166 @@ type PriceResponse struct {
 	} `json:"result"`
 }
 
-// Пример структуры для хранения данных об активах
-type Asset struct {
-	Symbol string `json:"name"`
-}
+// Функция для получения текущей цены актива
+func (c *Client) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	path := fmt.Sprintf("/api/v5/market/ticker?instId=%s", symbol)
+	log.Printf("Запрос к URL: %s", c.BaseURL+path) // Логируем URL
+
+	// Структура для ответа API OKX
+	var priceResponse struct {
+		Code string `json:"code"`
+		Data []struct {
+			LastPrice string `json:"last"`
+		} `json:"data"`
+	}
+
+	if err := c.getJSON(context.Background(), path, &priceResponse); err != nil {
+		return decimal.Decimal{}, err
+	}
 
-// GetAssets возвращает список из 10 популярных активов
-func GetAssets() ([]string, error) {
-	popularAssets := []string{
-		"BTC-USDT", "ETH-USDT", "XRP-USDT", "LTC-USDT", "BCH-USDT",
-		"TON-USDT", "DOT-USDT", "SOL-USDT", "DOGE-USDT", "LINK-USDT",
+	if priceResponse.Code != "0" || len(priceResponse.Data) == 0 {
+		return decimal.Decimal{}, fmt.Errorf("не удалось найти цену для актива %s", symbol)
 	}
 
-	return popularAssets, nil
+	price, err := parseDecimal(priceResponse.Data[0].LastPrice)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("некорректная цена актива %s: %w", symbol, err)
+	}
+	log.Printf("Получена цена: %s", price)
+	return price, nil
 }
 
-// Функция для получения текущей цены актива
-func GetCurrentPrice(symbol string) (string, error) {
-	url := fmt.Sprintf("https://www.okx.com/api/v5/market/ticker?instId=%s", symbol)
-	log.Printf("Запрос к URL: %s", url) // Логируем URL
+// BookLevel описывает уровень стакана
+type BookLevel struct {
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+// OrderBook содержит лучшие уровни стакана на покупку и продажу
+type OrderBook struct {
+	Asks []BookLevel // По возрастанию цены
+	Bids []BookLevel // По убыванию цены
+}
+
+// GetOrderBook возвращает стакан актива глубиной depth уровней
+func (c *Client) GetOrderBook(symbol string, depth int) (OrderBook, error) {
+	// Уровни стакана OKX приходят массивами строк [цена, объем, 0, количество заявок]
+	var bookResponse struct {
+		Code string `json:"code"`
+		Data []struct {
+			Asks [][]string `json:"asks"`
+			Bids [][]string `json:"bids"`
+		} `json:"data"`
+	}
+
+	if err := c.getJSON(context.Background(), fmt.Sprintf("/api/v5/market/books?instId=%s&sz=%d", symbol, depth), &bookResponse); err != nil {
+		return OrderBook{}, err
+	}
+
+	if bookResponse.Code != "0" || len(bookResponse.Data) == 0 {
+		return OrderBook{}, fmt.Errorf("не удалось получить стакан для актива %s", symbol)
+	}
 
-	resp, err := http.Get(url)
+	asks, err := parseBookLevels(bookResponse.Data[0].Asks)
 	if err != nil {
-		log.Printf("Ошибка при выполнении запроса: %v", err)
-		return "", err
+		return OrderBook{}, err
 	}
-	defer resp.Body.Close()
+	bids, err := parseBookLevels(bookResponse.Data[0].Bids)
+	if err != nil {
+		return OrderBook{}, err
+	}
+
+	return OrderBook{Asks: asks, Bids: bids}, nil
+}
 
-	log.Printf("Получен статус код: %d", resp.StatusCode)
+// parseBookLevels разбирает уровни стакана из строкового представления OKX
+func parseBookLevels(raw [][]string) ([]BookLevel, error) {
+	levels := make([]BookLevel, 0, len(raw))
+	for _, level := range raw {
+		if len(level) < 2 {
+			return nil, fmt.Errorf("некорректный уровень стакана: %v", level)
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка при запросе: %s", resp.Status)
+		price, err := parseDecimal(level[0])
+		if err != nil {
+			return nil, fmt.Errorf("некорректная цена в стакане: %w", err)
+		}
+		size, err := parseDecimal(level[1])
+		if err != nil {
+			return nil, fmt.Errorf("некорректный объем в стакане: %w", err)
+		}
+
+		levels = append(levels, BookLevel{Price: price, Size: size})
 	}
+	return levels, nil
+}
 
-	// Структура для ответа API OKX
-	var priceResponse struct {
+// Ticker содержит последнюю цену и лучшие цены покупки и продажи
+type Ticker struct {
+	Last decimal.Decimal
+	Bid  decimal.Decimal
+	Ask  decimal.Decimal
+	Time time.Time
+}
+
+// GetTicker возвращает тикер актива
+func (c *Client) GetTicker(symbol string) (Ticker, error) {
+	var tickerResponse struct {
 		Code string `json:"code"`
 		Data []struct {
-			LastPrice string `json:"last"`
+			Last  string `json:"last"`
+			BidPx string `json:"bidPx"`
+			AskPx string `json:"askPx"`
+			Ts    string `json:"ts"`
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&priceResponse); err != nil {
-		log.Printf("Ошибка при декодировании JSON: %v", err)
-		return "", err
+	if err := c.getJSON(context.Background(), fmt.Sprintf("/api/v5/market/ticker?instId=%s", symbol), &tickerResponse); err != nil {
+		return Ticker{}, err
 	}
 
-	if priceResponse.Code != "0" || len(priceResponse.Data) == 0 {
-		return "", fmt.Errorf("не удалось найти цену для актива %s", symbol)
+	if tickerResponse.Code != "0" || len(tickerResponse.Data) == 0 {
+		return Ticker{}, fmt.Errorf("не удалось найти цену для актива %s", symbol)
 	}
 
-	price := priceResponse.Data[0].LastPrice
-	log.Printf("Получена цена: %s", price)
-	return price, nil
+	data := tickerResponse.Data[0]
+	last, err := parseDecimal(data.Last)
+	if err != nil {
+		return Ticker{}, fmt.Errorf("некорректная цена в тикере %s: %w", symbol, err)
+	}
+	bid, err := parseDecimal(data.BidPx)
+	if err != nil {
+		return Ticker{}, fmt.Errorf("некорректная цена покупки в тикере %s: %w", symbol, err)
+	}
+	ask, err := parseDecimal(data.AskPx)
+	if err != nil {
+		return Ticker{}, fmt.Errorf("некорректная цена продажи в тикере %s: %w", symbol, err)
+	}
+
+	ms, err := strconv.ParseInt(data.Ts, 10, 64)
+	if err != nil {
+		return Ticker{}, fmt.Errorf("некорректное время в тикере %s: %w", symbol, err)
+	}
+	return Ticker{Last: last, Bid: bid, Ask: ask, Time: time.UnixMilli(ms)}, nil
+}
+
+// parseDecimal разбирает число из строкового представления OKX без потери точности
+func parseDecimal(raw string) (decimal.Decimal, error) {
+	value, err := decimal.NewFromString(raw)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("некорректное число %q", raw)
+	}
+	return value, nil
+}
+
+// parseDecimals разбирает несколько чисел из строкового представления OKX
+func parseDecimals(raw ...string) ([]decimal.Decimal, error) {
+	values := make([]decimal.Decimal, len(raw))
+	for i, value := range raw {
+		var err error
+		if values[i], err = parseDecimal(value); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
 }
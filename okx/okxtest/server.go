@@ -0,0 +1,348 @@
+// Package okxtest предоставляет локальный сервер, имитирующий REST и WebSocket API OKX.
+// Сервер отдает заданные тикеры, свечи, стаканы и инструменты и умеет отвечать ошибками,
+// чтобы бот, портфели и стратегии можно было проверять без доступа к интернету
+package okxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/shopspring/decimal"
+)
+
+// WSPath задает путь публичного WebSocket API на тестовом сервере
+const WSPath = "/ws/v5/public"
+
+// failure описывает запланированный ошибочный ответ
+type failure struct {
+	status int    // HTTP-статус, если не 200
+	code   string // Код OKX при статусе 200
+	msg    string
+	times  int
+}
+
+// Server имитирует API OKX поверх httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	tickers     map[string]okx.Ticker
+	books       map[string]okx.OrderBook
+	candles     map[string]map[okx.Bar][]okx.Candle
+	instruments []okx.Instrument
+	failures    map[string]*failure
+	requests    map[string]int
+	clients     map[*wsClient]bool
+}
+
+// NewServer запускает тестовый сервер; его нужно остановить вызовом Close
+func NewServer() *Server {
+	s := &Server{
+		tickers:  make(map[string]okx.Ticker),
+		books:    make(map[string]okx.OrderBook),
+		candles:  make(map[string]map[okx.Bar][]okx.Candle),
+		failures: make(map[string]*failure),
+		requests: make(map[string]int),
+		clients:  make(map[*wsClient]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/market/ticker", s.handleTicker)
+	mux.HandleFunc("/api/v5/market/books", s.handleBooks)
+	mux.HandleFunc("/api/v5/market/candles", s.handleCandles)
+	mux.HandleFunc("/api/v5/market/history-candles", s.handleCandles)
+	mux.HandleFunc("/api/v5/public/instruments", s.handleInstruments)
+	mux.HandleFunc(WSPath, s.handleWS)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// WSURL возвращает адрес WebSocket API тестового сервера
+func (s *Server) WSURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + WSPath
+}
+
+// Close закрывает соединения WebSocket и останавливает сервер
+func (s *Server) Close() {
+	s.DropConnections()
+	s.Server.Close()
+}
+
+// SetPrice задает последнюю цену инструмента с нулевым спредом
+func (s *Server) SetPrice(instID string, price decimal.Decimal) {
+	s.SetTicker(instID, okx.Ticker{Last: price, Bid: price, Ask: price, Time: time.Now()})
+}
+
+// SetTicker задает тикер инструмента и рассылает его подписчикам канала tickers
+func (s *Server) SetTicker(instID string, ticker okx.Ticker) {
+	if ticker.Time.IsZero() {
+		ticker.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	s.tickers[instID] = ticker
+	s.mu.Unlock()
+
+	s.broadcast(okx.ChannelTickers, instID, tickerData(instID, ticker))
+}
+
+// SetBook задает стакан инструмента и рассылает его подписчикам канала books5
+func (s *Server) SetBook(instID string, book okx.OrderBook) {
+	s.mu.Lock()
+	s.books[instID] = book
+	s.mu.Unlock()
+
+	s.broadcast(okx.ChannelBooks5, instID, bookData(book, 5))
+}
+
+// SetCandles задает свечи инструмента для размера bar в любом порядке
+func (s *Server) SetCandles(instID string, bar okx.Bar, candles []okx.Candle) {
+	sorted := append([]okx.Candle(nil), candles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.candles[instID] == nil {
+		s.candles[instID] = make(map[okx.Bar][]okx.Candle)
+	}
+	s.candles[instID][bar] = sorted
+}
+
+// SetInstruments задает список спотовых инструментов; пустое состояние отдается как live
+func (s *Server) SetInstruments(instruments []okx.Instrument) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instruments = append([]okx.Instrument(nil), instruments...)
+}
+
+// PublishTrade рассылает сделку подписчикам канала trades
+func (s *Server) PublishTrade(instID string, trade okx.Trade) {
+	s.broadcast(okx.ChannelTrades, instID, map[string]string{
+		"instId":  instID,
+		"tradeId": trade.ID,
+		"px":      trade.Price.String(),
+		"sz":      trade.Size.String(),
+		"side":    trade.Side,
+		"ts":      formatMillis(trade.Time),
+	})
+}
+
+// FailStatus заставляет следующие times запросов к path (например "/api/v5/market/ticker")
+// вернуть HTTP-статус status, например 429 или 503
+func (s *Server) FailStatus(path string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[path] = &failure{status: status, times: times}
+}
+
+// FailCode заставляет следующие times запросов к path вернуть статус 200 с кодом OKX code
+func (s *Server) FailCode(path, code, msg string, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[path] = &failure{code: code, msg: msg, times: times}
+}
+
+// Requests возвращает количество запросов к path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// handleTicker отвечает на /api/v5/market/ticker
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, r) {
+		return
+	}
+
+	instID := r.URL.Query().Get("instId")
+	s.mu.Lock()
+	ticker, ok := s.tickers[instID]
+	s.mu.Unlock()
+
+	if !ok {
+		writeResponse(w, "51001", "Instrument ID does not exist", []any{})
+		return
+	}
+	writeResponse(w, "0", "", []any{tickerData(instID, ticker)})
+}
+
+// handleBooks отвечает на /api/v5/market/books
+func (s *Server) handleBooks(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	depth, err := strconv.Atoi(query.Get("sz"))
+	if err != nil || depth <= 0 {
+		depth = 1
+	}
+
+	s.mu.Lock()
+	book, ok := s.books[query.Get("instId")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeResponse(w, "51001", "Instrument ID does not exist", []any{})
+		return
+	}
+	writeResponse(w, "0", "", []any{bookData(book, depth)})
+}
+
+// handleCandles отвечает на /api/v5/market/candles и /api/v5/market/history-candles:
+// отдает свечи от новых к старым, открытые раньше after, не более limit штук
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > 300 {
+		limit = 100
+	}
+	var after time.Time
+	if raw := query.Get("after"); raw != "" {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			writeResponse(w, "51000", "Parameter after error", []any{})
+			return
+		}
+		after = time.UnixMilli(ms)
+	}
+
+	s.mu.Lock()
+	candles := s.candles[query.Get("instId")][okx.Bar(query.Get("bar"))]
+	s.mu.Unlock()
+
+	data := []any{}
+	for _, candle := range candles {
+		if len(data) == limit {
+			break
+		}
+		if !after.IsZero() && !candle.Time.Before(after) {
+			continue
+		}
+
+		confirmed := "0"
+		if candle.Confirmed {
+			confirmed = "1"
+		}
+		data = append(data, []string{
+			formatMillis(candle.Time),
+			candle.Open.String(), candle.High.String(), candle.Low.String(), candle.Close.String(),
+			candle.Volume.String(), candle.Volume.String(), candle.QuoteVolume.String(),
+			confirmed,
+		})
+	}
+	writeResponse(w, "0", "", data)
+}
+
+// handleInstruments отвечает на /api/v5/public/instruments
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, r) {
+		return
+	}
+	if instType := r.URL.Query().Get("instType"); instType != "SPOT" {
+		writeResponse(w, "51000", "Parameter instType error", []any{})
+		return
+	}
+
+	s.mu.Lock()
+	instruments := append([]okx.Instrument(nil), s.instruments...)
+	s.mu.Unlock()
+
+	data := make([]any, 0, len(instruments))
+	for _, instrument := range instruments {
+		state := instrument.State
+		if state == "" {
+			state = okx.InstrumentLive
+		}
+		data = append(data, map[string]string{
+			"instType": "SPOT",
+			"instId":   instrument.InstID,
+			"baseCcy":  instrument.BaseCcy,
+			"quoteCcy": instrument.QuoteCcy,
+			"lotSz":    instrument.LotSz.String(),
+			"tickSz":   instrument.TickSz.String(),
+			"minSz":    instrument.MinSz.String(),
+			"state":    state,
+		})
+	}
+	writeResponse(w, "0", "", data)
+}
+
+// fail учитывает запрос и отвечает запланированной ошибкой, если она есть
+func (s *Server) fail(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	planned, ok := s.failures[r.URL.Path]
+	if ok {
+		planned.times--
+		if planned.times <= 0 {
+			delete(s.failures, r.URL.Path)
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	if planned.status != 0 {
+		http.Error(w, http.StatusText(planned.status), planned.status)
+		return true
+	}
+	writeResponse(w, planned.code, planned.msg, []any{})
+	return true
+}
+
+// writeResponse записывает ответ в формате REST API OKX
+func writeResponse(w http.ResponseWriter, code, msg string, data []any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"code": code, "msg": msg, "data": data})
+}
+
+// tickerData представляет тикер в формате OKX
+func tickerData(instID string, ticker okx.Ticker) map[string]string {
+	return map[string]string{
+		"instId": instID,
+		"last":   ticker.Last.String(),
+		"bidPx":  ticker.Bid.String(),
+		"askPx":  ticker.Ask.String(),
+		"ts":     formatMillis(ticker.Time),
+	}
+}
+
+// bookData представляет depth лучших уровней стакана в формате OKX
+func bookData(book okx.OrderBook, depth int) map[string]any {
+	levels := func(side []okx.BookLevel) [][]string {
+		rows := [][]string{}
+		for i, level := range side {
+			if i == depth {
+				break
+			}
+			rows = append(rows, []string{level.Price.String(), level.Size.String(), "0", "1"})
+		}
+		return rows
+	}
+	return map[string]any{"asks": levels(book.Asks), "bids": levels(book.Bids), "ts": formatMillis(time.Now())}
+}
+
+// formatMillis представляет время в миллисекундах
+func formatMillis(t time.Time) string {
+	return fmt.Sprint(t.UnixMilli())
+}
@@ -0,0 +1,147 @@
+package okxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/gorilla/websocket"
+)
+
+// wsClient хранит соединение и подписки клиента WebSocket
+type wsClient struct {
+	conn          *websocket.Conn
+	writeMu       sync.Mutex
+	subscriptions map[wsArg]bool
+}
+
+// wsArg идентифицирует канал инструмента
+type wsArg struct {
+	Channel okx.Channel `json:"channel"`
+	InstID  string      `json:"instId"`
+}
+
+// handleWS обслуживает соединение публичного WebSocket API: ping, subscribe и unsubscribe
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := &wsClient{conn: conn, subscriptions: make(map[wsArg]bool)}
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if string(message) == "ping" {
+			client.write([]byte("pong"))
+			continue
+		}
+
+		var request struct {
+			Op   string  `json:"op"`
+			Args []wsArg `json:"args"`
+		}
+		if err := json.Unmarshal(message, &request); err != nil {
+			client.writeJSON(map[string]string{"event": "error", "code": "60012", "msg": "Invalid request"})
+			continue
+		}
+
+		for _, arg := range request.Args {
+			switch request.Op {
+			case "subscribe":
+				s.mu.Lock()
+				client.subscriptions[arg] = true
+				ticker, hasTicker := s.tickers[arg.InstID]
+				book, hasBook := s.books[arg.InstID]
+				s.mu.Unlock()
+
+				client.writeJSON(map[string]any{"event": "subscribe", "arg": arg})
+
+				// Как и OKX, сразу отправляем текущее состояние канала
+				switch {
+				case arg.Channel == okx.ChannelTickers && hasTicker:
+					client.writeJSON(map[string]any{"arg": arg, "data": []any{tickerData(arg.InstID, ticker)}})
+				case arg.Channel == okx.ChannelBooks5 && hasBook:
+					client.writeJSON(map[string]any{"arg": arg, "data": []any{bookData(book, 5)}})
+				}
+
+			case "unsubscribe":
+				s.mu.Lock()
+				delete(client.subscriptions, arg)
+				s.mu.Unlock()
+
+				client.writeJSON(map[string]any{"event": "unsubscribe", "arg": arg})
+
+			default:
+				client.writeJSON(map[string]string{"event": "error", "code": "60012", "msg": "Invalid request"})
+			}
+		}
+	}
+}
+
+// broadcast рассылает данные подписчикам канала инструмента
+func (s *Server) broadcast(channel okx.Channel, instID string, data any) {
+	arg := wsArg{Channel: channel, InstID: instID}
+
+	s.mu.Lock()
+	var recipients []*wsClient
+	for client := range s.clients {
+		if client.subscriptions[arg] {
+			recipients = append(recipients, client)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, client := range recipients {
+		client.writeJSON(map[string]any{"arg": arg, "data": []any{data}})
+	}
+}
+
+// DropConnections разрывает все соединения WebSocket, чтобы проверить переподключение клиентов
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for client := range s.clients {
+		client.conn.Close()
+	}
+}
+
+// Connections возвращает количество открытых соединений WebSocket
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.clients)
+}
+
+// write отправляет текстовое сообщение
+func (c *wsClient) write(message []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// writeJSON отправляет сообщение в формате JSON
+func (c *wsClient) writeJSON(v any) {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.write(message)
+}
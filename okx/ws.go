@@ -0,0 +1,391 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// PublicWSURL задает адрес публичного WebSocket API OKX
+const PublicWSURL = "wss://ws.okx.com:8443/ws/v5/public"
+
+const (
+	// OKX закрывает соединение, если в течение 30 секунд не было данных
+	defaultPingInterval   = 20 * time.Second
+	defaultReconnectDelay = time.Second
+	maxReconnectDelay     = 30 * time.Second
+	subscriberBuffer      = 64
+)
+
+// Channel задает канал публичного WebSocket API
+type Channel string
+
+// Поддерживаемые каналы
+const (
+	ChannelTickers Channel = "tickers"
+	ChannelTrades  Channel = "trades"
+	ChannelBooks5  Channel = "books5"
+)
+
+// Trade описывает сделку на бирже
+type Trade struct {
+	ID    string
+	Price decimal.Decimal
+	Size  decimal.Decimal
+	Side  string // buy или sell со стороны тейкера
+	Time  time.Time
+}
+
+// Event содержит обновление канала; заполнено поле, соответствующее каналу
+type Event struct {
+	Channel Channel
+	InstID  string
+	Ticker  *Ticker
+	Trades  []Trade
+	Book    *OrderBook
+}
+
+// subscription идентифицирует подписку на канал инструмента
+type subscription struct {
+	Channel Channel `json:"channel"`
+	InstID  string  `json:"instId"`
+}
+
+// Stream поддерживает соединение с публичным WebSocket API OKX: переподключается при обрыве,
+// восстанавливает подписки, отправляет ping и раздает обновления подписчикам внутри процесса
+type Stream struct {
+	URL            string
+	PingInterval   time.Duration
+	ReconnectDelay time.Duration // Начальная пауза перед переподключением, удваивается до 30 секунд
+
+	mu          sync.Mutex
+	writeMu     sync.Mutex
+	conn        *websocket.Conn
+	subscribers map[subscription][]chan Event
+}
+
+// NewStream создает клиент WebSocket API по адресу url
+func NewStream(url string) *Stream {
+	return &Stream{
+		URL:            url,
+		PingInterval:   defaultPingInterval,
+		ReconnectDelay: defaultReconnectDelay,
+		subscribers:    make(map[subscription][]chan Event),
+	}
+}
+
+// Subscribe подписывается на канал инструмента. Обновления приходят в канал до вызова отмены;
+// если подписчик не успевает читать, лишние обновления отбрасываются
+func (s *Stream) Subscribe(channel Channel, instID string) (<-chan Event, func()) {
+	key := subscription{Channel: channel, InstID: instID}
+	events := make(chan Event, subscriberBuffer)
+
+	s.mu.Lock()
+	first := len(s.subscribers[key]) == 0
+	s.subscribers[key] = append(s.subscribers[key], events)
+	conn := s.conn
+	s.mu.Unlock()
+
+	if first && conn != nil {
+		if err := s.send(conn, "subscribe", []subscription{key}); err != nil {
+			log.Printf("Ошибка подписки на %s %s: %v", channel, instID, err)
+		}
+	}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { s.unsubscribe(key, events) })
+	}
+	return events, cancel
+}
+
+// unsubscribe удаляет подписчика и отписывается от канала, если подписчиков не осталось
+func (s *Stream) unsubscribe(key subscription, events chan Event) {
+	s.mu.Lock()
+	subscribers := s.subscribers[key]
+	for i, subscriber := range subscribers {
+		if subscriber == events {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+	last := len(subscribers) == 0
+	if last {
+		delete(s.subscribers, key)
+	} else {
+		s.subscribers[key] = subscribers
+	}
+	conn := s.conn
+	s.mu.Unlock()
+
+	close(events)
+	if last && conn != nil {
+		if err := s.send(conn, "unsubscribe", []subscription{key}); err != nil {
+			log.Printf("Ошибка отписки от %s %s: %v", key.Channel, key.InstID, err)
+		}
+	}
+}
+
+// Run поддерживает соединение, пока не будет отменен контекст
+func (s *Stream) Run(ctx context.Context) {
+	delay := s.ReconnectDelay
+	for {
+		connected, err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = s.ReconnectDelay
+		}
+		log.Printf("Соединение с WebSocket OKX потеряно: %v. Переподключение через %s", err, delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// session устанавливает одно соединение, восстанавливает подписки и читает сообщения до ошибки
+func (s *Stream) session(ctx context.Context) (bool, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conn = conn
+	keys := make([]subscription, 0, len(s.subscribers))
+	for key := range s.subscribers {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+
+	if len(keys) > 0 {
+		if err := s.send(conn, "subscribe", keys); err != nil {
+			return true, err
+		}
+	}
+
+	// Закрываем соединение при отмене контекста и отправляем ping, чтобы биржа не разорвала его
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(s.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-ticker.C:
+				if err := s.write(conn, websocket.TextMessage, []byte("ping")); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(2 * s.PingInterval))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		if string(message) == "pong" {
+			continue
+		}
+
+		event, err := parseEvent(message)
+		if err != nil {
+			log.Printf("Ошибка разбора сообщения WebSocket OKX: %v", err)
+			continue
+		}
+		if event != nil {
+			s.publish(*event)
+		}
+	}
+}
+
+// publish раздает обновление подписчикам канала
+func (s *Stream) publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscriber := range s.subscribers[subscription{Channel: event.Channel, InstID: event.InstID}] {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// send отправляет запрос подписки или отписки
+func (s *Stream) send(conn *websocket.Conn, op string, keys []subscription) error {
+	request, err := json.Marshal(struct {
+		Op   string         `json:"op"`
+		Args []subscription `json:"args"`
+	}{Op: op, Args: keys})
+	if err != nil {
+		return err
+	}
+	return s.write(conn, websocket.TextMessage, request)
+}
+
+// write отправляет сообщение; соединение допускает только одного пишущего одновременно
+func (s *Stream) write(conn *websocket.Conn, messageType int, data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return conn.WriteMessage(messageType, data)
+}
+
+// parseEvent разбирает сообщение WebSocket API. Для служебных сообщений возвращает nil
+func parseEvent(message []byte) (*Event, error) {
+	var envelope struct {
+		Event string            `json:"event"`
+		Code  string            `json:"code"`
+		Msg   string            `json:"msg"`
+		Arg   subscription      `json:"arg"`
+		Data  []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		return nil, err
+	}
+
+	switch envelope.Event {
+	case "":
+	case "error":
+		return nil, fmt.Errorf("ошибка OKX %s: %s", envelope.Code, envelope.Msg)
+	default:
+		// Подтверждения subscribe и unsubscribe
+		return nil, nil
+	}
+
+	event := &Event{Channel: envelope.Arg.Channel, InstID: envelope.Arg.InstID}
+	switch envelope.Arg.Channel {
+	case ChannelTickers:
+		for _, raw := range envelope.Data {
+			ticker, err := parseWSTicker(raw)
+			if err != nil {
+				return nil, err
+			}
+			event.Ticker = &ticker
+		}
+	case ChannelTrades:
+		for _, raw := range envelope.Data {
+			trade, err := parseWSTrade(raw)
+			if err != nil {
+				return nil, err
+			}
+			event.Trades = append(event.Trades, trade)
+		}
+	case ChannelBooks5:
+		for _, raw := range envelope.Data {
+			book, err := parseWSBook(raw)
+			if err != nil {
+				return nil, err
+			}
+			event.Book = &book
+		}
+	default:
+		return nil, nil
+	}
+	return event, nil
+}
+
+// parseWSTicker разбирает данные канала tickers
+func parseWSTicker(raw json.RawMessage) (Ticker, error) {
+	var data struct {
+		Last  string `json:"last"`
+		BidPx string `json:"bidPx"`
+		AskPx string `json:"askPx"`
+		Ts    string `json:"ts"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return Ticker{}, err
+	}
+
+	values, err := parseDecimals(data.Last, data.BidPx, data.AskPx)
+	if err != nil {
+		return Ticker{}, fmt.Errorf("некорректный тикер: %w", err)
+	}
+	ts, err := parseMillis(data.Ts)
+	if err != nil {
+		return Ticker{}, err
+	}
+	return Ticker{Last: values[0], Bid: values[1], Ask: values[2], Time: ts}, nil
+}
+
+// parseWSTrade разбирает данные канала trades
+func parseWSTrade(raw json.RawMessage) (Trade, error) {
+	var data struct {
+		TradeID string `json:"tradeId"`
+		Px      string `json:"px"`
+		Sz      string `json:"sz"`
+		Side    string `json:"side"`
+		Ts      string `json:"ts"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return Trade{}, err
+	}
+
+	values, err := parseDecimals(data.Px, data.Sz)
+	if err != nil {
+		return Trade{}, fmt.Errorf("некорректная сделка: %w", err)
+	}
+	ts, err := parseMillis(data.Ts)
+	if err != nil {
+		return Trade{}, err
+	}
+	return Trade{ID: data.TradeID, Price: values[0], Size: values[1], Side: data.Side, Time: ts}, nil
+}
+
+// parseWSBook разбирает данные канала books5
+func parseWSBook(raw json.RawMessage) (OrderBook, error) {
+	var data struct {
+		Asks [][]string `json:"asks"`
+		Bids [][]string `json:"bids"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return OrderBook{}, err
+	}
+
+	asks, err := parseBookLevels(data.Asks)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	bids, err := parseBookLevels(data.Bids)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	return OrderBook{Asks: asks, Bids: bids}, nil
+}
+
+// parseMillis разбирает время в миллисекундах
+func parseMillis(raw string) (time.Time, error) {
+	ms, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректное время %q", raw)
+	}
+	return time.UnixMilli(ms), nil
+}
@@ -0,0 +1,59 @@
+package okx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx"
+	"github.com/VadimBorzenkov/TradeSimulatorBot/okx/okxtest"
+	"github.com/shopspring/decimal"
+)
+
+// nextTicker ждет тикер из канала подписки не дольше секунды
+func nextTicker(t *testing.T, events <-chan okx.Event) okx.Ticker {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Ticker != nil {
+				return *event.Ticker
+			}
+		case <-timeout:
+			t.Fatal("тикер не получен")
+		}
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(60000))
+
+	stream := okx.NewStream(server.WSURL())
+	stream.ReconnectDelay = 10 * time.Millisecond
+	events, cancel := stream.Subscribe(okx.ChannelTickers, "BTC-USDT")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go stream.Run(ctx)
+
+	if ticker := nextTicker(t, events); !ticker.Last.Equal(decimal.NewFromInt(60000)) {
+		t.Fatalf("цена %s, ожидалась 60000", ticker.Last)
+	}
+
+	// Пока соединения нет, цена меняется; после переподключения подписка восстанавливается
+	// и сервер присылает текущий тикер
+	server.DropConnections()
+	server.SetPrice("BTC-USDT", decimal.NewFromInt(61000))
+
+	if ticker := nextTicker(t, events); !ticker.Last.Equal(decimal.NewFromInt(61000)) {
+		t.Fatalf("цена после переподключения %s, ожидалась 61000", ticker.Last)
+	}
+	if server.Connections() != 1 {
+		t.Fatalf("открыто соединений: %d, ожидалось 1", server.Connections())
+	}
+}